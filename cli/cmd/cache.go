@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makemore/scaffold/internal/registry"
+	"github.com/makemore/scaffold/internal/source"
+	"github.com/spf13/cobra"
+)
+
+var pruneOlderThan string
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean the template cache",
+}
+
+var cacheInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the cache location, size and entry count",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := templateCacheDir()
+		entries, err := cacheEntries(dir)
+		if err != nil {
+			return err
+		}
+
+		var total int64
+		for _, e := range entries {
+			total += e.Size
+		}
+
+		fmt.Printf("Location: %s\n", dir)
+		fmt.Printf("Entries:  %d\n", len(entries))
+		fmt.Printf("Size:     %s\n", formatSize(total))
+
+		for _, e := range entries {
+			name := e.Source
+			if name == "" {
+				name = e.Name
+			}
+			fmt.Printf("  %-10s %s\n", formatSize(e.Size), name)
+		}
+		return nil
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove everything from the cache",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := cacheEntries(templateCacheDir())
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if err := os.RemoveAll(e.Path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", e.Name, err)
+			}
+		}
+
+		fmt.Printf("🧹 Removed %d cache entries\n", len(entries))
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:     "prune",
+	Short:   "Remove cache entries that haven't been used recently",
+	Example: `  scaffold cache prune --older-than 30d`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		age, err := parseAge(pruneOlderThan)
+		if err != nil {
+			return err
+		}
+
+		entries, err := cacheEntries(templateCacheDir())
+		if err != nil {
+			return err
+		}
+		pruned, err := pruneCache(entries, age, time.Now())
+		if err != nil {
+			return err
+		}
+
+		var freed int64
+		for _, e := range pruned {
+			freed += e.Size
+		}
+		fmt.Printf("🧹 Pruned %d cache entries (%s)\n", len(pruned), formatSize(freed))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheInfoCmd, cacheCleanCmd, cachePruneCmd)
+
+	cachePruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "30d", "Remove entries unused for this long (e.g. 30d, 12h)")
+}
+
+// templateCacheDir returns the cache directory the fetcher uses
+func templateCacheDir() string {
+	return source.NewFetcher(cacheSubdir(source.CacheSubdir)).CacheDir
+}
+
+// cacheEntry is a top-level item in the cache directory
+type cacheEntry struct {
+	Name     string
+	Path     string
+	Source   string // From the entry's sidecar, if any
+	Size     int64
+	LastUsed time.Time
+}
+
+// cacheEntries returns the templates cached in dir, along with whatever an
+// older scaffold left in its parent, the cache root, before indexes and
+// templates were cached apart. Nothing reads those any more, so they are
+// only listed to be cleaned or pruned.
+func cacheEntries(dir string) ([]cacheEntry, error) {
+	entries, err := listCacheEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := readCacheEntries(filepath.Dir(dir), map[string]bool{
+		source.CacheSubdir:   true,
+		registry.CacheSubdir: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, legacy...), nil
+}
+
+// listCacheEntries returns the entries in the cache. A missing cache
+// directory has no entries.
+func listCacheEntries(dir string) ([]cacheEntry, error) {
+	return readCacheEntries(dir, nil)
+}
+
+// readCacheEntries returns the entries in dir other than those named in skip
+func readCacheEntries(dir string, skip map[string]bool) ([]cacheEntry, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	entries := make([]cacheEntry, 0, len(items))
+	for _, item := range items {
+		if skip[item.Name()] {
+			continue
+		}
+		path := filepath.Join(dir, item.Name())
+		info, err := item.Info()
+		if err != nil {
+			return nil, err
+		}
+		size, err := diskUsage(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, cacheEntry{
+			Name:     item.Name(),
+			Path:     path,
+			Source:   source.ReadCacheSource(path),
+			Size:     size,
+			LastUsed: info.ModTime(),
+		})
+	}
+	return entries, nil
+}
+
+// diskUsage returns the total size of the regular files under path
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// pruneCache removes the entries last used before now minus age
+func pruneCache(entries []cacheEntry, age time.Duration, now time.Time) ([]cacheEntry, error) {
+	cutoff := now.Add(-age)
+	var pruned []cacheEntry
+	for _, e := range entries {
+		if !e.LastUsed.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			return pruned, fmt.Errorf("failed to remove %s: %w", e.Name, err)
+		}
+		pruned = append(pruned, e)
+	}
+	return pruned, nil
+}
+
+// parseAge parses a duration, additionally accepting a number of days
+// such as "30d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: use e.g. 30d or 12h", s)
+	}
+	return d, nil
+}
+
+// formatSize formats a byte count for display
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
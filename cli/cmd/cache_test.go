@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/registry"
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// writeCacheEntry creates a cache entry with files of the given sizes, last
+// used at the given time
+func writeCacheEntry(t *testing.T, dir, name string, sizes []int, lastUsed time.Time) {
+	t.Helper()
+
+	entry := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Join(entry, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	for i, size := range sizes {
+		path := filepath.Join(entry, "sub", string(rune('a'+i)))
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.Chtimes(entry, lastUsed, lastUsed); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+}
+
+func TestListCacheEntries_Size(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeCacheEntry(t, dir, "repo_a", []int{100, 250}, now)
+	writeCacheEntry(t, dir, "repo_b", []int{1024}, now)
+
+	entries, err := listCacheEntries(dir)
+	if err != nil {
+		t.Fatalf("listCacheEntries() error = %v", err)
+	}
+
+	sizes := make(map[string]int64)
+	for _, e := range entries {
+		sizes[e.Name] = e.Size
+	}
+	if len(entries) != 2 || sizes["repo_a"] != 350 || sizes["repo_b"] != 1024 {
+		t.Errorf("sizes = %v, want repo_a=350 repo_b=1024", sizes)
+	}
+
+	// A missing cache is empty rather than an error
+	entries, err = listCacheEntries(filepath.Join(dir, "missing"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("listCacheEntries(missing) = %v, %v, want none", entries, err)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeCacheEntry(t, dir, "old", []int{10}, now.Add(-45*24*time.Hour))
+	writeCacheEntry(t, dir, "recent", []int{10}, now.Add(-2*24*time.Hour))
+
+	entries, err := listCacheEntries(dir)
+	if err != nil {
+		t.Fatalf("listCacheEntries() error = %v", err)
+	}
+	pruned, err := pruneCache(entries, 30*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("pruneCache() error = %v", err)
+	}
+
+	if len(pruned) != 1 || pruned[0].Name != "old" {
+		t.Errorf("pruned = %+v, want only old", pruned)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+		t.Error("old entry should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "recent")); err != nil {
+		t.Error("recent entry should be kept")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"xd", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	flagDir, envDir, configDir := t.TempDir(), t.TempDir(), t.TempDir()
+	tests := []struct {
+		name   string
+		flag   string
+		env    string
+		config string
+		want   string
+	}{
+		{name: "flag", flag: flagDir, env: envDir, config: configDir, want: flagDir},
+		{name: "env", env: envDir, config: configDir, want: envDir},
+		{name: "config", config: configDir, want: configDir},
+		{name: "default"},
+	}
+
+	oldConfig, oldFlag := userConfig, cacheDirFlag
+	t.Cleanup(func() { userConfig, cacheDirFlag = oldConfig, oldFlag })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDirFlag = tt.flag
+			t.Setenv(cacheDirEnv, tt.env)
+			userConfig = &config.UserConfig{CacheDir: tt.config}
+
+			if got := cacheDir(); got != tt.want {
+				t.Errorf("cacheDir() = %q, want %q", got, tt.want)
+			}
+			wantTemplates := filepath.Join(tt.want, source.CacheSubdir)
+			if tt.want == "" {
+				home, _ := os.UserHomeDir()
+				wantTemplates = filepath.Join(home, ".scaffold", "cache", source.CacheSubdir)
+			}
+			if got := newFetcher().CacheDir; got != wantTemplates {
+				t.Errorf("fetcher cache = %q, want %q", got, wantTemplates)
+			}
+		})
+	}
+}
+
+func TestCacheDir_IndexesApartFromTemplates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "version: \"1\"\nofficial:\n  extra:\n    source: file:./extra\n")
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	useIndex(t, "version: \"1\"\n")
+	t.Setenv(registry.ExtraIndexesEnv, "")
+	oldConfig, oldFlag := userConfig, cacheDirFlag
+	t.Cleanup(func() { userConfig, cacheDirFlag = oldConfig, oldFlag })
+	cacheDirFlag = root
+	userConfig = &config.UserConfig{Indexes: []config.IndexConfig{{URL: server.URL + "/templates.yaml"}}}
+
+	reg, err := newRegistry()
+	if err != nil {
+		t.Fatalf("newRegistry() error = %v", err)
+	}
+	if got, err := reg.Resolve(context.Background(), "extra"); err != nil || got != "file:./extra" {
+		t.Fatalf("Resolve() = %q, %v, want the extra index entry", got, err)
+	}
+
+	indexes, _ := filepath.Glob(filepath.Join(root, registry.CacheSubdir, "index-*.yaml"))
+	if len(indexes) != 1 {
+		t.Errorf("cached indexes = %v, want one under %s", indexes, registry.CacheSubdir)
+	}
+	entries, err := listCacheEntries(templateCacheDir())
+	if err != nil {
+		t.Fatalf("listCacheEntries() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("template cache entries = %+v, want none", entries)
+	}
+}
+
+func TestCacheEntries_LegacyLayout(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	templates := filepath.Join(root, source.CacheSubdir)
+	writeCacheEntry(t, templates, "repo_a", []int{10}, now)
+	writeCacheEntry(t, filepath.Join(root, registry.CacheSubdir), "index", []int{10}, now)
+	// Left by an older scaffold, which cached everything in the root
+	writeCacheEntry(t, root, "old_repo", []int{10}, now)
+
+	entries, err := cacheEntries(templates)
+	if err != nil {
+		t.Fatalf("cacheEntries() error = %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, e := range entries {
+		names[e.Name] = true
+	}
+	if len(entries) != 2 || !names["repo_a"] || !names["old_repo"] {
+		t.Errorf("entries = %+v, want repo_a and old_repo", entries)
+	}
+}
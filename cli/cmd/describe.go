@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var describeJSON bool
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <source>",
+	Short: "Show what a template declares, without generating anything",
+	Long: `Fetch a template and print its manifest: name, description, version and
+type, its variables with their types, defaults and choices, the actions it
+runs after generation, the modules it requires or conflicts with, and how
+many files it holds.
+
+Nothing is generated, so this is a safe way to inspect a template before
+running scaffold init with it.`,
+	Example: `  scaffold describe django
+  scaffold describe github:org/templates//api#v2 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDescribe,
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+
+	describeCmd.Flags().BoolVar(&describeJSON, "json", false, "Output the description as JSON")
+}
+
+func runDescribe(cmd *cobra.Command, args []string) error {
+	ctx := commandContext(cmd)
+	reg, err := newRegistry()
+	if err != nil {
+		return err
+	}
+
+	s, err := resolveSource(ctx, reg, args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	fetcher := newFetcher()
+	defer fetcher.Close()
+	if err := s.fetchWith(ctx, fetcher); err != nil {
+		return err
+	}
+	d, err := describeSource(s)
+	if err != nil {
+		return err
+	}
+
+	if describeJSON {
+		return printDescriptionJSON(os.Stdout, d)
+	}
+	printDescription(os.Stdout, d)
+	return nil
+}
+
+// description is what describe reports about a fetched template, and its
+// --json representation
+type description struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Version     string              `json:"version,omitempty"`
+	Type        string              `json:"type"`
+	Source      string              `json:"source"`
+	Extends     string              `json:"extends,omitempty"`
+	Requires    []string            `json:"requires"`
+	Conflicts   []string            `json:"conflicts"`
+	Files       describedFiles      `json:"files"`
+	Variables   []describedVariable `json:"variables"`
+	Actions     []describedAction   `json:"actions"`
+}
+
+type describedFiles struct {
+	Total       int `json:"total"`
+	Conditional int `json:"conditional"`
+	Excluded    int `json:"excluded"`
+}
+
+type describedVariable struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Required    bool     `json:"required"`
+	Choices     []string `json:"choices,omitempty"`
+}
+
+type describedAction struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Summary   string `json:"summary,omitempty"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// describeSource summarizes the manifest and files of a fetched source
+func describeSource(s *plannedSource) (*description, error) {
+	m := s.Manifest
+	count, err := template.NewProcessor(m, s.Path, "").Count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count files of %s: %w", s.Input, err)
+	}
+
+	d := &description{
+		Name:        s.name(),
+		Description: m.Description,
+		Version:     m.Version,
+		Type:        m.Type,
+		Source:      s.Source.String(), // Credentials redacted
+		Extends:     m.Extends,
+		Requires:    append([]string{}, m.Requires...),
+		Conflicts:   append([]string{}, m.Conflicts...),
+		Files:       describedFiles{Total: count.Files, Conditional: count.Conditional, Excluded: count.Excluded},
+		Variables:   []describedVariable{},
+		Actions:     []describedAction{},
+	}
+	for _, v := range m.Variables {
+		typ := v.Type
+		if typ == "" {
+			typ = "string"
+		}
+		def := v.Default
+		if v.Secret && def != "" {
+			def = secretMask
+		}
+		d.Variables = append(d.Variables, describedVariable{
+			Name:        v.Name,
+			Type:        typ,
+			Description: v.Description,
+			Default:     def,
+			Required:    v.Required,
+			Choices:     v.Choices,
+		})
+	}
+	for _, a := range m.Actions {
+		d.Actions = append(d.Actions, describedAction{
+			Name:      a.Name,
+			Type:      a.Type,
+			Summary:   actionSummary(a),
+			Condition: a.Condition,
+		})
+	}
+	return d, nil
+}
+
+// actionSummary describes an action in one line: its description, or else
+// the command it runs or the first line of its message
+func actionSummary(a config.Action) string {
+	switch {
+	case a.Description != "":
+		return a.Description
+	case a.Type == "command":
+		return strings.Join(append([]string{a.Command}, a.Args...), " ")
+	case a.Type == "message":
+		line, _, _ := strings.Cut(strings.TrimSpace(a.Message), "\n")
+		return line
+	}
+	return ""
+}
+
+// printDescription writes a description as aligned fields followed by
+// tables of the variables and actions
+func printDescription(w io.Writer, d *description) {
+	files := fmt.Sprintf("%d", d.Files.Total)
+	var notes []string
+	if d.Files.Conditional > 0 {
+		notes = append(notes, fmt.Sprintf("%d conditional", d.Files.Conditional))
+	}
+	if d.Files.Excluded > 0 {
+		notes = append(notes, fmt.Sprintf("%d excluded", d.Files.Excluded))
+	}
+	if len(notes) > 0 {
+		files += " (" + strings.Join(notes, ", ") + ")"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range [][2]string{
+		{"Name", d.Name},
+		{"Description", d.Description},
+		{"Version", d.Version},
+		{"Type", d.Type},
+		{"Source", d.Source},
+		{"Extends", d.Extends},
+		{"Requires", strings.Join(d.Requires, ", ")},
+		{"Conflicts", strings.Join(d.Conflicts, ", ")},
+		{"Files", files},
+	} {
+		fmt.Fprintf(tw, "%s:\t%s\n", row[0], orDash(row[1]))
+	}
+	tw.Flush()
+
+	if len(d.Variables) > 0 {
+		fmt.Fprintln(w, "\nVariables:")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  NAME\tTYPE\tDEFAULT\tREQUIRED\tCHOICES\tDESCRIPTION")
+		for _, v := range d.Variables {
+			required := "no"
+			if v.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", v.Name, v.Type, orDash(v.Default), required,
+				orDash(strings.Join(v.Choices, ", ")), orDash(v.Description))
+		}
+		tw.Flush()
+	}
+
+	if len(d.Actions) > 0 {
+		fmt.Fprintln(w, "\nActions:")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, a := range d.Actions {
+			summary := a.Summary
+			if a.Condition != "" {
+				summary += " (if " + a.Condition + ")"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", a.Name, a.Type, strings.TrimSpace(summary))
+		}
+		tw.Flush()
+	}
+}
+
+// printDescriptionJSON writes a description as a JSON object
+func printDescriptionJSON(w io.Writer, d *description) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode description: %w", err)
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
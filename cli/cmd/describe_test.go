@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// describeFixture fetches a fixture template and describes it
+func describeFixture(t *testing.T) *description {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "api")
+	writeTemplate(t, dir, `
+name: api
+description: REST API service
+type: module
+version: "1.2.0"
+requires: [db]
+conflicts: [grpc]
+variables:
+  - name: port
+    description: Listen port
+    default: "8080"
+  - name: auth
+    type: choice
+    choices: [jwt, session]
+    default: jwt
+  - name: api_key
+    required: true
+  - name: db_password
+    secret: true
+    default: hunter2
+files:
+  exclude: ["*.log"]
+  conditions:
+    "auth/*": auth == jwt
+actions:
+  - name: install
+    type: command
+    command: go
+    args: [mod, tidy]
+  - name: done
+    type: message
+    message: |
+      API added to {{ project_name }}
+      Run it with go run .
+    condition: port
+`, map[string]string{
+		"main.go":     "package main",
+		"auth/jwt.go": "package auth",
+		"debug.log":   "noise",
+	})
+	reg := useIndex(t, "version: \"1\"\n")
+
+	s, err := resolveSource(context.Background(), reg, "file:"+dir)
+	if err != nil {
+		t.Fatalf("resolveSource() error = %v", err)
+	}
+	if err := s.fetchWith(context.Background(), source.NewFetcher(t.TempDir())); err != nil {
+		t.Fatalf("fetchWith() error = %v", err)
+	}
+	d, err := describeSource(s)
+	if err != nil {
+		t.Fatalf("describeSource() error = %v", err)
+	}
+	d.Source = "file:./api" // Independent of the temp directory
+	return d
+}
+
+func TestPrintDescription(t *testing.T) {
+	var out bytes.Buffer
+	printDescription(&out, describeFixture(t))
+
+	want := `Name:         api
+Description:  REST API service
+Version:      1.2.0
+Type:         module
+Source:       file:./api
+Extends:      -
+Requires:     db
+Conflicts:    grpc
+Files:        2 (1 conditional, 1 excluded)
+
+Variables:
+  NAME         TYPE    DEFAULT   REQUIRED  CHOICES       DESCRIPTION
+  port         string  8080      no        -             Listen port
+  auth         choice  jwt       no        jwt, session  -
+  api_key      string  -         yes       -             -
+  db_password  string  ********  no        -             -
+
+Actions:
+  install  command  go mod tidy
+  done     message  API added to {{ project_name }} (if port)
+`
+	if out.String() != want {
+		t.Errorf("printDescription() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPrintDescriptionJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := printDescriptionJSON(&out, describeFixture(t)); err != nil {
+		t.Fatalf("printDescriptionJSON() error = %v", err)
+	}
+
+	var got description
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if got.Name != "api" || got.Type != "module" || got.Version != "1.2.0" {
+		t.Errorf("name, type, version = %q, %q, %q", got.Name, got.Type, got.Version)
+	}
+	if want := (describedFiles{Total: 2, Conditional: 1, Excluded: 1}); got.Files != want {
+		t.Errorf("files = %+v, want %+v", got.Files, want)
+	}
+	if bytes.Contains(out.Bytes(), []byte("hunter2")) {
+		t.Errorf("secret default should not be shown:\n%s", out.String())
+	}
+	if len(got.Variables) != 4 || !got.Variables[2].Required || !reflect.DeepEqual(got.Variables[1].Choices, []string{"jwt", "session"}) {
+		t.Errorf("variables = %+v", got.Variables)
+	}
+	if len(got.Actions) != 2 || got.Actions[0].Summary != "go mod tidy" || got.Actions[1].Condition != "port" {
+		t.Errorf("actions = %+v", got.Actions)
+	}
+	if !reflect.DeepEqual(got.Requires, []string{"db"}) || !reflect.DeepEqual(got.Conflicts, []string{"grpc"}) {
+		t.Errorf("requires, conflicts = %v, %v", got.Requires, got.Conflicts)
+	}
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/diff"
+	"github.com/makemore/scaffold/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffDir  string
+	diffStat bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how a project has drifted from its templates",
+	Long: `Re-render the templates and modules recorded in scaffold.lock with the
+recorded variables, and show a unified diff against the project.
+
+Only files scaffold generated are compared; other files are ignored. Secret
+variables aren't recorded, so they are read from SCAFFOLD_VAR_<NAME>; files
+using a secret that isn't set are left out.`,
+	Example: `  scaffold diff
+  scaffold diff --dir ./myapp --stat`,
+	Args: cobra.NoArgs,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffDir, "dir", "C", ".", "Project directory")
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "Show a per-file summary instead of the full diff")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	diffs, err := diffProject(commandContext(cmd), diffDir)
+	if err != nil {
+		return err
+	}
+
+	if len(diffs) == 0 {
+		fmt.Println("✅ No differences from the templates")
+		return nil
+	}
+
+	for _, d := range diffs {
+		if diffStat {
+			fmt.Printf(" %s | +%d -%d\n", d.Path, d.Inserted, d.Deleted)
+		} else {
+			fmt.Print(d.Patch)
+		}
+	}
+	if diffStat {
+		fmt.Printf(" %d files changed\n", len(diffs))
+	}
+	return nil
+}
+
+// diffProject re-renders the project in dir from its lockfile and returns the
+// generated files that differ from the render
+func diffProject(ctx context.Context, dir string) ([]fileDiff, error) {
+	lock, err := config.LoadLockfile(dir)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, fmt.Errorf("no %s found in %s", config.LockFile, dir)
+	}
+
+	plan, err := planFromLockfile(lock)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := newFetcher()
+	defer fetcher.Close()
+	if err := plan.fetch(ctx, fetcher); err != nil {
+		return nil, err
+	}
+	for _, name := range changedSources(lock, plan) {
+		log.Warn("%s has changed since the project was generated", name)
+	}
+
+	vars, missing, err := diffVariables(plan, lock.Variables, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+
+	renderDir, err := os.MkdirTemp("", "scaffold-diff-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(renderDir)
+
+	if _, err := plan.render(ctx, nil, renderDir, vars); err != nil {
+		return nil, err
+	}
+
+	paths := managedFiles(lock)
+	if len(missing) > 0 {
+		var skipped []string
+		paths, skipped, err = withoutSecretFiles(renderDir, paths)
+		if err != nil {
+			return nil, err
+		}
+		if len(skipped) > 0 {
+			log.Warn("not comparing %s, which use the secrets %s; set %s<NAME> to compare them",
+				strings.Join(skipped, ", "), strings.Join(missing, ", "), envVarPrefix)
+		}
+	}
+	return diffManagedFiles(renderDir, dir, paths)
+}
+
+// secretSentinel stands in for the secrets diff has no value for, so that
+// the files using them can be told apart and left out
+const secretSentinel = "scaffoldmissingsecret7c1e"
+
+// diffVariables returns the variables to re-render a project with. The
+// lockfile leaves out secrets, so they are read from SCAFFOLD_VAR_<NAME>
+// variables in environ; the names of those that aren't set are returned, and
+// they render as secretSentinel. Computed secrets are derived again.
+func diffVariables(plan *initPlan, locked map[string]string, environ []string) (map[string]string, []string, error) {
+	vars := make(map[string]string, len(locked))
+	for name, value := range locked {
+		vars[name] = value
+	}
+
+	env := envVariables(&config.Manifest{Variables: plan.variables()}, environ)
+	var missing []string
+	for _, v := range plan.variables() {
+		if !v.Secret {
+			continue
+		}
+		if value, ok := env[v.Name]; ok {
+			vars[v.Name] = value
+			continue
+		}
+		vars[v.Name] = secretSentinel
+		missing = append(missing, v.Name)
+	}
+
+	for _, s := range plan.declarationOrder() {
+		if err := template.ApplyComputed(s.Manifest.Computed, vars); err != nil {
+			return nil, nil, fmt.Errorf("failed to compute variables for %s: %w", s.Input, err)
+		}
+	}
+	sort.Strings(missing)
+	return vars, missing, nil
+}
+
+// withoutSecretFiles splits the given rendered files into those that can be
+// compared and those that use a secret diff has no value for
+func withoutSecretFiles(renderDir string, paths []string) (kept, skipped []string, err error) {
+	for _, p := range paths {
+		rendered, err := readIfExists(filepath.Join(renderDir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, nil, err
+		}
+		// Filters may have changed the case of the value
+		if bytes.Contains(bytes.ToLower(rendered), []byte(secretSentinel)) {
+			skipped = append(skipped, p)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, skipped, nil
+}
+
+// fileDiff is the difference between a rendered and a project file
+type fileDiff struct {
+	Path     string
+	Patch    string
+	Inserted int
+	Deleted  int
+}
+
+// diffManagedFiles compares the given files between a fresh render and the
+// project, returning only those that differ. A file missing on either side
+// is compared as empty.
+func diffManagedFiles(renderDir, projectDir string, paths []string) ([]fileDiff, error) {
+	var diffs []fileDiff
+	for _, p := range paths {
+		rendered, err := readIfExists(filepath.Join(renderDir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		current, err := readIfExists(filepath.Join(projectDir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(rendered, current) {
+			continue
+		}
+
+		if bytes.IndexByte(rendered, 0) >= 0 || bytes.IndexByte(current, 0) >= 0 {
+			diffs = append(diffs, fileDiff{Path: p, Patch: fmt.Sprintf("Binary files a/%s and b/%s differ\n", p, p)})
+			continue
+		}
+
+		a, b := string(rendered), string(current)
+		inserted, deleted := diff.Count(diff.Compute(diff.Lines(a), diff.Lines(b)))
+		diffs = append(diffs, fileDiff{
+			Path:     p,
+			Patch:    diff.Unified("a/"+p, "b/"+p, a, b),
+			Inserted: inserted,
+			Deleted:  deleted,
+		})
+	}
+	return diffs, nil
+}
+
+// readIfExists reads a file, treating a missing file as empty
+func readIfExists(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
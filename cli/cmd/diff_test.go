@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/logger"
+)
+
+func TestDiffManagedFiles(t *testing.T) {
+	renderDir := t.TempDir()
+	projectDir := t.TempDir()
+
+	write := func(dir, path, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write(renderDir, "README.md", "# myapp\n")
+	write(projectDir, "README.md", "# myapp\n")
+	write(renderDir, "settings.py", "DEBUG = False\nNAME = 'myapp'\n")
+	write(projectDir, "settings.py", "DEBUG = True\nNAME = 'myapp'\n")
+	write(projectDir, "notes.txt", "not managed\n")
+
+	diffs, err := diffManagedFiles(renderDir, projectDir, []string{"README.md", "settings.py"})
+	if err != nil {
+		t.Fatalf("diffManagedFiles() error = %v", err)
+	}
+
+	if len(diffs) != 1 {
+		t.Fatalf("len(diffs) = %d, want 1 (only the modified file)", len(diffs))
+	}
+	d := diffs[0]
+	if d.Path != "settings.py" || d.Inserted != 1 || d.Deleted != 1 {
+		t.Errorf("diff = %+v, want settings.py +1 -1", d)
+	}
+	for _, want := range []string{"--- a/settings.py", "+++ b/settings.py", "-DEBUG = False", "+DEBUG = True", " NAME = 'myapp'"} {
+		if !strings.Contains(d.Patch, want) {
+			t.Errorf("Patch missing %q:\n%s", want, d.Patch)
+		}
+	}
+}
+
+func TestDiffProject_Secrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, `
+name: base
+type: base
+variables:
+  - name: api_key
+    secret: true
+computed:
+  auth_header: "Bearer {{ api_key }}"
+`, map[string]string{
+		"README.md": "# {{ project_name }}\n",
+		".env":      "API_KEY={{ api_key }}\n",
+		"auth.txt":  "{{ auth_header | upper }}\n",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	_, stderr := useLogger(t, logger.Normal)
+	oldVars := variables
+	t.Cleanup(func() { variables = oldVars })
+	variables = []string{"api_key=s3cret"}
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	// Without the secret, the files using it are left out rather than shown
+	// as changed
+	stderr.Reset()
+	diffs, err := diffProject(context.Background(), outDir)
+	if err != nil {
+		t.Fatalf("diffProject() error = %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("diffs = %+v, want none without the secret", diffs)
+	}
+	for _, want := range []string{".env", "auth.txt", "api_key", "SCAFFOLD_VAR_"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want a note mentioning %s", stderr.String(), want)
+		}
+	}
+
+	// With the secret from the environment, they are compared
+	t.Setenv("SCAFFOLD_VAR_API_KEY", "s3cret")
+	if diffs, err := diffProject(context.Background(), outDir); err != nil || len(diffs) != 0 {
+		t.Errorf("diffProject() = %+v, %v, want no differences with the secret", diffs, err)
+	}
+	t.Setenv("SCAFFOLD_VAR_API_KEY", "rotated")
+	diffs, err = diffProject(context.Background(), outDir)
+	if err != nil {
+		t.Fatalf("diffProject() error = %v", err)
+	}
+	var paths []string
+	for _, d := range diffs {
+		paths = append(paths, d.Path)
+	}
+	if strings.Join(paths, " ") != ".env auth.txt" {
+		t.Errorf("diffs = %v, want .env and auth.txt for a different secret", paths)
+	}
+}
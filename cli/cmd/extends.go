@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/registry"
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// resolveExtends follows the base template's extends chain, fetching each
+// parent and adding it to the plan so that it is processed before the
+// template extending it. A relative file: parent is resolved against the
+// directory of the template that declares it. Cycles are reported with the
+// full chain.
+func (p *initPlan) resolveExtends(ctx context.Context, reg *registry.Registry, fetcher *source.Fetcher) error {
+	chain := []*plannedSource{p.Base}
+	var parents []*plannedSource
+
+	for child := p.Base; child.Manifest.Extends != ""; {
+		parent, err := resolveSource(ctx, reg, child.Manifest.Extends)
+		if err != nil {
+			return fmt.Errorf("%s extends %s: %w", child.name(), child.Manifest.Extends, err)
+		}
+		if parent.Source.Type == source.TypeFile && isRelativePath(parent.Source.URL) {
+			parent.Source.URL = filepath.Join(child.Path, parent.Source.URL)
+			parent.Resolved = parent.Source.String()
+		}
+
+		for i, s := range chain {
+			if s.Source.String() == parent.Source.String() {
+				names := make([]string, 0, len(chain)-i+1)
+				for _, c := range chain[i:] {
+					names = append(names, c.name())
+				}
+				names = append(names, s.name())
+				return fmt.Errorf("circular template inheritance: %s", strings.Join(names, " -> "))
+			}
+		}
+
+		if err := parent.fetchWith(ctx, fetcher); err != nil {
+			return err
+		}
+
+		chain = append(chain, parent)
+		parents = append([]*plannedSource{parent}, parents...)
+		child = parent
+	}
+
+	p.Parents = parents
+	return nil
+}
+
+// isRelativePath reports whether a file source path is relative to the
+// current directory
+func isRelativePath(path string) bool {
+	return !filepath.IsAbs(path) && !strings.HasPrefix(path, "~/")
+}
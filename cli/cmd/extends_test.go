@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/source"
+)
+
+func TestResolveExtends(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTemplate(t, filepath.Join(tmpDir, "common"), `
+name: common
+type: base
+variables:
+  - name: license
+    default: MIT
+  - name: author
+    default: nobody
+actions:
+  - name: common-note
+    type: message
+    message: from common
+`, map[string]string{
+		"LICENSE":   "{{ license }}",
+		"README.md": "common readme",
+	})
+	writeTemplate(t, filepath.Join(tmpDir, "django"), `
+name: django
+type: base
+extends: file:../common
+variables:
+  - name: author
+    default: django team
+actions:
+  - name: django-note
+    type: message
+    message: from django
+`, map[string]string{
+		"README.md": "django readme by {{ author }}",
+		"manage.py": "# manage",
+	})
+
+	reg := useIndex(t, "version: \"1\"\n")
+	plan, err := resolvePlan(context.Background(), reg, "file:"+filepath.Join(tmpDir, "django"), nil)
+	if err != nil {
+		t.Fatalf("resolvePlan() error = %v", err)
+	}
+	fetcher := source.NewFetcher(t.TempDir())
+	if err := plan.fetch(context.Background(), fetcher); err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	if err := plan.resolveExtends(context.Background(), reg, fetcher); err != nil {
+		t.Fatalf("resolveExtends() error = %v", err)
+	}
+
+	if len(plan.Parents) != 1 || plan.Parents[0].name() != "common" {
+		t.Fatalf("Parents = %v, want [common]", plan.Parents)
+	}
+
+	// The child's declaration overrides the inherited default
+	vars := map[string]string{}
+	for _, v := range plan.variables() {
+		vars[v.Name] = v.Default
+	}
+	if vars["author"] != "django team" || vars["license"] != "MIT" {
+		t.Errorf("variables = %v, want author from django and license from common", vars)
+	}
+
+	outDir := t.TempDir()
+	actions, err := plan.render(context.Background(), nil, outDir, vars)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	want := map[string]string{
+		"LICENSE":   "MIT",
+		"README.md": "django readme by django team",
+		"manage.py": "# manage",
+	}
+	for path, content := range want {
+		got, err := os.ReadFile(filepath.Join(outDir, path))
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v, want %q", path, got, err, content)
+		}
+	}
+
+	if len(actions) != 2 || actions[0].Name != "common-note" || actions[1].Name != "django-note" {
+		t.Errorf("actions = %+v, want common-note then django-note", actions)
+	}
+}
+
+func TestResolveExtends_Cycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTemplate(t, filepath.Join(tmpDir, "a"), "name: a\ntype: base\nextends: file:../b\n", nil)
+	writeTemplate(t, filepath.Join(tmpDir, "b"), "name: b\ntype: base\nextends: file:../c\n", nil)
+	writeTemplate(t, filepath.Join(tmpDir, "c"), "name: c\ntype: base\nextends: file:../a\n", nil)
+
+	reg := useIndex(t, "version: \"1\"\n")
+	plan, err := resolvePlan(context.Background(), reg, "file:"+filepath.Join(tmpDir, "a"), nil)
+	if err != nil {
+		t.Fatalf("resolvePlan() error = %v", err)
+	}
+	fetcher := source.NewFetcher(t.TempDir())
+	if err := plan.fetch(context.Background(), fetcher); err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+
+	err = plan.resolveExtends(context.Background(), reg, fetcher)
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("resolveExtends() error = %v, want cycle a -> b -> c -> a", err)
+	}
+}
@@ -4,23 +4,41 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/makemore/scaffold/internal/action"
+	"github.com/makemore/scaffold/internal/archive"
 	"github.com/makemore/scaffold/internal/config"
-	"github.com/makemore/scaffold/internal/registry"
-	"github.com/makemore/scaffold/internal/source"
+	"github.com/makemore/scaffold/internal/prompt"
 	"github.com/makemore/scaffold/internal/template"
 	"github.com/spf13/cobra"
 )
 
 var (
-	baseTemplate string
-	addModules   []string
-	variables    []string
-	outputDir    string
-	noPrompt     bool
+	baseTemplate    string
+	addModules      []string
+	templateVersion string
+	variables       []string
+	setValues       []string
+	varFiles        []string
+	varsFile        string
+	profile         string
+	recordFile      string
+	replayFile      string
+	outputDir       string
+	noPrompt        bool
+	assumeYes       bool
+	forceInit       bool
+	archiveType     string
+	keepDir         bool
+	dryRun          bool
+	showSecrets     bool
+	allowCmds       bool
+	warnUnresolved  bool
+	strictInit      bool
+	requireManifest bool
+	keepGoing       bool
 )
 
 var initCmd = &cobra.Command{
@@ -45,6 +63,10 @@ URLs:
   https://example.com/template.tar.gz
   https://example.com/template.zip
 
+Standard input (gzip tarball):
+  -
+  file:-
+
 Shorthand aliases:
   github:org/repo
   gitlab:org/repo
@@ -55,18 +77,37 @@ Shorthand aliases:
   # With base template
   scaffold init myapp --base github:org/base-template
 
+  # With a registry template pinned to a release
+  scaffold init myapp --base django --template-version v2.1.0
+
   # With modules
   scaffold init myapp \
     --base git:https://github.com/org/template \
     --add file:~/modules/postgres \
     --add github:org/mod-auth
 
+  # In the current, empty directory, named after it
+  scaffold init --output . --base github:org/template
+
   # With variables (non-interactive)
   scaffold init myapp \
     --base github:org/template \
     --var project_name=myapp \
     --var org=myorg \
-    --no-prompt`,
+    --var-file ssh_key=~/.ssh/id_ed25519.pub \
+    --no-prompt
+
+  # With grouped values for gotemplate files, as {{ .database.host }}
+  scaffold init myapp --base github:org/template \
+    --set database.host=localhost \
+    --set database.port=5432
+
+  # With the values for production, from scaffold.prod.yaml
+  scaffold init myapp --base github:org/template --vars-file vars.yaml --profile prod
+
+  # Answer the prompts once, then generate the same project in CI
+  scaffold init myapp --base github:org/template --record session.yaml
+  scaffold init myapp --base github:org/template --replay session.yaml`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runInit,
 }
@@ -76,19 +117,60 @@ func init() {
 
 	initCmd.Flags().StringVarP(&baseTemplate, "base", "b", "", "Base template source")
 	initCmd.Flags().StringArrayVarP(&addModules, "add", "a", nil, "Additional modules to layer")
-	initCmd.Flags().StringArrayVarP(&variables, "var", "v", nil, "Variables in key=value format")
+	initCmd.Flags().StringVar(&templateVersion, "template-version", "", "Git ref or version range of the base template, in place of a #ref in its source")
+	initCmd.Flags().StringArrayVarP(&variables, "var", "v", nil, "Variables in key=value format; key=@path reads the value from a file, key=@@value keeps a leading @")
+	initCmd.Flags().StringArrayVar(&setValues, "set", nil, "Nested values in dotted.key=value format, seen by gotemplate files as {{ .dotted.key }}")
+	initCmd.Flags().StringArrayVar(&varFiles, "var-file", nil, "Read a variable's value from a file, as key=path")
+	initCmd.Flags().StringVar(&varsFile, "vars-file", "", "YAML or JSON file of variable values")
+	initCmd.Flags().StringVar(&profile, "profile", "", "Apply the values of a profile: scaffold.<profile>.yaml, or the profiles section of --vars-file")
+	initCmd.Flags().StringVar(&recordFile, "record", "", "Write the variable values, as answered, to a vars file for --replay")
+	initCmd.Flags().StringVar(&replayFile, "replay", "", "Generate without prompting from the values a --record run wrote")
+	initCmd.MarkFlagsMutuallyExclusive("replay", "vars-file")
+	initCmd.MarkFlagsMutuallyExclusive("replay", "record")
 	initCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory (defaults to project name)")
 	initCmd.Flags().BoolVar(&noPrompt, "no-prompt", false, "Disable interactive prompts")
+	initCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip confirmation prompts")
+	initCmd.Flags().BoolVarP(&forceInit, "force", "f", false, "Generate into an existing output directory, overwriting files")
+	initCmd.Flags().StringVar(&archiveType, "archive", "", "Package the project as an archive (tar.gz or zip)")
+	initCmd.Flags().BoolVar(&keepDir, "keep-dir", false, "Keep the project directory when using --archive")
+	initCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be created or overwritten without writing anything")
+	initCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Show secret variable values in the recap instead of masking them")
+	initCmd.Flags().BoolVar(&allowCmds, "allow-commands", false, "Run the template's command actions without asking")
+	initCmd.Flags().BoolVarP(&keepGoing, "keep-going", "k", false, "Generate the other files when some fail, then report every failure")
+	initCmd.Flags().BoolVar(&requireManifest, "require-manifest", false, "Fail for templates without a scaffold.yaml instead of generating all their files")
+	initCmd.Flags().BoolVar(&strictInit, "strict", false, "Fail when modules declare a variable with a conflicting type or choices")
+	initCmd.Flags().BoolVar(&warnUnresolved, "warn-unresolved", false, "Warn about {{ variable }} placeholders left in generated files (default on when prompting)")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
+	ctx := commandContext(cmd)
+	_, prompting := replaySession()
 	projectName := ""
 	if len(args) > 0 {
 		projectName = args[0]
 	}
 
+	// Without a name or --output, an empty current directory is generated
+	// into in place
+	outDir := outputDir
+	if projectName == "" && outDir == "" && dirIsEmpty(".") {
+		outDir = "."
+	}
+	if outDir != "" {
+		dir, err := outputPath(outDir)
+		if err != nil {
+			return err
+		}
+		outDir = dir
+
+		// The project is named after its directory unless told otherwise
+		if projectName == "" {
+			projectName = filepath.Base(outDir)
+		}
+	}
+
 	// If no project name and interactive mode, prompt for it
-	if projectName == "" && !noPrompt {
+	if projectName == "" && prompting {
 		prompt := &survey.Input{Message: "Project name:"}
 		if err := survey.AskOne(prompt, &projectName, survey.WithValidator(survey.Required)); err != nil {
 			return err
@@ -99,53 +181,48 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("project name is required (or use interactive mode)")
 	}
 
-	// Determine output directory
-	outDir := outputDir
+	// Default the output directory to the project name
 	if outDir == "" {
 		outDir = projectName
 	}
 
-	// Check if output directory already exists
-	if _, err := os.Stat(outDir); err == nil {
-		return fmt.Errorf("directory %s already exists", outDir)
+	// Validate the archive format before doing any work
+	var format archive.Format
+	archivePath := ""
+	if archiveType != "" {
+		f, err := archive.ParseFormat(archiveType)
+		if err != nil {
+			return err
+		}
+		format = f
+		archivePath = filepath.Clean(outDir) + "." + string(format)
+		if _, err := os.Stat(archivePath); err == nil && !forceInit {
+			return fmt.Errorf("archive %s already exists (use --force to overwrite it)", archivePath)
+		}
 	}
+	writeDir := archivePath == "" || keepDir
 
-	// If no base template specified, prompt or show list
-	if baseTemplate == "" && !noPrompt {
-		reg := registry.New("")
-		templates, _ := reg.List()
-
-		// Build options list
-		options := make([]string, 0, len(templates)+1)
-		names := make([]string, 0, len(templates))
-		for name := range templates {
-			names = append(names, name)
-		}
-		sort.Strings(names)
+	// Generate into an existing directory only when it is empty
+	if _, err := os.Stat(outDir); err == nil && writeDir && !forceInit && !dirIsEmpty(outDir) {
+		return fmt.Errorf("directory %s already exists and is not empty (use --force to generate into it)", outDir)
+	}
 
-		for _, name := range names {
-			entry := templates[name]
-			options = append(options, fmt.Sprintf("%s - %s", name, entry.Description))
-		}
-		options = append(options, "Other (enter URL)")
+	// Fall back to the configured default base template
+	if baseTemplate == "" {
+		baseTemplate = userConfig.Base
+	}
 
-		var selection string
-		prompt := &survey.Select{
-			Message: "Select a template:",
-			Options: options,
-		}
-		if err := survey.AskOne(prompt, &selection); err != nil {
+	// If no base template specified, prompt or show list
+	if baseTemplate == "" && prompting {
+		reg, err := newRegistry()
+		if err != nil {
 			return err
 		}
+		templates, _ := reg.List(ctx)
 
-		if selection == "Other (enter URL)" {
-			urlPrompt := &survey.Input{Message: "Template URL:"}
-			if err := survey.AskOne(urlPrompt, &baseTemplate, survey.WithValidator(survey.Required)); err != nil {
-				return err
-			}
-		} else {
-			// Extract template name from selection
-			baseTemplate = strings.SplitN(selection, " - ", 2)[0]
+		baseTemplate, err = pickTemplate(templates)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -153,204 +230,322 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--base template is required (or use interactive mode)")
 	}
 
-	fmt.Printf("🚀 Creating project: %s\n", projectName)
+	log.Progress("🚀 Creating project: %s", projectName)
 
-	// Resolve template shorthand to full source
-	reg := registry.New("")
-	resolvedSource, err := reg.Resolve(baseTemplate)
+	// Resolve all sources up front so mistakes surface before fetching
+	reg, err := newRegistry()
+	if err != nil {
+		return err
+	}
+	plan, err := resolvePlan(ctx, reg, baseTemplate, addModules)
 	if err != nil {
-		return fmt.Errorf("failed to resolve template: %w", err)
+		return err
+	}
+	if templateVersion != "" {
+		if err := plan.Base.pinVersion(log, templateVersion); err != nil {
+			return err
+		}
 	}
+	plan.KeepGoing = keepGoing
 
-	fmt.Printf("📦 Template: %s\n", resolvedSource)
+	// Re-running the command that generated the project changes nothing,
+	// which its lockfile tells without fetching anything
+	if archivePath == "" && !dryRun && !prompting && upToDate(ctx, outDir, plan, projectName) {
+		log.Result("✅ %s is already up to date", outDir)
+		return nil
+	}
+	printPlan(log.ProgressWriter(), plan)
 
-	// Parse the source URI
-	src, err := source.Parse(resolvedSource)
-	if err != nil {
-		return fmt.Errorf("failed to parse source: %w", err)
+	if prompting && !assumeYes {
+		proceed := true
+		confirm := &survey.Confirm{Message: "Proceed?", Default: true}
+		if err := survey.AskOne(confirm, &proceed); err != nil {
+			return err
+		}
+		if !proceed {
+			return fmt.Errorf("aborted")
+		}
 	}
 
-	// Fetch the template
-	fmt.Println("⬇️  Fetching template...")
-	fetcher := source.NewFetcher("")
-	templatePath, err := fetcher.Fetch(src)
-	if err != nil {
-		return fmt.Errorf("failed to fetch template: %w", err)
+	// Fetch all templates and load their manifests
+	log.Progress("⬇️  Fetching templates...")
+	fetcher := newFetcher()
+	defer fetcher.Close()
+	if err := plan.fetch(ctx, fetcher); err != nil {
+		return err
+	}
+	if err := plan.resolveExtends(ctx, reg, fetcher); err != nil {
+		return err
+	}
+	if err := plan.orderModules(); err != nil {
+		return err
+	}
+	if err := plan.checkOutputOutside(outDir); err != nil {
+		return err
 	}
 
-	// Load the manifest
-	manifest, err := config.LoadManifest(templatePath)
-	if err != nil {
-		return fmt.Errorf("failed to load manifest: %w", err)
+	// Modules reusing a variable name for something else are likely clashes
+	if conflicts := plan.variableConflicts(); len(conflicts) > 0 {
+		if strictInit {
+			return fmt.Errorf("conflicting variable declarations:\n  %s", strings.Join(conflicts, "\n  "))
+		}
+		for _, c := range conflicts {
+			log.Warn("%s", c)
+		}
 	}
 
+	// Merge variables declared by the base and all modules
+	merged := &config.Manifest{Variables: plan.variables()}
+
 	// Collect variables
-	vars := collectVariables(manifest, projectName)
-
-	// Prompt for missing required variables
-	if !noPrompt {
-		for _, v := range manifest.Variables {
-			if _, ok := vars[v.Name]; !ok {
-				message := v.Name
-				if v.Description != "" {
-					message = v.Description
-				}
-
-				var val string
-				var err error
-
-				switch v.Type {
-				case "select", "choice":
-					if len(v.Choices) > 0 {
-						prompt := &survey.Select{
-							Message: message,
-							Options: v.Choices,
-							Default: v.Default,
-						}
-						err = survey.AskOne(prompt, &val)
-					} else {
-						prompt := &survey.Input{Message: message, Default: v.Default}
-						err = survey.AskOne(prompt, &val)
-					}
-				case "confirm", "boolean":
-					var confirm bool
-					prompt := &survey.Confirm{
-						Message: message,
-						Default: v.Default == "true",
-					}
-					err = survey.AskOne(prompt, &confirm)
-					if confirm {
-						val = "true"
-					} else {
-						val = "false"
-					}
-				default:
-					prompt := &survey.Input{Message: message, Default: v.Default}
-					if v.Required {
-						err = survey.AskOne(prompt, &val, survey.WithValidator(survey.Required))
-					} else {
-						err = survey.AskOne(prompt, &val)
-					}
-				}
-
-				if err != nil {
-					return err
-				}
-				vars[v.Name] = val
+	vars, err := collectVariables(merged, projectName, userConfig.Variables)
+	if err != nil {
+		return err
+	}
+
+	// Prompt once for all missing variables
+	if prompting {
+		printPendingVariables(log.ProgressWriter(), merged.Variables, vars)
+		vars, err = prompt.PromptForVariables(merged, vars)
+		if err != nil {
+			return err
+		}
+
+		// Recap the values and let them be changed until they are confirmed
+		if !assumeYes {
+			vars, err = reviewVariables(merged, vars, hiddenSecrets(plan.secrets()))
+			if err != nil {
+				return err
 			}
 		}
 	}
 
-	// Create output directory
-	if err := os.MkdirAll(outDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if recordFile != "" {
+		if err := recordSession(recordFile, vars, plan.secrets()); err != nil {
+			return err
+		}
 	}
 
-	// Process template
-	fmt.Println("📝 Processing template...")
-	processor := template.NewProcessor(manifest, templatePath, outDir)
-	processor.SetVariables(vars)
-
-	if err := processor.Process(); err != nil {
-		return fmt.Errorf("failed to process template: %w", err)
+	// Derive computed variables. project_module is built in unless a
+	// manifest computes its own.
+	if !plan.computes(projectModuleVar) {
+		setProjectModule(vars)
+	}
+	for _, s := range plan.declarationOrder() {
+		if err := template.ApplyComputed(s.Manifest.Computed, vars); err != nil {
+			return fmt.Errorf("failed to compute variables for %s: %w", s.Input, err)
+		}
 	}
 
-	// Process additional modules
-	for _, moduleSource := range addModules {
-		fmt.Printf("📦 Adding module: %s\n", moduleSource)
+	// Generate into a staging directory so a failure leaves nothing behind. A
+	// dry run renders into a temporary directory instead, away from outDir.
+	var staging string
+	if dryRun {
+		staging, err = os.MkdirTemp("", "scaffold-dry-run-")
+	} else {
+		staging, err = stageDir(outDir)
+	}
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
 
-		// Resolve module source
-		resolvedModule, err := reg.Resolve(moduleSource)
-		if err != nil {
-			return fmt.Errorf("failed to resolve module %s: %w", moduleSource, err)
+	actions, err := plan.render(ctx, log, staging, vars)
+	if err != nil {
+		return err
+	}
+	if shouldWarnUnresolved(cmd, prompting) {
+		for _, u := range plan.result().Unresolved {
+			log.Warn("unresolved %s in %s", u.Token, u.File)
 		}
+	}
 
-		// Parse the module source
-		moduleSrc, err := source.Parse(resolvedModule)
-		if err != nil {
-			return fmt.Errorf("failed to parse module source: %w", err)
-		}
+	failed := plan.fileErrors()
 
-		// Fetch the module
-		modulePath, err := fetcher.Fetch(moduleSrc)
+	if dryRun {
+		changes, err := previewChanges(staging, outDir, plan.result())
 		if err != nil {
-			return fmt.Errorf("failed to fetch module: %w", err)
+			return err
 		}
+		printChanges(os.Stdout, changes)
+		return incomplete(failed)
+	}
 
-		// Load module manifest
-		moduleManifest, err := config.LoadManifest(modulePath)
-		if err != nil {
-			return fmt.Errorf("failed to load module manifest: %w", err)
-		}
+	// Record what was generated for update/remove
+	lock, err := buildLockfile(plan, staging, vars)
+	if err != nil {
+		return err
+	}
+	if err := config.SaveLockfile(staging, lock); err != nil {
+		return err
+	}
 
-		// Prompt for module-specific variables
-		if !noPrompt {
-			for _, v := range moduleManifest.Variables {
-				if _, ok := vars[v.Name]; !ok {
-					message := v.Name
-					if v.Description != "" {
-						message = v.Description
-					}
-
-					var val string
-					prompt := &survey.Input{Message: message, Default: v.Default}
-					if err := survey.AskOne(prompt, &val); err != nil {
-						return err
-					}
-					vars[v.Name] = val
-				}
-			}
+	// Version ranges are only resolved by fetching, so a re-run asking for one
+	// is compared once the project is rendered
+	if archivePath == "" && failed == nil && lockUnchanged(outDir, lock) {
+		log.Result("✅ %s is already up to date", outDir)
+		return nil
+	}
+
+	if archivePath != "" {
+		if err := archive.Create(format, staging, archivePath, filepath.Base(filepath.Clean(outDir))); err != nil {
+			return err
 		}
+		log.Result("\n📦 Archive created at: %s", archivePath)
+		if !writeDir {
+			log.Result("   %s", formatSummary(plan.result()))
+			return incomplete(failed)
+		}
+	}
 
-		// Process module (layer on top of existing files)
-		moduleProcessor := template.NewProcessor(moduleManifest, modulePath, outDir)
-		moduleProcessor.SetVariables(vars)
+	if err := commitStaged(staging, outDir); err != nil {
+		return err
+	}
 
-		if err := moduleProcessor.Process(); err != nil {
-			return fmt.Errorf("failed to process module %s: %w", moduleSource, err)
-		}
+	// The project is missing files, so its actions may well fail too
+	if failed != nil {
+		log.Result("\n⚠️  Project created at %s without some files", outDir)
+		log.Result("   %s", formatSummary(plan.result()))
+		return incomplete(failed)
+	}
 
-		// Collect module actions
-		manifest.Actions = append(manifest.Actions, moduleManifest.Actions...)
+	// Run post-generation actions in the finished project
+	executor := &action.Executor{
+		Dir:             outDir,
+		Vars:            vars,
+		Log:             log,
+		AllowCommands:   allowCmds || userConfig.AllowCommands,
+		AllowedCommands: userConfig.AllowedCommands,
+		Interactive:     prompting,
+		Editor:          userConfig.Editor,
+	}
+	if prompting && !assumeYes {
+		executor.Confirm = confirmAction
+		executor.ConsentCommands = consentToCommands
+	}
+	if err := executor.Run(actions); err != nil {
+		return err
 	}
 
-	fmt.Printf("\n✅ Project created at: %s\n", outDir)
-	fmt.Println("\nNext steps:")
-	fmt.Printf("  cd %s\n", outDir)
+	log.Result("\n✅ Project created at: %s", outDir)
+	log.Result("   %s", formatSummary(plan.result()))
+	log.Result("\nNext steps:")
+	log.Result("  cd %s", outDir)
 
 	// Show post-generation actions
-	for _, action := range manifest.Actions {
+	for _, action := range actions {
 		if action.Type == "message" {
-			fmt.Printf("  %s\n", action.Message)
+			log.Result("  %s", action.Message)
 		}
 	}
 
 	return nil
 }
 
-func collectVariables(manifest *config.Manifest, projectName string) map[string]string {
-	vars := make(map[string]string)
+// incomplete reports the files --keep-going left out, or returns nil when
+// there were none
+func incomplete(failed error) error {
+	if failed == nil {
+		return nil
+	}
+	return fmt.Errorf("some files could not be generated, post-generation actions were skipped:\n%w", failed)
+}
 
-	// Set project_name and common variants
-	vars["project_name"] = projectName
-	vars["project_slug"] = strings.ReplaceAll(strings.ToLower(projectName), "-", "_")
+// outputPath makes an output directory given as . or .. absolute, so that it
+// has a real name and a parent to stage the project in
+func outputPath(dir string) (string, error) {
+	if base := filepath.Base(dir); base != "." && base != ".." {
+		return dir, nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	return abs, nil
+}
 
-	// Parse --var flags
-	for _, v := range variables {
-		parts := strings.SplitN(v, "=", 2)
-		if len(parts) == 2 {
-			vars[parts[0]] = parts[1]
-		}
+// dirIsEmpty reports whether path is a directory with no entries
+func dirIsEmpty(path string) bool {
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) == 0
+}
+
+// shouldWarnUnresolved reports whether to warn about leftover placeholders. Unless
+// --warn-unresolved is given explicitly, it is on when prompting.
+func shouldWarnUnresolved(cmd *cobra.Command, prompting bool) bool {
+	if cmd.Flags().Changed("warn-unresolved") {
+		return warnUnresolved
 	}
+	return prompting
+}
 
-	// Apply defaults for missing variables
-	for _, v := range manifest.Variables {
-		if _, ok := vars[v.Name]; !ok && v.Default != "" {
-			vars[v.Name] = v.Default
+// Choices offered after the variables are recapped
+const (
+	reviewAccept = "Use these values"
+	reviewChange = "Change a value"
+	reviewReask  = "Answer every prompt again"
+)
+
+// reviewVariables recaps the values and asks whether to use them, change one
+// of them or answer every prompt again, until they are accepted
+func reviewVariables(manifest *config.Manifest, vars map[string]string, secrets map[string]bool) (map[string]string, error) {
+	choices := []string{reviewAccept}
+	if len(manifest.Variables) > 0 {
+		choices = append(choices, reviewChange, reviewReask)
+	}
+
+	for {
+		fmt.Fprint(log.ProgressWriter(), formatRecap(vars, secrets))
+		choice := reviewAccept
+		review := &survey.Select{Message: "Use these values?", Options: choices, Default: reviewAccept}
+		if err := survey.AskOne(review, &choice); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch choice {
+		case reviewAccept:
+			return vars, nil
+		case reviewChange:
+			index := 0
+			pick := &survey.Select{Message: "Change which value?", Options: variableOptions(manifest, vars, secrets)}
+			if err := survey.AskOne(pick, &index); err != nil {
+				return nil, err
+			}
+			vars, err = prompt.PromptForVariables(editVariable(manifest, vars, manifest.Variables[index].Name))
+		case reviewReask:
+			vars, err = prompt.PromptForVariables(reaskVariables(manifest, vars))
 		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// confirmAction asks whether to run an optional action
+func confirmAction(message string) (bool, error) {
+	run := true
+	if err := survey.AskOne(&survey.Confirm{Message: message, Default: true}, &run); err != nil {
+		return false, err
 	}
+	return run, nil
+}
 
-	return vars
+// consentToCommands lists the commands the templates want to run and asks
+// once whether to run them
+func consentToCommands(commands []string) (bool, error) {
+	w := log.ProgressWriter()
+	fmt.Fprintln(w, "⚠️  The template wants to run these commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\n", c)
+	}
+
+	run := false
+	confirm := &survey.Confirm{Message: "Run these commands?", Default: false}
+	if err := survey.AskOne(confirm, &run); err != nil {
+		return false, err
+	}
+	return run, nil
 }
 
 // absPath returns the absolute path, handling ~ expansion
@@ -362,4 +557,3 @@ func absPath(path string) string {
 	abs, _ := filepath.Abs(path)
 	return abs
 }
-
@@ -0,0 +1,879 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+)
+
+// setInitFlags sets the init flags for a test and restores them afterwards
+func setInitFlags(t *testing.T, base, output string) {
+	t.Helper()
+
+	oldBase, oldOutput, oldNoPrompt := baseTemplate, outputDir, noPrompt
+	t.Cleanup(func() {
+		baseTemplate, outputDir, noPrompt = oldBase, oldOutput, oldNoPrompt
+	})
+	baseTemplate, outputDir, noPrompt = base, output, true
+}
+
+// useLogger captures log output at the given level for the duration of a test
+func useLogger(t *testing.T, level logger.Level) (stdout, stderr *bytes.Buffer) {
+	t.Helper()
+
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	old := log
+	t.Cleanup(func() { log = old })
+	log = logger.New(stdout, stderr, level)
+	return stdout, stderr
+}
+
+func TestRunInit_Quiet(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{
+		"README.md": "# {{ project_name }}",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	stdout, stderr := useLogger(t, logger.Quiet)
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("quiet output = %q / %q, want none", stdout.String(), stderr.String())
+	}
+	got, err := os.ReadFile(filepath.Join(outDir, "README.md"))
+	if err != nil || string(got) != "# myapp" {
+		t.Errorf("README.md = %q, %v, want generated project", got, err)
+	}
+}
+
+func TestRunInit_ProgressToStderr(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"a.txt": "a"})
+	useIndex(t, "version: \"1\"\n")
+
+	setInitFlags(t, "file:"+baseDir, filepath.Join(tmpDir, "myapp"))
+	stdout, stderr := useLogger(t, logger.Verbose)
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	if !strings.Contains(stderr.String(), "Creating project") || !strings.Contains(stderr.String(), "wrote a.txt") {
+		t.Errorf("stderr = %q, want progress and debug output", stderr.String())
+	}
+	if strings.Contains(stdout.String(), "Creating project") || !strings.Contains(stdout.String(), "Project created") {
+		t.Errorf("stdout = %q, want only results", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "1 files created, 0 skipped, 0 overwritten") {
+		t.Errorf("stdout = %q, want a summary", stdout.String())
+	}
+}
+
+func TestRunInit_FailureLeavesNoOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	moduleDir := filepath.Join(tmpDir, "broken")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	writeTemplate(t, moduleDir, "name: broken\ntype: module\nengine: gotemplate\n", map[string]string{"bad.txt": "{{ if }}"})
+	useIndex(t, "version: \"1\"\n")
+
+	projects := filepath.Join(tmpDir, "projects")
+	outDir := filepath.Join(projects, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	oldModules := addModules
+	t.Cleanup(func() { addModules = oldModules })
+	addModules = []string{"file:" + moduleDir}
+	useLogger(t, logger.Quiet)
+
+	if err := runInit(initCmd, []string{"myapp"}); err == nil {
+		t.Fatal("runInit() should return error for a broken module")
+	}
+
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("%s should not exist after a failed init", outDir)
+	}
+	entries, _ := os.ReadDir(projects)
+	for _, e := range entries {
+		t.Errorf("leftover %s after a failed init", e.Name())
+	}
+}
+
+func TestRunInit_OutputInsideTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	useIndex(t, "version: \"1\"\n")
+	useLogger(t, logger.Quiet)
+	link := filepath.Join(tmpDir, "link")
+	if err := os.Symlink(baseDir, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		outDir  string
+		wantErr bool
+	}{
+		{"subdirectory", filepath.Join(baseDir, "out", "myapp"), true},
+		{"the template itself", baseDir, true},
+		{"through a symlink", filepath.Join(link, "myapp"), true},
+		{"sibling with a common prefix", filepath.Join(tmpDir, "base-app"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setInitFlags(t, "file:"+baseDir, tt.outDir)
+			forceInit = true // The template itself isn't empty
+			defer func() { forceInit = false }()
+
+			err := runInit(initCmd, []string{"myapp"})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("runInit() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "is inside the template") {
+				t.Fatalf("runInit() error = %v, want the output rejected", err)
+			}
+			if _, err := os.Stat(filepath.Join(baseDir, "out")); !os.IsNotExist(err) {
+				t.Error("nothing should be written into the template")
+			}
+			entries, _ := os.ReadDir(baseDir)
+			if len(entries) != 2 {
+				t.Errorf("template holds %d entries after a rejected init, want 2", len(entries))
+			}
+		})
+	}
+}
+
+func TestRunInit_KeepGoing(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	moduleDir := filepath.Join(tmpDir, "broken")
+	writeTemplate(t, baseDir, "name: base\ntype: base\nactions:\n  - name: touch\n    type: command\n    command: touch ran\n", map[string]string{
+		"README.md": "# {{ project_name }}",
+	})
+	writeTemplate(t, moduleDir, "name: broken\ntype: module\nengine: gotemplate\n", map[string]string{
+		"bad.txt":  "{{ if }}",
+		"good.txt": "{{ .project_name }}",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	oldModules, oldKeepGoing, oldAllow := addModules, keepGoing, allowCmds
+	t.Cleanup(func() { addModules, keepGoing, allowCmds = oldModules, oldKeepGoing, oldAllow })
+	addModules = []string{"file:" + moduleDir}
+	keepGoing, allowCmds = true, true
+	useLogger(t, logger.Quiet)
+
+	err := runInit(initCmd, []string{"myapp"})
+	if err == nil || !strings.Contains(err.Error(), "file:"+moduleDir+": bad.txt:1:") {
+		t.Fatalf("runInit() error = %v, want the failed file reported", err)
+	}
+
+	for file, want := range map[string]string{"README.md": "# myapp", "good.txt": "myapp"} {
+		if got, err := os.ReadFile(filepath.Join(outDir, file)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", file, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "bad.txt")); !os.IsNotExist(err) {
+		t.Error("bad.txt should not be generated")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "ran")); !os.IsNotExist(err) {
+		t.Error("actions should not run for an incomplete project")
+	}
+}
+
+func TestRunInit_Force(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}", "logs/.scaffold-keep": ""})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	for name, content := range map[string]string{"README.md": "old", "notes.txt": "mine"} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	setInitFlags(t, "file:"+baseDir, outDir)
+	useLogger(t, logger.Quiet)
+
+	if err := runInit(initCmd, []string{"myapp"}); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("runInit() error = %v, want already exists error mentioning --force", err)
+	}
+
+	oldForce := forceInit
+	t.Cleanup(func() { forceInit = oldForce })
+	forceInit = true
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(outDir, "README.md")); string(got) != "# myapp" {
+		t.Errorf("README.md = %q, want regenerated", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(outDir, "notes.txt")); string(got) != "mine" {
+		t.Errorf("notes.txt = %q, want untouched", got)
+	}
+	if info, err := os.Stat(filepath.Join(outDir, "logs")); err != nil || !info.IsDir() {
+		t.Errorf("empty directory logs should be generated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, config.LockFile)); err != nil {
+		t.Errorf("lockfile should be written: %v", err)
+	}
+}
+
+func TestRunInit_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	useIndex(t, "version: \"1\"\n")
+
+	parent := filepath.Join(tmpDir, "projects")
+	setInitFlags(t, "file:"+baseDir, filepath.Join(parent, "myapp"))
+	useLogger(t, logger.Quiet)
+	oldDryRun := dryRun
+	t.Cleanup(func() { dryRun = oldDryRun })
+	dryRun = true
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if _, err := os.Stat(parent); !os.IsNotExist(err) {
+		t.Errorf("dry run should not create %s", parent)
+	}
+}
+
+func TestRunInit_UpToDate(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\nvariables:\n  - name: author\n", map[string]string{
+		"README.md": "# {{ project_name }} by {{ author }}",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	oldForce, oldVars := forceInit, variables
+	t.Cleanup(func() { forceInit, variables = oldForce, oldVars })
+	forceInit = true
+	variables = []string{"author=alice"}
+	useLogger(t, logger.Quiet)
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	// Backdate the project so any rewrite shows up as a newer mtime
+	readme := filepath.Join(outDir, "README.md")
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, path := range []string{readme, filepath.Join(outDir, config.LockFile)} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+	}
+
+	stdout, stderr := useLogger(t, logger.Normal)
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "already up to date") {
+		t.Errorf("stdout = %q, want an up to date report", stdout.String())
+	}
+	if strings.Contains(stderr.String(), "Fetching") {
+		t.Errorf("stderr = %q, want nothing fetched on an identical re-run", stderr.String())
+	}
+	for _, path := range []string{readme, filepath.Join(outDir, config.LockFile)} {
+		if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(old) {
+			t.Errorf("%s was rewritten on an identical re-run", path)
+		}
+	}
+
+	variables = []string{"author=bob"}
+	stdout, _ = useLogger(t, logger.Normal)
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if strings.Contains(stdout.String(), "already up to date") {
+		t.Errorf("stdout = %q, want a rewrite for a changed variable", stdout.String())
+	}
+	if got, _ := os.ReadFile(readme); string(got) != "# myapp by bob" {
+		t.Errorf("README.md = %q, want regenerated with the new author", got)
+	}
+
+	// A local template is read again to tell whether it was changed
+	if err := os.WriteFile(filepath.Join(baseDir, "README.md"), []byte("# {{ project_name }}, by {{ author }}"), 0644); err != nil {
+		t.Fatalf("Failed to edit template: %v", err)
+	}
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if got, _ := os.ReadFile(readme); string(got) != "# myapp, by bob" {
+		t.Errorf("README.md = %q, want regenerated from the edited template", got)
+	}
+}
+
+func TestRunInit_UpToDateGitRef(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo")
+	writeTemplate(t, repo, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	gitCommitAll(t, repo)
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	branch, first := git("symbolic-ref", "--short", "HEAD"), git("rev-parse", "HEAD")
+
+	useIndex(t, "version: \"1\"\n")
+	oldConfig, oldForce, oldNoCache := userConfig, forceInit, noCache
+	t.Cleanup(func() { userConfig, forceInit, noCache = oldConfig, oldForce, oldNoCache })
+	userConfig = &config.UserConfig{CacheDir: t.TempDir()}
+	forceInit = true
+
+	sources := map[string]string{
+		"default branch": "git:file://" + repo,
+		"branch":         "git:file://" + repo + "#" + branch,
+		"commit":         "git:file://" + repo + "#" + first,
+	}
+	outDirs := make(map[string]string)
+	for name, src := range sources {
+		outDirs[name] = filepath.Join(t.TempDir(), "myapp")
+		setInitFlags(t, src, outDirs[name])
+		useLogger(t, logger.Quiet)
+		if err := runInit(initCmd, []string{"myapp"}); err != nil {
+			t.Fatalf("%s: runInit() error = %v", name, err)
+		}
+		stdout, _ := useLogger(t, logger.Normal)
+		if err := runInit(initCmd, []string{"myapp"}); err != nil {
+			t.Fatalf("%s: runInit() error = %v", name, err)
+		}
+		if !strings.Contains(stdout.String(), "already up to date") {
+			t.Errorf("%s: stdout = %q, want an up to date report", name, stdout.String())
+		}
+	}
+
+	// Move the branch; only the sources following it have to be generated
+	// again, from a fresh clone as the cached one isn't updated
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("# {{ project_name }}!"), 0644); err != nil {
+		t.Fatalf("Failed to edit template: %v", err)
+	}
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-am", "edit")
+	noCache = true
+
+	for name, src := range sources {
+		setInitFlags(t, src, outDirs[name])
+		stdout, _ := useLogger(t, logger.Normal)
+		if err := runInit(initCmd, []string{"myapp"}); err != nil {
+			t.Fatalf("%s: runInit() error = %v", name, err)
+		}
+		want := "# myapp!"
+		if name == "commit" {
+			want = "# myapp"
+			if !strings.Contains(stdout.String(), "already up to date") {
+				t.Errorf("%s: stdout = %q, want an up to date report", name, stdout.String())
+			}
+		} else if strings.Contains(stdout.String(), "already up to date") {
+			t.Errorf("%s: stdout = %q, want a rewrite for the moved branch", name, stdout.String())
+		}
+		if got, _ := os.ReadFile(filepath.Join(outDirs[name], "README.md")); string(got) != want {
+			t.Errorf("%s: README.md = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRunInit_InPlace(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	useIndex(t, "version: \"1\"\n")
+	useLogger(t, logger.Quiet)
+
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{name: "output dot", output: "."},
+		{name: "no name in empty directory", output: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "myrepo")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			t.Chdir(dir)
+			setInitFlags(t, "file:"+baseDir, tt.output)
+
+			if err := runInit(initCmd, nil); err != nil {
+				t.Fatalf("runInit() error = %v", err)
+			}
+
+			if got, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(got) != "# myrepo" {
+				t.Errorf("README.md = %q, want named after the directory", got)
+			}
+			entries, _ := os.ReadDir(filepath.Dir(dir))
+			if len(entries) != 1 {
+				t.Errorf("parent directory has %d entries, want only the project", len(entries))
+			}
+		})
+	}
+}
+
+func TestRunInit_InPlaceNotEmpty(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	useIndex(t, "version: \"1\"\n")
+	useLogger(t, logger.Quiet)
+
+	dir := filepath.Join(tmpDir, "myrepo")
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	t.Chdir(dir)
+	setInitFlags(t, "file:"+baseDir, ".")
+
+	err := runInit(initCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("runInit() error = %v, want not empty error", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); !os.IsNotExist(err) {
+		t.Error("nothing should be generated into a non-empty directory")
+	}
+
+	// Without --output, a non-empty directory still needs a project name
+	setInitFlags(t, "file:"+baseDir, "")
+	if err := runInit(initCmd, nil); err == nil || !strings.Contains(err.Error(), "project name is required") {
+		t.Errorf("runInit() error = %v, want project name required", err)
+	}
+}
+
+// gitTemplateRepo commits a repository holding a template at its root and
+// another in template/, both with a .github directory
+func gitTemplateRepo(t *testing.T) string {
+	t.Helper()
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	writeTemplate(t, repo, "name: root\ntype: base\n", map[string]string{
+		"ROOT.md":                           "root",
+		"template/scaffold.yaml":            "name: sub\ntype: base\n",
+		"template/README.md":                "# {{ project_name }}",
+		"template/.github/workflows/ci.yml": "name: {{ project_name }}",
+		"template/.gitignore":               "*.log",
+	})
+	gitCommitAll(t, repo)
+	return repo
+}
+
+// gitCommitAll makes dir a git repository with everything in it committed
+func gitCommitAll(t *testing.T, dir string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestRunInit_GitSource(t *testing.T) {
+	repo := gitTemplateRepo(t)
+	useIndex(t, "version: \"1\"\n")
+	useLogger(t, logger.Quiet)
+	oldConfig := userConfig
+	t.Cleanup(func() { userConfig = oldConfig })
+	userConfig = &config.UserConfig{CacheDir: t.TempDir()}
+
+	tests := []struct {
+		name    string
+		base    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "subdir",
+			base:    "git:file://" + repo + "//template",
+			want:    []string{"README.md", ".github/workflows/ci.yml", ".gitignore"},
+			notWant: []string{".git", "ROOT.md", "template"},
+		},
+		{
+			name:    "repository root",
+			base:    "git:file://" + repo,
+			want:    []string{"ROOT.md", "template/README.md", "template/.github/workflows/ci.yml"},
+			notWant: []string{".git"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := filepath.Join(t.TempDir(), "myapp")
+			setInitFlags(t, tt.base, outDir)
+
+			if err := runInit(initCmd, []string{"myapp"}); err != nil {
+				t.Fatalf("runInit() error = %v", err)
+			}
+			for _, path := range tt.want {
+				if _, err := os.Stat(filepath.Join(outDir, path)); err != nil {
+					t.Errorf("%s should be generated: %v", path, err)
+				}
+			}
+			for _, path := range tt.notWant {
+				if _, err := os.Stat(filepath.Join(outDir, path)); !os.IsNotExist(err) {
+					t.Errorf("%s should not be generated", path)
+				}
+			}
+		})
+	}
+}
+
+func TestRunInit_ExternalIncludes(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo")
+	manifest := "name: app\ntype: base\nfiles:\n  exclude:\n    - \"*.tmp\"\n  external_includes:\n" +
+		"    - {from: ../../shared/.editorconfig}\n    - {from: ../../shared/ci, to: .github/workflows}\n"
+	writeTemplate(t, repo, "name: root\ntype: base\n", map[string]string{
+		"shared/.editorconfig":        "root = true",
+		"shared/ci/build.yml":         "name: {{ project_name }}",
+		"shared/ci/notes.tmp":         "scratch",
+		"templates/app/scaffold.yaml": manifest,
+		"templates/app/README.md":     "# {{ project_name }}",
+	})
+	gitCommitAll(t, repo)
+	useIndex(t, "version: \"1\"\n")
+	useLogger(t, logger.Quiet)
+	oldConfig := userConfig
+	t.Cleanup(func() { userConfig = oldConfig })
+	userConfig = &config.UserConfig{CacheDir: t.TempDir()}
+
+	outDir := filepath.Join(t.TempDir(), "myapp")
+	setInitFlags(t, "git:file://"+repo+"//templates/app", outDir)
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	want := map[string]string{
+		"README.md":                   "# myapp",
+		".editorconfig":               "root = true",
+		".github/workflows/build.yml": "name: myapp",
+		// The template's exclude patterns don't apply to external includes
+		".github/workflows/notes.tmp": "scratch",
+	}
+	for path, content := range want {
+		got, err := os.ReadFile(filepath.Join(outDir, path))
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v, want %q", path, got, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "shared")); !os.IsNotExist(err) {
+		t.Error("shared should not be generated")
+	}
+}
+
+func TestRunInit_GitSourceMissingSubdir(t *testing.T) {
+	repo := gitTemplateRepo(t)
+	useIndex(t, "version: \"1\"\n")
+	useLogger(t, logger.Quiet)
+	oldConfig := userConfig
+	t.Cleanup(func() { userConfig = oldConfig })
+	userConfig = &config.UserConfig{CacheDir: t.TempDir()}
+
+	for _, subdir := range []string{"missing", "../.."} {
+		setInitFlags(t, "git:file://"+repo+"//"+subdir, filepath.Join(t.TempDir(), "myapp"))
+		err := runInit(initCmd, []string{"myapp"})
+		if err == nil || !strings.Contains(err.Error(), "subdirectory") {
+			t.Errorf("runInit() with subdir %s error = %v, want subdirectory error", subdir, err)
+		}
+	}
+}
+
+func TestRunInit_Archive(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{"README.md": "# {{ project_name }}"})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	oldArchive, oldKeep := archiveType, keepDir
+	t.Cleanup(func() { archiveType, keepDir = oldArchive, oldKeep })
+	archiveType = "zip"
+	useLogger(t, logger.Quiet)
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if _, err := os.Stat(outDir + ".zip"); err != nil {
+		t.Errorf("archive should be created: %v", err)
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("%s should not be kept without --keep-dir", outDir)
+	}
+
+	keepDir = true
+	archiveType = "tar.gz"
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "README.md")); err != nil {
+		t.Errorf("%s should be kept with --keep-dir: %v", outDir, err)
+	}
+}
+
+func TestRunInit_WarnUnresolved(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\n", map[string]string{
+		"README.md": "# {{ project_name }}\nBy {{ author }}\n",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	_, stderr := useLogger(t, logger.Normal)
+
+	// Off by default without prompts
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if strings.Contains(stderr.String(), "unresolved") {
+		t.Errorf("stderr = %q, want no warning without --warn-unresolved", stderr.String())
+	}
+
+	flag := initCmd.Flags().Lookup("warn-unresolved")
+	t.Cleanup(func() {
+		flag.Value.Set("false")
+		flag.Changed = false
+	})
+	if err := initCmd.Flags().Set("warn-unresolved", "true"); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	outputDir = filepath.Join(tmpDir, "other")
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if want := "unresolved {{ author }} in README.md"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+	if strings.Contains(stderr.String(), "project_name") {
+		t.Errorf("stderr = %q, want no warning for a set variable", stderr.String())
+	}
+}
+
+func TestRunInit_ProjectModule(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, "name: base\ntype: base\ncomputed:\n  import_path: \"{{ project_module }}/internal\"\n", map[string]string{
+		"go.mod":  "module {{ project_module }}\n",
+		"main.go": "import \"{{ import_path }}\"\n",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	useLogger(t, logger.Quiet)
+	oldVars := variables
+	t.Cleanup(func() { variables = oldVars })
+	variables = []string{"org=Acme Corp"}
+
+	if err := runInit(initCmd, []string{"My-App"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	for file, want := range map[string]string{
+		"go.mod":  "module github.com/acme-corp/my_app\n",
+		"main.go": "import \"github.com/acme-corp/my_app/internal\"\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(outDir, file))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", file, got, err, want)
+		}
+	}
+}
+
+func TestRunInit_ListVariable(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	modDir := filepath.Join(tmpDir, "compose")
+	writeTemplate(t, baseDir, "name: base\ntype: base\nvariables:\n  - name: services\n    type: list\n", map[string]string{
+		"SERVICES": "{{ services }}\n",
+	})
+	writeTemplate(t, modDir, "name: compose\ntype: module\nengine: gotemplate\n", map[string]string{
+		"compose.yml": "services:\n{{- range .services }}\n  {{ . }}:\n    build: ./{{ . }}\n{{- end }}\n",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	useLogger(t, logger.Quiet)
+	oldModules, oldVars := addModules, variables
+	t.Cleanup(func() { addModules, variables = oldModules, oldVars })
+	addModules = []string{"file:" + modDir}
+	variables = []string{"services=api,web,worker"}
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	for file, want := range map[string]string{
+		"SERVICES":    "api,web,worker\n",
+		"compose.yml": "services:\n  api:\n    build: ./api\n  web:\n    build: ./web\n  worker:\n    build: ./worker\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(outDir, file))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", file, got, err, want)
+		}
+	}
+}
+
+func TestRunInit_NoManifest(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "plain")
+	if err := os.MkdirAll(filepath.Join(baseDir, "__project_slug__"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	for path, content := range map[string]string{
+		"README.md":                    "# {{ project_name }} by {{ author }}\n",
+		"__project_slug__/__init__.py": "",
+	} {
+		if err := os.WriteFile(filepath.Join(baseDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	useLogger(t, logger.Quiet)
+	oldVars, oldRequire := variables, requireManifest
+	t.Cleanup(func() { variables, requireManifest = oldVars, oldRequire })
+	variables = []string{"author=alice"}
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(outDir, "README.md")); string(got) != "# myapp by alice\n" {
+		t.Errorf("README.md = %q, want variables substituted", got)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "myapp", "__init__.py")); err != nil {
+		t.Errorf("renamed package missing: %v", err)
+	}
+
+	requireManifest = true
+	setInitFlags(t, "file:"+baseDir, filepath.Join(tmpDir, "strict"))
+	err := runInit(initCmd, []string{"myapp"})
+	if !errors.Is(err, config.ErrNoManifest) {
+		t.Errorf("runInit() error = %v, want ErrNoManifest with --require-manifest", err)
+	}
+}
+
+func TestRunInit_VariableConflict(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	modDir := filepath.Join(tmpDir, "mod")
+	writeTemplate(t, baseDir, "name: base\ntype: base\nvariables:\n  - name: cache\n    type: confirm\n    default: \"true\"\n", nil)
+	writeTemplate(t, modDir, "name: redis\ntype: module\nvariables:\n  - name: cache\n    default: redis\n", nil)
+	useIndex(t, "version: \"1\"\n")
+
+	setInitFlags(t, "file:"+baseDir, filepath.Join(tmpDir, "myapp"))
+	_, stderr := useLogger(t, logger.Normal)
+	oldModules, oldStrict := addModules, strictInit
+	t.Cleanup(func() { addModules, strictInit = oldModules, oldStrict })
+	addModules = []string{"file:" + modDir}
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+	if want := "variable cache: redis declares it as string, but base declares it as bool"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr = %q, want warning %q", stderr.String(), want)
+	}
+
+	strictInit = true
+	setInitFlags(t, "file:"+baseDir, filepath.Join(tmpDir, "strict"))
+	err := runInit(initCmd, []string{"myapp"})
+	if err == nil || !strings.Contains(err.Error(), "conflicting variable declarations") {
+		t.Errorf("runInit() error = %v, want conflict error with --strict", err)
+	}
+}
+
+func TestRunInit_SecretNotInLockfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	writeTemplate(t, baseDir, `
+name: base
+type: base
+variables:
+  - name: api_key
+    secret: true
+computed:
+  auth_header: "Bearer {{ api_key }}"
+`, map[string]string{
+		".env": "API_KEY={{ api_key }}\nAUTH={{ auth_header }}\n",
+	})
+	useIndex(t, "version: \"1\"\n")
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+baseDir, outDir)
+	useLogger(t, logger.Quiet)
+	oldVars := variables
+	t.Cleanup(func() { variables = oldVars })
+	variables = []string{"api_key=s3cret"}
+
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(outDir, ".env"))
+	if err != nil || string(got) != "API_KEY=s3cret\nAUTH=Bearer s3cret\n" {
+		t.Errorf(".env = %q, %v, want the secret substituted", got, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, config.LockFile))
+	if err != nil {
+		t.Fatalf("Failed to read lockfile: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("lockfile contains the secret:\n%s", data)
+	}
+	lock, err := config.LoadLockfile(outDir)
+	if err != nil {
+		t.Fatalf("LoadLockfile() error = %v", err)
+	}
+	if lock.Variables["project_name"] != "myapp" {
+		t.Errorf("lockfile variables = %v, want non-secret variables kept", lock.Variables)
+	}
+}
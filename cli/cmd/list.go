@@ -1,13 +1,23 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sort"
+	"text/tabwriter"
 
 	"github.com/makemore/scaffold/internal/registry"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listWide bool
+	listJSON bool
+	listTags []string
+)
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available templates",
@@ -17,11 +27,27 @@ var listCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().BoolVarP(&listWide, "wide", "w", false, "Show full template sources")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Output the templates as JSON")
+	listCmd.Flags().StringArrayVar(&listTags, "tag", nil, "Only list templates with this tag (repeat to require several)")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	reg := registry.New("")
-	templates, err := reg.List()
+	reg, err := newRegistry()
+	if err != nil {
+		return err
+	}
+
+	if listJSON {
+		templates, err := reg.ListDetailed(commandContext(cmd), listTags...)
+		if err != nil {
+			return fmt.Errorf("failed to load template index: %w", err)
+		}
+		return printTemplatesJSON(os.Stdout, templates)
+	}
+
+	templates, err := reg.List(commandContext(cmd), listTags...)
 	if err != nil {
 		return fmt.Errorf("failed to load template index: %w", err)
 	}
@@ -31,20 +57,10 @@ func runList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Sort template names
-	names := make([]string, 0, len(templates))
-	for name := range templates {
-		names = append(names, name)
-	}
-	sort.Strings(names)
-
 	fmt.Println("Available templates:")
 	fmt.Println()
 
-	for _, name := range names {
-		entry := templates[name]
-		fmt.Printf("  %-12s  %s\n", name, entry.Description)
-	}
+	printTemplates(os.Stdout, templates, listWide)
 
 	fmt.Println()
 	fmt.Println("Usage:")
@@ -56,3 +72,64 @@ func runList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// compactSourceWidth is the longest source shown without --wide
+const compactSourceWidth = 40
+
+// printTemplates writes aligned name, version, source and description
+// columns, sorted by name
+func printTemplates(w io.Writer, templates map[string]registry.TemplateEntry, wide bool) {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tVERSION\tSOURCE\tDESCRIPTION")
+	for _, name := range names {
+		entry := templates[name]
+
+		version := entry.Version
+		if version == "" {
+			version = "-"
+		}
+
+		src := entry.Source
+		if !wide && len(src) > compactSourceWidth {
+			src = src[:compactSourceWidth-3] + "..."
+		}
+
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", name, version, src, entry.Description)
+	}
+	tw.Flush()
+}
+
+// templateJSON is the --json representation of a template
+type templateJSON struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Source      string   `json:"source"`
+	Official    bool     `json:"official"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// printTemplatesJSON writes the templates as a JSON array
+func printTemplatesJSON(w io.Writer, templates []registry.ListedTemplate) error {
+	out := make([]templateJSON, 0, len(templates))
+	for _, t := range templates {
+		out = append(out, templateJSON{
+			Name:        t.Name,
+			Description: t.Description,
+			Source:      t.Source,
+			Official:    t.Origin == registry.OriginOfficial,
+			Tags:        t.Tags,
+		})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode templates: %w", err)
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const listIndex = `
+version: "1"
+official:
+  django:
+    source: "github:scaffold-dev/scaffold//templates/django-base"
+    description: "Django REST API"
+    version: "v1.4.0"
+community:
+  astro:
+    source: "github:someone/astro-starter"
+    description: "Astro site"
+`
+
+func TestPrintTemplates(t *testing.T) {
+	reg := useIndex(t, listIndex)
+	templates, err := reg.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		wide bool
+		want string
+	}{
+		{
+			name: "compact",
+			want: `  NAME    VERSION  SOURCE                                    DESCRIPTION
+  astro   -        github:someone/astro-starter              Astro site
+  django  v1.4.0   github:scaffold-dev/scaffold//templat...  Django REST API
+`,
+		},
+		{
+			name: "wide",
+			wide: true,
+			want: `  NAME    VERSION  SOURCE                                               DESCRIPTION
+  astro   -        github:someone/astro-starter                         Astro site
+  django  v1.4.0   github:scaffold-dev/scaffold//templates/django-base  Django REST API
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printTemplates(&buf, templates, tt.wide)
+			if buf.String() != tt.want {
+				t.Errorf("printTemplates() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintTemplatesJSON(t *testing.T) {
+	reg := useIndex(t, listIndex)
+	templates, err := reg.ListDetailed(context.Background())
+	if err != nil {
+		t.Fatalf("ListDetailed() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printTemplatesJSON(&buf, templates); err != nil {
+		t.Fatalf("printTemplatesJSON() error = %v", err)
+	}
+
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+
+	want := []map[string]interface{}{
+		{"name": "astro", "description": "Astro site", "source": "github:someone/astro-starter", "official": false},
+		{"name": "django", "description": "Django REST API", "source": "github:scaffold-dev/scaffold//templates/django-base", "official": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// buildLockfile records the sources, generated files and variables of a
+// completed init. Secret variables are left out. File hashes are taken after
+// all sources were processed.
+func buildLockfile(plan *initPlan, outDir string, vars map[string]string) (*config.Lockfile, error) {
+	lock := &config.Lockfile{
+		Version:   config.LockfileVersion,
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Variables: withoutSecrets(vars, plan.secrets()),
+	}
+
+	for _, parent := range plan.Parents {
+		locked, err := lockedSource(parent, outDir)
+		if err != nil {
+			return nil, err
+		}
+		lock.Parents = append(lock.Parents, locked)
+	}
+
+	base, err := lockedSource(plan.Base, outDir)
+	if err != nil {
+		return nil, err
+	}
+	lock.Base = base
+
+	for _, m := range plan.Modules {
+		module, err := lockedSource(m, outDir)
+		if err != nil {
+			return nil, err
+		}
+		lock.Modules = append(lock.Modules, module)
+	}
+
+	return lock, nil
+}
+
+// lockedSource records a processed source. Credentials in its URL are left
+// out, as the lockfile is committed with the project; replays authenticate
+// through the environment or git's credential helpers instead.
+func lockedSource(s *plannedSource, outDir string) (config.LockedSource, error) {
+	files, err := config.LockFiles(outDir, s.Files)
+	if err != nil {
+		return config.LockedSource{}, fmt.Errorf("failed to lock %s: %w", source.Redact(s.Input), err)
+	}
+
+	return config.LockedSource{
+		Name:   source.StripCredentials(s.name()),
+		Source: source.StripCredentials(s.Resolved),
+		Ref:    s.Ref,
+		Commit: s.Commit,
+		Hash:   s.Hash,
+		Files:  files,
+	}, nil
+}
+
+// planFromLockfile rebuilds the plan recorded in a lockfile. Locked sources
+// are already resolved, so the registry is not consulted.
+func planFromLockfile(lock *config.Lockfile) (*initPlan, error) {
+	plan := &initPlan{}
+	for _, locked := range lock.Parents {
+		parent, err := plannedFromLock(locked)
+		if err != nil {
+			return nil, err
+		}
+		plan.Parents = append(plan.Parents, parent)
+	}
+
+	base, err := plannedFromLock(lock.Base)
+	if err != nil {
+		return nil, err
+	}
+	plan.Base = base
+
+	for _, locked := range lock.Modules {
+		module, err := plannedFromLock(locked)
+		if err != nil {
+			return nil, err
+		}
+		plan.Modules = append(plan.Modules, module)
+	}
+	return plan, nil
+}
+
+func plannedFromLock(locked config.LockedSource) (*plannedSource, error) {
+	src, err := source.Parse(locked.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse locked source %s: %w", locked.Source, err)
+	}
+	// The locked commit pins the source exactly, even when a branch or tag has
+	// moved since. Older lockfiles only have the ref, which pins version
+	// ranges to the tag they resolved to.
+	switch {
+	case locked.Commit != "":
+		src.Ref = locked.Commit
+	case locked.Ref != "":
+		src.Ref = locked.Ref
+	}
+	return &plannedSource{Input: locked.Source, Resolved: locked.Source, Source: src}, nil
+}
+
+// changedSources returns the names of the sources whose content no longer
+// matches the hash recorded in the lockfile. Both list their sources in the
+// same order; sources without a recorded hash are skipped.
+func changedSources(lock *config.Lockfile, plan *initPlan) []string {
+	var changed []string
+	for i, locked := range lock.Sources() {
+		fetched := plan.sources()[i]
+		if locked.Hash != "" && fetched.Hash != locked.Hash {
+			changed = append(changed, locked.Name)
+		}
+	}
+	return changed
+}
+
+// managedFiles returns every file recorded in the lockfile, in order and
+// without duplicates
+func managedFiles(lock *config.Lockfile) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, s := range lock.Sources() {
+		for _, f := range s.Files {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				paths = append(paths, f.Path)
+			}
+		}
+	}
+	return paths
+}
+
+// lockUnchanged reports whether dir already holds the project lock describes:
+// its lockfile records the same sources, variables and files, and none of
+// those files has been edited or removed since
+func lockUnchanged(dir string, lock *config.Lockfile) bool {
+	existing, err := config.LoadLockfile(dir)
+	if err != nil || existing == nil || !existing.SameAs(lock) {
+		return false
+	}
+	return filesUnedited(dir, existing)
+}
+
+// upToDate reports, before anything is fetched, whether dir already holds
+// what an init of plan would generate. Its lockfile must record the plan's
+// base and modules at the refs they ask for, those refs must still point at
+// the locked commits, local sources must hash as they did, the variables given on the command line, in files and in the
+// environment must have their locked values, and no generated file may have
+// been edited or removed. Variables left to their defaults are taken to be
+// unchanged, as the defaults are only known once the templates are fetched.
+func upToDate(ctx context.Context, dir string, plan *initPlan, projectName string) bool {
+	existing, err := config.LoadLockfile(dir)
+	if err != nil || existing == nil || !lockedPlan(existing, plan) {
+		return false
+	}
+	if !sourcesUnchanged(ctx, existing) || !filesUnedited(dir, existing) {
+		return false
+	}
+
+	manifest := &config.Manifest{}
+	for name := range existing.Variables {
+		manifest.Variables = append(manifest.Variables, config.Variable{Name: name})
+	}
+	vars, err := collectVariables(manifest, projectName, userConfig.Variables)
+	if err != nil {
+		return false
+	}
+	for name, value := range vars {
+		if locked, ok := existing.Variables[name]; !ok || locked != value {
+			return false
+		}
+	}
+	return true
+}
+
+// lockedPlan reports whether a lockfile records the base and modules of a
+// plan that hasn't been fetched yet. Modules are locked in the order they
+// were applied, which may not be the order they were given in.
+func lockedPlan(lock *config.Lockfile, plan *initPlan) bool {
+	if len(lock.Modules) != len(plan.Modules) || !lockedAs(lock.Base, plan.Base) {
+		return false
+	}
+	pending := slices.Clone(lock.Modules)
+	for _, m := range plan.Modules {
+		i := slices.IndexFunc(pending, func(locked config.LockedSource) bool { return lockedAs(locked, m) })
+		if i < 0 {
+			return false
+		}
+		pending = slices.Delete(pending, i, i+1)
+	}
+	return true
+}
+
+// lockedAs reports whether a locked source is s at the ref s asks for. A
+// version range is locked as the tag it resolved to, so it never matches.
+func lockedAs(locked config.LockedSource, s *plannedSource) bool {
+	return locked.Source == source.StripCredentials(s.Resolved) && locked.Ref == s.Source.Ref
+}
+
+// sourcesUnchanged reports whether the locked sources would fetch as they
+// did. A git branch or tag may have moved, so it must still point at the
+// locked commit, which the remote tells without cloning. Local sources, which
+// no ref pins, are read in place and must still hash as they did. Archives
+// would have to be downloaded again and what was read from stdin can't be
+// compared at all. OCI sources are locked at a digest, which can't move.
+func sourcesUnchanged(ctx context.Context, lock *config.Lockfile) bool {
+	fetcher := newFetcher()
+	defer fetcher.Close()
+
+	for _, locked := range lock.Sources() {
+		src, err := source.Parse(locked.Source)
+		if err != nil {
+			return false
+		}
+		switch src.Type {
+		case source.TypeOCI:
+			continue
+		case source.TypeGit:
+			src.Ref = locked.Ref
+			commit, err := fetcher.RemoteCommit(ctx, src)
+			if err != nil || commit == "" || !strings.HasPrefix(locked.Commit, commit) {
+				return false
+			}
+		case source.TypeFile:
+			path, err := fetcher.Fetch(ctx, src)
+			if err != nil {
+				return false
+			}
+			if hash, err := config.HashTree(path); err != nil || hash != locked.Hash {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// filesUnedited reports whether every file a lockfile records is still in dir
+// as it was generated
+func filesUnedited(dir string, lock *config.Lockfile) bool {
+	for _, s := range lock.Sources() {
+		for _, f := range s.Files {
+			hash, err := config.HashFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+			if err != nil || hash != f.Hash {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var newTemplateType string
+
+var newTemplateCmd = &cobra.Command{
+	Use:   "new-template <name>",
+	Short: "Create a starter template to build on",
+	Long: `Create a directory holding a starter template: a commented scaffold.yaml,
+an example file using {{ project_name }} and a .scaffoldignore.
+
+Try a base template with scaffold init myapp --base file:./<name>, or a
+module with --add file:./<name>.`,
+	Example: `  scaffold new-template my-template
+  scaffold new-template auth --type module`,
+	Args: cobra.ExactArgs(1),
+	RunE: runNewTemplate,
+}
+
+func init() {
+	rootCmd.AddCommand(newTemplateCmd)
+
+	newTemplateCmd.Flags().StringVarP(&newTemplateType, "type", "t", "base", "Template type: base or module")
+}
+
+func runNewTemplate(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	files, err := starterTemplate(filepath.Base(filepath.Clean(dir)), newTemplateType)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+	if err := writeStarterTemplate(dir, files); err != nil {
+		return err
+	}
+
+	log.Result("✅ Created %s template at %s", newTemplateType, dir)
+	if newTemplateType == "module" {
+		log.Result("   Try it: scaffold init myapp --base <base> --add file:%s", dir)
+	} else {
+		log.Result("   Try it: scaffold init myapp --base file:%s", dir)
+	}
+	return nil
+}
+
+// starterTemplate returns the files of a starter template, keyed by
+// slash-separated path
+func starterTemplate(name, typ string) (map[string]string, error) {
+	var manifest, exampleFile, example string
+	switch typ {
+	case "base":
+		manifest, exampleFile, example = starterBaseManifest, "README.md", starterBaseReadme
+	case "module":
+		manifest, exampleFile, example = starterModuleManifest, "docs/"+name+".md", starterModuleDoc
+	default:
+		return nil, fmt.Errorf("unknown template type %q (use base or module)", typ)
+	}
+
+	// SLUG stands for the name as a variable name, NAME for the name itself
+	replacer := strings.NewReplacer("SLUG", template.Slug(name), "NAME", name)
+	return map[string]string{
+		config.ManifestFile: replacer.Replace(manifest),
+		exampleFile:         replacer.Replace(example),
+		template.IgnoreFile: starterIgnore,
+	}, nil
+}
+
+// writeStarterTemplate writes files into a new directory, removing it again
+// if anything fails
+func writeStarterTemplate(dir string, files map[string]string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(fullPath), err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to write %s: %w", fullPath, err)
+		}
+	}
+	return nil
+}
+
+const starterBaseManifest = `# Template manifest. Run "scaffold schema" for a JSON Schema that gives your
+# editor autocomplete for this file.
+name: NAME
+description: A short description shown by scaffold list
+type: base            # base templates start a project; modules layer onto one
+version: "0.1.0"
+
+# Variables are prompted for, or passed with --var name=value. project_name
+# and project_slug are always set from the project name.
+variables:
+  - name: author
+    description: Author name
+    help: Used in the README and license
+    default: Anonymous
+
+  - name: license
+    description: License
+    type: choice
+    choices: [MIT, Apache-2.0]
+    default: MIT
+
+# Values derived from other variables, usable like any variable
+# computed:
+#   image: "{{ project_slug }}:latest"
+
+# File names may use __variable__, e.g. __project_slug__/__init__.py
+files:
+  # Patterns never generated; .scaffoldignore adds more
+  exclude: []
+  # Paths to rename on output
+  # rename:
+  #   gitignore: .gitignore
+
+# Steps run in the generated project
+actions:
+  - name: welcome
+    type: message
+    message: "Created {{ project_name }}. Next: cd {{ project_slug }}"
+`
+
+const starterModuleManifest = `# Template manifest. Run "scaffold schema" for a JSON Schema that gives your
+# editor autocomplete for this file.
+name: NAME
+description: A short description shown by scaffold list
+type: module          # layered onto a base with scaffold init --add
+version: "0.1.0"
+
+# Modules this one needs; they must be added too and are applied first
+# requires: []
+
+# Variables shared with the base, such as project_name, are only asked once
+variables:
+  - name: SLUG_enabled
+    description: Enable NAME
+    type: confirm
+    default: "true"
+
+files:
+  # Patterns never generated; .scaffoldignore adds more
+  exclude: []
+  # Only generate matching files when the condition holds
+  # conditions:
+  #   "docs/*": SLUG_enabled
+
+actions:
+  - name: NAME-added
+    type: message
+    message: "NAME added to {{ project_name }}"
+`
+
+const starterBaseReadme = `# {{ project_name }}
+
+Created by {{ author }} from the NAME template.
+
+Licensed under {{ license }}.
+`
+
+const starterModuleDoc = `# NAME
+
+Added to {{ project_name }} by the NAME module.
+`
+
+const starterIgnore = `# Files in this template that are never generated, one gitignore-style
+# pattern per line, in addition to files.exclude in scaffold.yaml
+*.swp
+*~
+`
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/template"
+)
+
+func TestRunNewTemplate(t *testing.T) {
+	useLogger(t, logger.Quiet)
+	old := newTemplateType
+	t.Cleanup(func() { newTemplateType = old })
+
+	for _, typ := range []string{"base", "module"} {
+		t.Run(typ, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "my-template")
+			newTemplateType = typ
+			if err := runNewTemplate(newTemplateCmd, []string{dir}); err != nil {
+				t.Fatalf("runNewTemplate() error = %v", err)
+			}
+
+			manifest, err := config.LoadManifest(dir)
+			if err != nil {
+				t.Fatalf("LoadManifest() error = %v", err)
+			}
+			if manifest.Name != "my-template" || manifest.Type != typ {
+				t.Errorf("manifest name, type = %s, %s, want my-template, %s", manifest.Name, manifest.Type, typ)
+			}
+			for _, v := range manifest.Variables {
+				if len(template.References("{{ "+v.Name+" }}")) != 1 {
+					t.Errorf("variable %q is not a valid variable name", v.Name)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, template.IgnoreFile)); err != nil {
+				t.Errorf("%s should be created: %v", template.IgnoreFile, err)
+			}
+
+			if err := runNewTemplate(newTemplateCmd, []string{dir}); err == nil {
+				t.Error("runNewTemplate() should refuse an existing directory")
+			}
+		})
+	}
+}
+
+func TestRunNewTemplate_Generates(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "starter")
+	useLogger(t, logger.Quiet)
+	useIndex(t, "version: \"1\"\n")
+	old := newTemplateType
+	t.Cleanup(func() { newTemplateType = old })
+	newTemplateType = "base"
+
+	if err := runNewTemplate(newTemplateCmd, []string{dir}); err != nil {
+		t.Fatalf("runNewTemplate() error = %v", err)
+	}
+
+	outDir := filepath.Join(tmpDir, "myapp")
+	setInitFlags(t, "file:"+dir, outDir)
+	if err := runInit(initCmd, []string{"myapp"}); err != nil {
+		t.Fatalf("runInit() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(outDir, "README.md"))
+	if err != nil || string(got) != "# myapp\n\nCreated by Anonymous from the starter template.\n\nLicensed under MIT.\n" {
+		t.Errorf("README.md = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, template.IgnoreFile)); !os.IsNotExist(err) {
+		t.Errorf("%s should not be generated", template.IgnoreFile)
+	}
+}
+
+func TestStarterTemplate_UnknownType(t *testing.T) {
+	if _, err := starterTemplate("x", "plugin"); err == nil {
+		t.Error("starterTemplate() should return error for an unknown type")
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/makemore/scaffold/internal/registry"
+)
+
+// otherTemplateLabel is the picker entry for entering a source by hand
+const otherTemplateLabel = "Other (enter URL)"
+
+// askTemplate is the survey entry point for the picker, replaceable in tests
+var askTemplate = survey.AskOne
+
+// templateOption is a picker entry. The label is only for display; the
+// selection is mapped back to the template by index, never by parsing.
+type templateOption struct {
+	Label       string
+	Name        string // Empty for the "other" entry
+	Description string
+}
+
+// templateOptions returns the picker entries grouped by primary category and
+// sorted by name, followed by the "other" entry
+func templateOptions(templates map[string]registry.TemplateEntry) []templateOption {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ci, cj := templates[names[i]].Category(), templates[names[j]].Category()
+		if ci != cj {
+			return ci < cj
+		}
+		return names[i] < names[j]
+	})
+
+	options := make([]templateOption, 0, len(names)+1)
+	for _, name := range names {
+		entry := templates[name]
+		label := fmt.Sprintf("%s - %s", name, entry.Description)
+		if category := entry.Category(); category != "" {
+			label += " [" + category + "]"
+		}
+		options = append(options, templateOption{Label: label, Name: name, Description: entry.Description})
+	}
+	return append(options, templateOption{Label: otherTemplateLabel})
+}
+
+// fuzzyMatch reports whether the characters of filter appear in text in
+// order, ignoring case and spaces
+func fuzzyMatch(filter, text string) bool {
+	text = strings.ToLower(text)
+	for _, r := range strings.ToLower(filter) {
+		if r == ' ' {
+			continue
+		}
+		i := strings.IndexRune(text, r)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(string(r)):]
+	}
+	return true
+}
+
+// pickTemplate lets the user choose a template, typing to filter by name and
+// description, or enter a source by hand
+func pickTemplate(templates map[string]registry.TemplateEntry) (string, error) {
+	options := templateOptions(templates)
+	labels := make([]string, len(options))
+	for i, o := range options {
+		labels[i] = o.Label
+	}
+
+	var selected int
+	prompt := &survey.Select{
+		Message:  "Select a template (type to filter):",
+		Options:  labels,
+		PageSize: 15,
+		Filter: func(filter, _ string, i int) bool {
+			o := options[i]
+			return o.Name == "" || fuzzyMatch(filter, o.Name+" "+o.Description)
+		},
+	}
+	if err := askTemplate(prompt, &selected); err != nil {
+		return "", err
+	}
+
+	if name := options[selected].Name; name != "" {
+		return name, nil
+	}
+
+	var source string
+	urlPrompt := &survey.Input{Message: "Template URL:"}
+	if err := askTemplate(urlPrompt, &source, survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+	return source, nil
+}
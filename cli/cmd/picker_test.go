@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/makemore/scaffold/internal/registry"
+)
+
+func TestTemplateOptions(t *testing.T) {
+	templates := map[string]registry.TemplateEntry{
+		"django": {Description: "Django - batteries included", Tags: []string{"python"}},
+		"axum":   {Description: "Rust web API", Tags: []string{"rust"}},
+		"blank":  {Description: "Empty project"},
+	}
+
+	options := templateOptions(templates)
+
+	want := []templateOption{
+		{Label: "blank - Empty project", Name: "blank", Description: "Empty project"},
+		{Label: "django - Django - batteries included [python]", Name: "django", Description: "Django - batteries included"},
+		{Label: "axum - Rust web API [rust]", Name: "axum", Description: "Rust web API"},
+		{Label: otherTemplateLabel},
+	}
+	if len(options) != len(want) {
+		t.Fatalf("len(templateOptions()) = %d, want %d", len(options), len(want))
+	}
+	for i := range want {
+		if options[i] != want[i] {
+			t.Errorf("templateOptions()[%d] = %+v, want %+v", i, options[i], want[i])
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		filter string
+		text   string
+		want   bool
+	}{
+		{"", "django Web framework", true},
+		{"dj", "django Web framework", true},
+		{"DJG", "django Web framework", true},
+		{"web fw", "django Web framework", true},
+		{"wd", "django Web framework", false},
+		{"flask", "django Web framework", false},
+	}
+
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.filter, tt.text); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.filter, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPickTemplate(t *testing.T) {
+	templates := map[string]registry.TemplateEntry{
+		"django":     {Description: "Django - REST API - auth", Tags: []string{"python"}},
+		"api - beta": {Description: "Experimental - do not use", Tags: []string{"python"}},
+		"nextjs":     {Description: "Next.js", Tags: []string{"web"}},
+	}
+
+	tests := []struct {
+		name    string
+		pick    string
+		answers []string
+		want    string
+	}{
+		{name: "description with separator", pick: "django - Django - REST API - auth [python]", want: "django"},
+		{name: "name with separator", pick: "api - beta - Experimental - do not use [python]", want: "api - beta"},
+		{name: "other", pick: otherTemplateLabel, answers: []string{"github:acme/tpl"}, want: "github:acme/tpl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := askTemplate
+			t.Cleanup(func() { askTemplate = orig })
+
+			answers := tt.answers
+			askTemplate = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+				switch p := p.(type) {
+				case *survey.Select:
+					for i, label := range p.Options {
+						if label == tt.pick {
+							*response.(*int) = i
+							return nil
+						}
+					}
+					t.Fatalf("option %q not offered in %v", tt.pick, p.Options)
+				case *survey.Input:
+					*response.(*string), answers = answers[0], answers[1:]
+				}
+				return nil
+			}
+
+			got, err := pickTemplate(templates)
+			if err != nil {
+				t.Fatalf("pickTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("pickTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,605 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/registry"
+	"github.com/makemore/scaffold/internal/source"
+	"github.com/makemore/scaffold/internal/suggest"
+	"github.com/makemore/scaffold/internal/template"
+)
+
+// plannedSource is a base template or module resolved for generation
+type plannedSource struct {
+	Input    string           // As given on the command line
+	Resolved string           // After registry lookup and provider expansion
+	Source   *source.Source   // Parsed source
+	Path     string           // Local path, set once fetched
+	Ref      string           // Ref with version ranges and OCI tags resolved, set once fetched
+	Commit   string           // Commit checked out for git sources, set once fetched
+	Root     string           // Top of the fetched source, above Path, set once fetched
+	Hash     string           // Content hash of sources that git or OCI don't pin, set once fetched
+	Manifest *config.Manifest // Loaded manifest, set once fetched
+	Files    []string         // Files written, set once processed
+	Result   *template.Result // What processing did, set once processed
+	Err      error            // Files that failed with --keep-going, joined
+}
+
+// initPlan describes the sources an init will compose
+type initPlan struct {
+	Parents []*plannedSource // Templates the base extends, root first
+	Base    *plannedSource
+	Modules []*plannedSource
+	// KeepGoing renders the rest of every source when some of its files fail
+	KeepGoing bool
+}
+
+// resolvePlan resolves and parses the base and module sources without
+// fetching anything, so mistyped sources are caught early
+func resolvePlan(ctx context.Context, reg *registry.Registry, base string, modules []string) (*initPlan, error) {
+	baseSrc, err := resolveSource(ctx, reg, base)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", base, err)
+	}
+
+	plan := &initPlan{Base: baseSrc}
+	for _, m := range modules {
+		moduleSrc, err := resolveSource(ctx, reg, m)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", m, err)
+		}
+		plan.Modules = append(plan.Modules, moduleSrc)
+	}
+	return plan, nil
+}
+
+func resolveSource(ctx context.Context, reg *registry.Registry, input string) (*plannedSource, error) {
+	resolved, err := reg.Resolve(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve: %w", err)
+	}
+	resolved = userConfig.ExpandProvider(resolved)
+
+	src, err := source.Parse(resolved)
+	if err != nil {
+		if s := suggestTemplate(ctx, reg, input); s != "" {
+			return nil, fmt.Errorf("failed to parse source: %w (did you mean %s?)", err, s)
+		}
+		return nil, fmt.Errorf("failed to parse source: %w", err)
+	}
+
+	return &plannedSource{Input: input, Resolved: resolved, Source: src}, nil
+}
+
+// suggestTemplate returns the registry name closest to a mistyped input, or
+// "" when there is none. Only shorthands are considered: anything with a
+// scheme or a path is taken to be meant as a source.
+func suggestTemplate(ctx context.Context, reg *registry.Registry, input string) string {
+	if strings.ContainsAny(input, ":/\\") {
+		return ""
+	}
+	names, err := reg.Names(ctx)
+	if err != nil {
+		return ""
+	}
+	return suggest.Closest(input, names, min(3, len(input)/2))
+}
+
+// pinVersion checks out version of a git source in place of the ref given
+// with it, if any, warning when that ref differs
+func (s *plannedSource) pinVersion(log *logger.Logger, version string) error {
+	if s.Source.Type != source.TypeGit {
+		return fmt.Errorf("--template-version needs a git source, but %s is a %s source", s.Input, s.Source.Type)
+	}
+	if s.Source.Ref != "" && s.Source.Ref != version {
+		log.Warn("--template-version %s overrides the ref %s of %s", version, s.Source.Ref, s.Input)
+	}
+	s.Source.Ref = version
+	return nil
+}
+
+// checkOutputOutside returns an error when outDir is, or is inside, the
+// directory of a file: source. Walking the template would then pick up the
+// files being generated.
+func (p *initPlan) checkOutputOutside(outDir string) error {
+	out, err := realPath(outDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	for _, s := range p.sources() {
+		if s.Source.Type != source.TypeFile || s.Path == "" {
+			continue
+		}
+		dir, err := realPath(s.Path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", s.Input, err)
+		}
+		if rel, err := filepath.Rel(dir, out); err == nil && filepath.IsLocal(rel) {
+			return fmt.Errorf("output directory %s is inside the template %s; generate it elsewhere", outDir, s.Input)
+		}
+	}
+	return nil
+}
+
+// realPath returns the absolute form of path with symlinks resolved as far
+// as it exists, so that paths yet to be created compare like existing ones
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	missing := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, missing), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return abs, nil
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+	}
+}
+
+// sources returns the sources in processing order: parents, the base and
+// then the modules
+func (p *initPlan) sources() []*plannedSource {
+	sources := append([]*plannedSource{}, p.Parents...)
+	sources = append(sources, p.Base)
+	return append(sources, p.Modules...)
+}
+
+// fetch fetches every source in the plan and loads its manifest
+func (p *initPlan) fetch(ctx context.Context, fetcher *source.Fetcher) error {
+	for _, s := range p.sources() {
+		if err := s.fetchWith(ctx, fetcher); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchWith fetches the source and loads its manifest. A source without a
+// manifest gets the default one unless --require-manifest is set.
+func (s *plannedSource) fetchWith(ctx context.Context, fetcher *source.Fetcher) error {
+	path, res, err := fetcher.FetchResolved(ctx, s.Source)
+	if err != nil {
+		return err
+	}
+	s.Ref = res.Ref
+	s.Commit = res.Commit
+	s.Root = res.Root
+
+	manifest, err := config.LoadManifest(path)
+	if errors.Is(err, config.ErrNoManifest) && !requireManifest {
+		log.Progress("📄 %s has no %s, generating all of its files", s.Input, config.ManifestFile)
+		manifest, err = config.DefaultManifest(), nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load manifest for %s: %w", s.Input, err)
+	}
+	if err := manifest.CheckScaffoldVersion(Version); err != nil {
+		return fmt.Errorf("template %s: %w", s.Input, err)
+	}
+
+	// Git commits and OCI digests pin their sources; the content of others
+	// is hashed so that a change to them can be told
+	if s.Source.Type != source.TypeGit && s.Source.Type != source.TypeOCI {
+		if s.Hash, err = config.HashTree(path); err != nil {
+			return err
+		}
+	}
+
+	s.Path = path
+	s.Manifest = manifest
+	return nil
+}
+
+// name returns the manifest name of a fetched source, falling back to its input
+func (s *plannedSource) name() string {
+	if s.Manifest != nil && s.Manifest.Name != "" {
+		return s.Manifest.Name
+	}
+	return s.Input
+}
+
+// orderModules verifies that every required module is part of the plan and
+// sorts the modules so each is applied after the modules it requires. The
+// base always comes first, and the command-line order breaks ties between
+// independent modules.
+func (p *initPlan) orderModules() error {
+	names := make([]string, 0, len(p.Modules)+1)
+	requires := make(map[string][]string)
+	byName := make(map[string][]*plannedSource)
+
+	for _, s := range p.sources() {
+		name := s.name()
+		if _, ok := requires[name]; !ok {
+			names = append(names, name)
+		}
+		requires[name] = append(requires[name], s.Manifest.Requires...)
+		byName[name] = append(byName[name], s)
+	}
+
+	for _, name := range names {
+		for _, req := range requires[name] {
+			if _, ok := requires[req]; !ok {
+				return fmt.Errorf("%s requires module %s, which is not included (add it with --add)", name, req)
+			}
+		}
+	}
+
+	order, err := orderByRequires(names, requires)
+	if err != nil {
+		return err
+	}
+
+	isModule := make(map[*plannedSource]bool)
+	for _, m := range p.Modules {
+		isModule[m] = true
+	}
+
+	modules := make([]*plannedSource, 0, len(p.Modules))
+	for _, name := range order {
+		for _, s := range byName[name] {
+			if isModule[s] {
+				modules = append(modules, s)
+			}
+		}
+	}
+	p.Modules = modules
+	return nil
+}
+
+// orderByRequires returns names ordered so that every name comes after the
+// names it requires. Otherwise the given order is preserved. A cycle in the
+// requires graph is reported with its full path.
+func orderByRequires(names []string, requires map[string][]string) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var order, path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("circular module requirement: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, req := range requires[name] {
+			if err := visit(req); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// render processes the parents, the base and then each module into outDir,
+// recording the files each one wrote, and returns the collected
+// post-generation actions. When sources write the same file, the higher
+// manifest priority wins. With KeepGoing, files that fail are recorded in
+// their source's Err instead of stopping. Cancelling ctx stops processing.
+func (p *initPlan) render(ctx context.Context, log *logger.Logger, outDir string, vars map[string]string) ([]config.Action, error) {
+	owners := template.Owners{}
+	lists := p.lists()
+	var actions []config.Action
+
+	// Parent templates are overlaid by the templates extending them
+	for _, parent := range p.Parents {
+		log.Progress("📝 Processing parent template: %s", parent.Input)
+		if err := p.renderSource(ctx, log, parent, outDir, vars, lists, owners); err != nil {
+			return nil, fmt.Errorf("failed to process parent template %s: %w", parent.Input, err)
+		}
+		actions = append(actions, parent.Manifest.Actions...)
+	}
+
+	log.Progress("📝 Processing template...")
+	if err := p.renderSource(ctx, log, p.Base, outDir, vars, lists, owners); err != nil {
+		return nil, fmt.Errorf("failed to process template: %w", err)
+	}
+	actions = append(actions, p.Base.Manifest.Actions...)
+
+	// Process additional modules (layer on top of existing files)
+	for _, module := range p.Modules {
+		log.Progress("📦 Adding module: %s", module.Input)
+		if err := p.renderSource(ctx, log, module, outDir, vars, lists, owners); err != nil {
+			return nil, fmt.Errorf("failed to process module %s: %w", module.Input, err)
+		}
+		actions = append(actions, module.Manifest.Actions...)
+	}
+
+	return actions, nil
+}
+
+// renderSource processes one source into outDir and records what it wrote.
+// Only an error that stopped processing is returned; with KeepGoing, the
+// files that failed are kept in the source's Err.
+func (p *initPlan) renderSource(ctx context.Context, log *logger.Logger, s *plannedSource, outDir string, vars map[string]string, lists []string, owners template.Owners) error {
+	processor := template.NewProcessor(s.Manifest, s.Path, outDir)
+	processor.SetVariables(vars)
+	processor.SetRoot(s.Root)
+	processor.SetLists(lists)
+	processor.SetOwners(owners)
+	processor.SetKeepGoing(p.KeepGoing)
+
+	result, err := processor.Process(ctx)
+	if result == nil {
+		return err
+	}
+	s.Err = err
+	s.Files = processor.WrittenFiles()
+	s.Result = result
+	logWrites(log, s)
+	return nil
+}
+
+// result combines the processing results of every rendered source
+func (p *initPlan) result() *template.Result {
+	total := &template.Result{}
+	for _, s := range p.sources() {
+		if s.Result != nil {
+			total.Add(s.Result)
+		}
+	}
+	return total
+}
+
+// fileErrors returns the file errors collected with --keep-going, one per
+// line with the source they came from, or nil when there were none
+func (p *initPlan) fileErrors() error {
+	var errs []error
+	for _, s := range p.sources() {
+		if s.Err == nil {
+			continue
+		}
+		failed := []error{s.Err}
+		if joined, ok := s.Err.(interface{ Unwrap() []error }); ok {
+			failed = joined.Unwrap()
+		}
+		for _, err := range failed {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Input, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// formatSummary describes how many files were created, skipped and
+// overwritten
+func formatSummary(result *template.Result) string {
+	return fmt.Sprintf("%d files created, %d skipped, %d overwritten",
+		len(result.Created), len(result.Skipped), len(result.Overwritten))
+}
+
+func logWrites(log *logger.Logger, s *plannedSource) {
+	for _, f := range s.Files {
+		log.Debug("wrote %s (%s)", f, s.name())
+	}
+}
+
+// declarationOrder returns the sources in order of precedence for variable
+// declarations: the base, its parents nearest first, and then the modules
+func (p *initPlan) declarationOrder() []*plannedSource {
+	sources := []*plannedSource{p.Base}
+	for i := len(p.Parents) - 1; i >= 0; i-- {
+		sources = append(sources, p.Parents[i])
+	}
+	return append(sources, p.Modules...)
+}
+
+// variables returns the variables declared across all fetched manifests in
+// declaration order. When several manifests declare the same name, the first
+// declaration wins, so a template can override what it inherits.
+func (p *initPlan) variables() []config.Variable {
+	var vars []config.Variable
+	seen := make(map[string]bool)
+
+	for _, s := range p.declarationOrder() {
+		if s.Manifest == nil {
+			continue
+		}
+		for _, v := range s.Manifest.Variables {
+			if !seen[v.Name] {
+				seen[v.Name] = true
+				vars = append(vars, v)
+			}
+		}
+	}
+	return vars
+}
+
+// computes reports whether any manifest declares the computed variable name
+func (p *initPlan) computes(name string) bool {
+	for _, s := range p.sources() {
+		if s.Manifest == nil {
+			continue
+		}
+		if _, ok := s.Manifest.Computed[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// variableConflicts describes the variables that a module declares with a
+// different type, or a different set of choices, than an earlier declaration.
+// The earlier declaration is the one used, so such a module was likely
+// written with another variable of the same name in mind. Templates may
+// redeclare what they extend, so only modules are checked.
+func (p *initPlan) variableConflicts() []string {
+	type declaration struct {
+		variable config.Variable
+		source   *plannedSource
+	}
+	first := make(map[string]declaration)
+	isModule := make(map[*plannedSource]bool)
+	for _, m := range p.Modules {
+		isModule[m] = true
+	}
+
+	var conflicts []string
+	for _, s := range p.declarationOrder() {
+		if s.Manifest == nil {
+			continue
+		}
+		for _, v := range s.Manifest.Variables {
+			prev, ok := first[v.Name]
+			if !ok {
+				first[v.Name] = declaration{v, s}
+				continue
+			}
+			if !isModule[s] {
+				continue
+			}
+
+			if kind, prevKind := variableKind(v.Type), variableKind(prev.variable.Type); kind != prevKind {
+				conflicts = append(conflicts, fmt.Sprintf("variable %s: %s declares it as %s, but %s declares it as %s",
+					v.Name, s.name(), kind, prev.source.name(), prevKind))
+			} else if (kind == "choice" || kind == "list") && !sameChoices(v.Choices, prev.variable.Choices) {
+				conflicts = append(conflicts, fmt.Sprintf("variable %s: %s declares choices [%s], but %s declares [%s]",
+					v.Name, s.name(), strings.Join(v.Choices, ", "), prev.source.name(), strings.Join(prev.variable.Choices, ", ")))
+			}
+		}
+	}
+	return conflicts
+}
+
+// variableKind normalizes the type names accepted for a variable
+func variableKind(typ string) string {
+	switch typ {
+	case "", "string":
+		return "string"
+	case "select", "choice":
+		return "choice"
+	case "confirm", "boolean", "bool":
+		return "bool"
+	case "int", "integer", "number":
+		return "int"
+	case "list", "multiselect":
+		return "list"
+	}
+	return typ
+}
+
+// sameChoices reports whether two choice lists hold the same choices, in any
+// order
+func sameChoices(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// lists returns the names of the list variables
+func (p *initPlan) lists() []string {
+	var names []string
+	for _, v := range p.variables() {
+		if v.IsList() {
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
+
+// secrets returns the names of the secret variables. A computed variable
+// derived from a secret is a secret too.
+func (p *initPlan) secrets() map[string]bool {
+	secrets := make(map[string]bool)
+	for _, v := range p.variables() {
+		if v.Secret {
+			secrets[v.Name] = true
+		}
+	}
+
+	computed := make(map[string]string)
+	for _, s := range p.declarationOrder() {
+		if s.Manifest == nil {
+			continue
+		}
+		for name, value := range s.Manifest.Computed {
+			computed[name] = value
+		}
+	}
+
+	// Repeat until no more computed variables turn out to depend on secrets
+	for changed := true; changed; {
+		changed = false
+		for name, value := range computed {
+			if secrets[name] {
+				continue
+			}
+			for _, ref := range template.References(value) {
+				if secrets[ref] {
+					secrets[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return secrets
+}
+
+// printPlan writes a summary of the resolved sources
+func printPlan(w io.Writer, plan *initPlan) {
+	fmt.Fprintln(w, "📋 Plan:")
+	printPlannedSource(w, "base", plan.Base)
+	for _, m := range plan.Modules {
+		printPlannedSource(w, "module", m)
+	}
+}
+
+func printPlannedSource(w io.Writer, kind string, s *plannedSource) {
+	fmt.Fprintf(w, "  %-7s %s → %s\n", kind, s.Input, s.Source)
+}
+
+// printPendingVariables lists the variables that will be prompted for
+func printPendingVariables(w io.Writer, declared []config.Variable, vars map[string]string) {
+	var pending []config.Variable
+	for _, v := range declared {
+		if _, ok := vars[v.Name]; !ok {
+			pending = append(pending, v)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "📝 Variables to configure:")
+	for _, v := range pending {
+		fmt.Fprintf(w, "  %-20s %s\n", v.Name, v.Description)
+	}
+}
@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/registry"
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// writeTemplate creates a template directory with a scaffold.yaml and files
+func writeTemplate(t *testing.T, dir, manifest string, files map[string]string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create template dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scaffold.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+// useIndex points the registry at a fixture index for the duration of the test
+func useIndex(t *testing.T, content string) *registry.Registry {
+	t.Helper()
+
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "templates.yaml")
+	if err := os.WriteFile(indexPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	t.Setenv("SCAFFOLD_INDEX", indexPath)
+
+	reg, err := registry.New(dir)
+	if err != nil {
+		t.Fatalf("registry.New() error = %v", err)
+	}
+	return reg
+}
+
+func TestResolvePlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	baseDir := filepath.Join(tmpDir, "base")
+	authDir := filepath.Join(tmpDir, "auth")
+	pgDir := filepath.Join(tmpDir, "postgres")
+
+	writeTemplate(t, baseDir, `
+name: base
+type: base
+variables:
+  - name: project_name
+  - name: database
+    default: sqlite
+`, nil)
+	writeTemplate(t, authDir, `
+name: auth
+type: module
+variables:
+  - name: auth_provider
+`, nil)
+	writeTemplate(t, pgDir, `
+name: postgres
+type: module
+variables:
+  - name: database
+    default: postgres
+  - name: db_name
+`, nil)
+
+	reg := useIndex(t, `
+version: "1"
+official:
+  mybase:
+    source: "file:`+baseDir+`"
+    description: "Base template"
+aliases:
+  pg: "file:`+pgDir+`"
+`)
+
+	plan, err := resolvePlan(context.Background(), reg, "mybase", []string{"file:" + authDir, "pg"})
+	if err != nil {
+		t.Fatalf("resolvePlan() error = %v", err)
+	}
+
+	if plan.Base.Input != "mybase" || plan.Base.Resolved != "file:"+baseDir {
+		t.Errorf("Base = %+v, want mybase resolved to file:%s", plan.Base, baseDir)
+	}
+	if plan.Base.Source.Type != source.TypeFile {
+		t.Errorf("Base.Source.Type = %v, want %v", plan.Base.Source.Type, source.TypeFile)
+	}
+	if len(plan.Modules) != 2 {
+		t.Fatalf("len(Modules) = %d, want 2", len(plan.Modules))
+	}
+	if plan.Modules[1].Input != "pg" || plan.Modules[1].Source.URL != pgDir {
+		t.Errorf("Modules[1] = %+v, want pg resolved to %s", plan.Modules[1], pgDir)
+	}
+	if plan.Base.Manifest != nil {
+		t.Error("resolvePlan() should not fetch sources")
+	}
+
+	if err := plan.fetch(context.Background(), source.NewFetcher(t.TempDir())); err != nil {
+		t.Fatalf("fetch() error = %v", err)
+	}
+	if plan.Modules[0].Manifest.Name != "auth" {
+		t.Errorf("Modules[0].Manifest.Name = %v, want auth", plan.Modules[0].Manifest.Name)
+	}
+
+	// Variables are merged in composition order, first declaration wins
+	vars := plan.variables()
+	wantNames := []string{"project_name", "database", "auth_provider", "db_name"}
+	if len(vars) != len(wantNames) {
+		t.Fatalf("len(variables) = %d, want %d", len(vars), len(wantNames))
+	}
+	for i, name := range wantNames {
+		if vars[i].Name != name {
+			t.Errorf("variables[%d] = %v, want %v", i, vars[i].Name, name)
+		}
+	}
+	if vars[1].Default != "sqlite" {
+		t.Errorf("database default = %v, want base's sqlite", vars[1].Default)
+	}
+}
+
+func TestResolvePlan_InvalidModule(t *testing.T) {
+	reg := useIndex(t, "version: \"1\"\n")
+
+	_, err := resolvePlan(context.Background(), reg, "file:./base", []string{"not-a-source"})
+	if err == nil {
+		t.Fatal("resolvePlan() should return error for an unparseable module")
+	}
+}
+
+func TestResolveSource_Suggestion(t *testing.T) {
+	reg := useIndex(t, `
+version: "1"
+official:
+  django:
+    source: "github:org/templates//django"
+community:
+  nextjs:
+    source: "github:org/templates//nextjs"
+aliases:
+  pg: "gitlab:acme/postgres-module"
+`)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string // Suggestion in the error, "" for none
+		wantErr bool
+	}{
+		{name: "official typo", input: "djanga", want: "django", wantErr: true},
+		{name: "community typo", input: "nexjs", want: "nextjs", wantErr: true},
+		{name: "nothing close", input: "rails", wantErr: true},
+		{name: "path is not a shorthand", input: "templates/djanga", wantErr: true},
+		{name: "url passes through", input: "https://example.com/djanga.tar.gz"},
+		{name: "provider passes through", input: "github:org/djanga"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveSource(context.Background(), reg, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveSource(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if got := strings.Contains(err.Error(), "did you mean"); got != (tt.want != "") {
+				t.Errorf("resolveSource(%q) error = %v, want suggestion %q", tt.input, err, tt.want)
+			} else if tt.want != "" && !strings.Contains(err.Error(), "did you mean "+tt.want+"?") {
+				t.Errorf("resolveSource(%q) error = %v, want suggestion %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinVersion(t *testing.T) {
+	reg := useIndex(t, `
+version: "1"
+official:
+  django:
+    source: "github:org/templates//django"
+`)
+
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantWarn bool
+		wantErr  bool
+	}{
+		{name: "registry shorthand", input: "django", want: "v2.1.0"},
+		{name: "source without ref", input: "github:org/repo", want: "v2.1.0"},
+		{name: "same ref", input: "github:org/repo#v2.1.0", want: "v2.1.0"},
+		{name: "flag wins over ref", input: "github:org/repo#v1.0.0", want: "v2.1.0", wantWarn: true},
+		{name: "not a git source", input: "https://example.com/t.tar.gz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr := useLogger(t, logger.Normal)
+			s, err := resolveSource(context.Background(), reg, tt.input)
+			if err != nil {
+				t.Fatalf("resolveSource() error = %v", err)
+			}
+
+			err = s.pinVersion(log, "v2.1.0")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pinVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if s.Source.Ref != tt.want {
+				t.Errorf("Ref = %q, want %q", s.Source.Ref, tt.want)
+			}
+			if warned := strings.Contains(stderr.String(), "overrides the ref v1.0.0"); warned != tt.wantWarn {
+				t.Errorf("warning = %q, want warning %v", stderr.String(), tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestOrderByRequires(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     []string
+		requires  map[string][]string
+		want      []string
+		wantCycle string
+	}{
+		{
+			name:     "acyclic",
+			names:    []string{"base", "api", "auth", "db"},
+			requires: map[string][]string{"api": {"auth", "db"}, "auth": {"db"}},
+			want:     []string{"base", "db", "auth", "api"},
+		},
+		{
+			name:     "independent modules keep order",
+			names:    []string{"base", "c", "b", "a"},
+			requires: map[string][]string{},
+			want:     []string{"base", "c", "b", "a"},
+		},
+		{
+			name:      "two-node cycle",
+			names:     []string{"base", "a", "b"},
+			requires:  map[string][]string{"a": {"b"}, "b": {"a"}},
+			wantCycle: "a -> b -> a",
+		},
+		{
+			name:      "longer cycle",
+			names:     []string{"base", "a", "b", "c", "d"},
+			requires:  map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"b"}},
+			wantCycle: "b -> c -> d -> b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderByRequires(tt.names, tt.requires)
+			if tt.wantCycle != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantCycle) {
+					t.Fatalf("orderByRequires() error = %v, want cycle %q", err, tt.wantCycle)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("orderByRequires() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderByRequires() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderModules(t *testing.T) {
+	plan := &initPlan{
+		Base: &plannedSource{Input: "base", Manifest: &config.Manifest{Name: "base"}},
+		Modules: []*plannedSource{
+			{Input: "github:org/api", Manifest: &config.Manifest{Name: "api", Requires: []string{"auth"}}},
+			{Input: "github:org/cache", Manifest: &config.Manifest{Name: "cache"}},
+			{Input: "github:org/auth", Manifest: &config.Manifest{Name: "auth", Requires: []string{"base"}}},
+			{Input: "github:org/metrics", Manifest: &config.Manifest{Name: "metrics"}},
+		},
+	}
+
+	if err := plan.orderModules(); err != nil {
+		t.Fatalf("orderModules() error = %v", err)
+	}
+
+	var got []string
+	for _, m := range plan.Modules {
+		got = append(got, m.name())
+	}
+	want := []string{"auth", "api", "cache", "metrics"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("module order = %v, want %v", got, want)
+	}
+	if plan.Base.name() != "base" {
+		t.Errorf("base = %v, want base", plan.Base.name())
+	}
+}
+
+func TestVariableConflicts(t *testing.T) {
+	base := &config.Manifest{Name: "base", Variables: []config.Variable{
+		{Name: "database", Type: "choice", Choices: []string{"postgres", "sqlite"}, Default: "sqlite"},
+		{Name: "use_docker", Type: "confirm"},
+		{Name: "org"},
+	}}
+
+	tests := []struct {
+		name   string
+		parent []config.Variable
+		module []config.Variable
+		want   []string
+	}{
+		{
+			name: "benign reuse",
+			module: []config.Variable{
+				{Name: "database", Type: "select", Choices: []string{"sqlite", "postgres"}, Default: "postgres"},
+				{Name: "use_docker", Type: "bool", Default: "true"},
+				{Name: "org", Type: "string", Description: "GitHub organization"},
+			},
+		},
+		{
+			name: "type conflict",
+			module: []config.Variable{
+				{Name: "use_docker"},
+				{Name: "org", Type: "int"},
+			},
+			want: []string{
+				"variable use_docker: mod declares it as string, but base declares it as bool",
+				"variable org: mod declares it as int, but base declares it as string",
+			},
+		},
+		{
+			name: "choice conflict",
+			module: []config.Variable{
+				{Name: "database", Type: "choice", Choices: []string{"mysql", "postgres"}},
+			},
+			want: []string{"variable database: mod declares choices [mysql, postgres], but base declares [postgres, sqlite]"},
+		},
+		{
+			name:   "template overriding its parent",
+			parent: []config.Variable{{Name: "use_docker", Type: "string"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &initPlan{
+				Parents: []*plannedSource{{Input: "parent", Manifest: &config.Manifest{Name: "parent", Variables: tt.parent}}},
+				Base:    &plannedSource{Input: "base", Manifest: base},
+				Modules: []*plannedSource{{Input: "mod", Manifest: &config.Manifest{Name: "mod", Variables: tt.module}}},
+			}
+			if got := plan.variableConflicts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("variableConflicts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderModules_Missing(t *testing.T) {
+	plan := &initPlan{
+		Base: &plannedSource{Input: "base", Manifest: &config.Manifest{Name: "base"}},
+		Modules: []*plannedSource{
+			{Input: "api", Manifest: &config.Manifest{Name: "api", Requires: []string{"auth"}}},
+		},
+	}
+
+	err := plan.orderModules()
+	if err == nil || !strings.Contains(err.Error(), "requires module auth") {
+		t.Errorf("orderModules() error = %v, want missing requirement error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/makemore/scaffold/internal/diff"
+	"github.com/makemore/scaffold/internal/template"
+)
+
+// Change statuses reported by a dry run
+const (
+	changeCreate    = "create"
+	changeOverwrite = "overwrite"
+	changeUnchanged = "unchanged"
+	changeSkip      = "skip"
+)
+
+// fileChange is what generating a file would do to the output directory
+type fileChange struct {
+	Path   string
+	Status string
+	Patch  string // Unified diff from the existing file, for overwrites
+}
+
+// previewChanges compares a staged render against the output directory
+// without touching it. Written files are reported as created, overwritten
+// (with a diff) or unchanged, followed by the skipped files.
+func previewChanges(renderDir, outDir string, result *template.Result) ([]fileChange, error) {
+	var changes []fileChange
+	seen := make(map[string]bool)
+
+	written := append(append([]string{}, result.Created...), result.Overwritten...)
+	for _, p := range written {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		rendered, err := readIfExists(filepath.Join(renderDir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		dest := filepath.Join(outDir, filepath.FromSlash(p))
+		existing, err := readIfExists(dest)
+		if err != nil {
+			return nil, err
+		}
+		_, statErr := os.Stat(dest)
+
+		change := fileChange{Path: p}
+		switch {
+		case os.IsNotExist(statErr):
+			change.Status = changeCreate
+		case bytes.Equal(rendered, existing):
+			change.Status = changeUnchanged
+		case bytes.IndexByte(rendered, 0) >= 0 || bytes.IndexByte(existing, 0) >= 0:
+			change.Status = changeOverwrite
+			change.Patch = fmt.Sprintf("Binary files a/%s and b/%s differ\n", p, p)
+		default:
+			change.Status = changeOverwrite
+			change.Patch = diff.Unified("a/"+p, "b/"+p, string(existing), string(rendered))
+		}
+		changes = append(changes, change)
+	}
+
+	for _, p := range result.Skipped {
+		changes = append(changes, fileChange{Path: p, Status: changeSkip})
+	}
+	return changes, nil
+}
+
+// printChanges writes each change's status, with the diff of overwrites
+func printChanges(w io.Writer, changes []fileChange) {
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %-10s %s\n", c.Status, c.Path)
+		if c.Patch != "" {
+			fmt.Fprint(w, c.Patch)
+		}
+	}
+}
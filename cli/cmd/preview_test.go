@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/template"
+)
+
+func TestPreviewChanges(t *testing.T) {
+	renderDir := t.TempDir()
+	outDir := t.TempDir()
+
+	write := func(dir, path, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write(renderDir, "README.md", "# myapp\n")
+	write(outDir, "README.md", "# myapp\n")
+	write(renderDir, "settings.py", "DEBUG = False\n")
+	write(outDir, "settings.py", "DEBUG = True\n")
+	write(renderDir, "main.py", "print('hi')\n")
+
+	result := &template.Result{
+		Created:     []string{"README.md", "settings.py", "main.py"},
+		Overwritten: []string{"settings.py"},
+		Skipped:     []string{"docker/"},
+	}
+	changes, err := previewChanges(renderDir, outDir, result)
+	if err != nil {
+		t.Fatalf("previewChanges() error = %v", err)
+	}
+
+	want := []struct{ path, status string }{
+		{"README.md", changeUnchanged},
+		{"settings.py", changeOverwrite},
+		{"main.py", changeCreate},
+		{"docker/", changeSkip},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d entries", changes, len(want))
+	}
+	for i, w := range want {
+		if changes[i].Path != w.path || changes[i].Status != w.status {
+			t.Errorf("changes[%d] = %s %s, want %s %s", i, changes[i].Status, changes[i].Path, w.status, w.path)
+		}
+	}
+
+	patch := changes[1].Patch
+	for _, line := range []string{"--- a/settings.py", "+++ b/settings.py", "-DEBUG = True", "+DEBUG = False"} {
+		if !strings.Contains(patch, line) {
+			t.Errorf("patch missing %q:\n%s", line, patch)
+		}
+	}
+	if changes[0].Patch != "" || changes[2].Patch != "" {
+		t.Error("only overwrites should carry a diff")
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "main.py")); !os.IsNotExist(err) {
+		t.Error("previewChanges() should not write to the output directory")
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	removeDir   string
+	removeForce bool
+)
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <module>",
+	Short: "Remove a module's files from a generated project",
+	Long: `Remove the files a module introduced, using the file manifest recorded
+in scaffold.lock.
+
+Only files created by the module alone are deleted. Files that another
+source also produced are left in place, since their original content
+was not stored. Files modified since generation are not removed unless
+--force is given.`,
+	Example: `  scaffold remove postgres
+  scaffold remove github:org/mod-auth --dir ./myapp --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().StringVarP(&removeDir, "dir", "C", ".", "Project directory")
+	removeCmd.Flags().BoolVarP(&removeForce, "force", "f", false, "Remove files even if modified since generation")
+}
+
+func runRemove(cmd *cobra.Command, args []string) error {
+	lock, err := config.LoadLockfile(removeDir)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return fmt.Errorf("no %s found in %s", config.LockFile, removeDir)
+	}
+
+	module := lock.Module(args[0])
+	if module == nil {
+		return fmt.Errorf("module %s is not part of this project", args[0])
+	}
+
+	plan, err := planRemoval(lock, module, removeDir)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Modified) > 0 && !removeForce {
+		return fmt.Errorf("files modified since generation (use --force to remove anyway):\n  %s",
+			strings.Join(plan.Modified, "\n  "))
+	}
+
+	toDelete := plan.Delete
+	if removeForce {
+		toDelete = append(toDelete, plan.Modified...)
+	}
+	if err := deleteFiles(removeDir, toDelete); err != nil {
+		return err
+	}
+
+	name := module.Name
+	lock.RemoveModule(name)
+	if err := config.SaveLockfile(removeDir, lock); err != nil {
+		return err
+	}
+
+	fmt.Printf("🗑️  Removed module %s (%d files)\n", name, len(toDelete))
+	for _, path := range plan.Shared {
+		fmt.Printf("  kept %s (also produced by another source)\n", path)
+	}
+	return nil
+}
+
+// removalPlan describes the effect of removing a module
+type removalPlan struct {
+	Delete   []string // Introduced only by the module and unchanged
+	Modified []string // Introduced only by the module but changed since generation
+	Shared   []string // Also produced by another source, left in place
+	Missing  []string // Already deleted
+}
+
+// planRemoval sorts a module's recorded files by what removing it should do
+func planRemoval(lock *config.Lockfile, module *config.LockedSource, dir string) (*removalPlan, error) {
+	others := make(map[string]bool)
+	for _, s := range lock.Sources() {
+		if s == module {
+			continue
+		}
+		for _, f := range s.Files {
+			others[f.Path] = true
+		}
+	}
+
+	plan := &removalPlan{}
+	for _, f := range module.Files {
+		if others[f.Path] {
+			plan.Shared = append(plan.Shared, f.Path)
+			continue
+		}
+
+		hash, err := config.HashFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				plan.Missing = append(plan.Missing, f.Path)
+				continue
+			}
+			return nil, fmt.Errorf("failed to hash %s: %w", f.Path, err)
+		}
+
+		if hash != f.Hash {
+			plan.Modified = append(plan.Modified, f.Path)
+		} else {
+			plan.Delete = append(plan.Delete, f.Path)
+		}
+	}
+	return plan, nil
+}
+
+// deleteFiles removes the given files and any directories left empty
+func deleteFiles(dir string, paths []string) error {
+	root := filepath.Clean(dir)
+
+	for _, p := range paths {
+		fullPath := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+
+		// Prune parent directories that are now empty
+		for parent := filepath.Dir(fullPath); parent != root; parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestPlanRemoval(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":             "# app",
+		"db/settings.py":        "DB = 'postgres'",
+		"db/migrations/0001.py": "# initial",
+		"docker-compose.yml":    "services: {}",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	baseFiles, err := config.LockFiles(dir, []string{"README.md", "docker-compose.yml"})
+	if err != nil {
+		t.Fatalf("LockFiles() error = %v", err)
+	}
+	moduleFiles, err := config.LockFiles(dir, []string{"db/settings.py", "db/migrations/0001.py", "docker-compose.yml"})
+	if err != nil {
+		t.Fatalf("LockFiles() error = %v", err)
+	}
+	moduleFiles = append(moduleFiles, config.LockedFile{Path: "db/gone.py", Hash: "sha256:00"})
+
+	lock := &config.Lockfile{
+		Base:    config.LockedSource{Name: "base", Files: baseFiles},
+		Modules: []config.LockedSource{{Name: "postgres", Source: "file:./postgres", Files: moduleFiles}},
+	}
+
+	// Modify a file after generation
+	if err := os.WriteFile(filepath.Join(dir, "db/settings.py"), []byte("DB = 'mysql'"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+
+	module := lock.Module("postgres")
+	plan, err := planRemoval(lock, module, dir)
+	if err != nil {
+		t.Fatalf("planRemoval() error = %v", err)
+	}
+
+	want := &removalPlan{
+		Delete:   []string{"db/migrations/0001.py"},
+		Modified: []string{"db/settings.py"},
+		Shared:   []string{"docker-compose.yml"},
+		Missing:  []string{"db/gone.py"},
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("planRemoval() = %+v, want %+v", plan, want)
+	}
+
+	// Deleting prunes directories left empty but keeps others
+	if err := deleteFiles(dir, plan.Delete); err != nil {
+		t.Fatalf("deleteFiles() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "db/migrations")); !os.IsNotExist(err) {
+		t.Error("db/migrations should be pruned once empty")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "db/settings.py")); err != nil {
+		t.Error("db/settings.py should be kept")
+	}
+
+	lock.RemoveModule("postgres")
+	if len(lock.Modules) != 0 {
+		t.Errorf("len(Modules) = %d, want 0 after RemoveModule", len(lock.Modules))
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makemore/scaffold/internal/source"
+	"github.com/spf13/cobra"
+)
+
+var resolveJSON bool
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <source>",
+	Short: "Show what a template name or source resolves to",
+	Long: `Resolve a template name, alias or source the way init does and print the
+parsed source, without fetching anything.
+
+Useful for checking what an index entry, alias or provider shorthand points
+at before using it.`,
+	Example: `  scaffold resolve django
+  scaffold resolve github:org/repo//templates/api#v1.2.0 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResolve,
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+
+	resolveCmd.Flags().BoolVar(&resolveJSON, "json", false, "Output the resolved source as JSON")
+}
+
+func runResolve(cmd *cobra.Command, args []string) error {
+	reg, err := newRegistry()
+	if err != nil {
+		return err
+	}
+
+	s, err := resolveSource(commandContext(cmd), reg, args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	if resolveJSON {
+		return printResolvedJSON(os.Stdout, s)
+	}
+	printResolved(os.Stdout, s)
+	return nil
+}
+
+// resolvedJSON is the --json representation of a resolved source
+type resolvedJSON struct {
+	Input    string `json:"input"`
+	Resolved string `json:"resolved"`
+	Type     string `json:"type"`
+	Provider string `json:"provider,omitempty"`
+	URL      string `json:"url"`
+	Subdir   string `json:"subdir,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+}
+
+// printResolved writes the fields of a resolved source as aligned rows
+func printResolved(w io.Writer, s *plannedSource) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range [][2]string{
+		{"Input", source.Redact(s.Input)},
+		{"Resolved", source.Redact(s.Resolved)},
+		{"Type", string(s.Source.Type)},
+		{"Provider", s.Source.Provider},
+		{"URL", source.Redact(s.Source.URL)},
+		{"Subdir", s.Source.Subdir},
+		{"Ref", s.Source.Ref},
+	} {
+		value := row[1]
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(tw, "%s:\t%s\n", row[0], value)
+	}
+	tw.Flush()
+}
+
+// printResolvedJSON writes a resolved source as a JSON object
+func printResolvedJSON(w io.Writer, s *plannedSource) error {
+	data, err := json.MarshalIndent(resolvedJSON{
+		Input:    source.Redact(s.Input),
+		Resolved: source.Redact(s.Resolved),
+		Type:     string(s.Source.Type),
+		Provider: s.Source.Provider,
+		URL:      source.Redact(s.Source.URL),
+		Subdir:   s.Source.Subdir,
+		Ref:      s.Source.Ref,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode source: %w", err)
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
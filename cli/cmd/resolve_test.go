@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const resolveIndex = `
+version: "1"
+official:
+  django:
+    source: "github:scaffold-dev/scaffold//templates/django-base#v1.4.0"
+    description: "Django REST API"
+aliases:
+  dj: django
+  pg: "gitlab:acme/postgres-module"
+`
+
+func TestResolve(t *testing.T) {
+	reg := useIndex(t, resolveIndex)
+
+	tests := []struct {
+		name  string
+		input string
+		want  resolvedJSON
+	}{
+		{
+			name:  "official",
+			input: "django",
+			want: resolvedJSON{
+				Input:    "django",
+				Resolved: "github:scaffold-dev/scaffold//templates/django-base#v1.4.0",
+				Type:     "git",
+				Provider: "github",
+				URL:      "https://github.com/scaffold-dev/scaffold",
+				Subdir:   "templates/django-base",
+				Ref:      "v1.4.0",
+			},
+		},
+		{
+			name:  "alias to official",
+			input: "dj",
+			want: resolvedJSON{
+				Input:    "dj",
+				Resolved: "github:scaffold-dev/scaffold//templates/django-base#v1.4.0",
+				Type:     "git",
+				Provider: "github",
+				URL:      "https://github.com/scaffold-dev/scaffold",
+				Subdir:   "templates/django-base",
+				Ref:      "v1.4.0",
+			},
+		},
+		{
+			name:  "alias to source",
+			input: "pg",
+			want: resolvedJSON{
+				Input:    "pg",
+				Resolved: "gitlab:acme/postgres-module",
+				Type:     "git",
+				Provider: "gitlab",
+				URL:      "https://gitlab.com/acme/postgres-module",
+			},
+		},
+		{
+			name:  "pass-through URL",
+			input: "https://example.com/template.tar.gz",
+			want: resolvedJSON{
+				Input:    "https://example.com/template.tar.gz",
+				Resolved: "https://example.com/template.tar.gz",
+				Type:     "url",
+				URL:      "https://example.com/template.tar.gz",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := resolveSource(context.Background(), reg, tt.input)
+			if err != nil {
+				t.Fatalf("resolveSource() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := printResolvedJSON(&buf, s); err != nil {
+				t.Fatalf("printResolvedJSON() error = %v", err)
+			}
+			var got resolvedJSON
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolved = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintResolved(t *testing.T) {
+	reg := useIndex(t, resolveIndex)
+	s, err := resolveSource(context.Background(), reg, "pg")
+	if err != nil {
+		t.Fatalf("resolveSource() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	printResolved(&buf, s)
+
+	want := strings.Join([]string{
+		"Input:     pg",
+		"Resolved:  gitlab:acme/postgres-module",
+		"Type:      git",
+		"Provider:  gitlab",
+		"URL:       https://gitlab.com/acme/postgres-module",
+		"Subdir:    -",
+		"Ref:       -",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("printResolved() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/registry"
+	"github.com/makemore/scaffold/internal/retry"
+	"github.com/makemore/scaffold/internal/source"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +24,29 @@ var (
 	Commit = "none"
 )
 
+var (
+	quiet      bool
+	verbose    bool
+	retries    int
+	timeout    time.Duration
+	cloneDepth int
+	submodules bool
+	// cacheDirFlag is --cache-dir, which overrides SCAFFOLD_CACHE_DIR and
+	// cache_dir in the config
+	cacheDirFlag string
+	// noEmbeddedFallback fails instead of using the built-in index
+	noEmbeddedFallback bool
+	// noCache fetches indexes and templates again instead of using the cache
+	noCache bool
+)
+
+// log writes progress to stderr and results to stdout at the level chosen
+// with --quiet or --verbose
+var log = logger.New(os.Stdout, os.Stderr, logger.Normal)
+
+// userConfig holds defaults from ~/.scaffold/config.yaml and .scaffoldrc
+var userConfig = &config.UserConfig{}
+
 var rootCmd = &cobra.Command{
 	Use:   "scaffold",
 	Short: "Bootstrap any software stack with sensible defaults",
@@ -32,15 +65,146 @@ Example:
   scaffold init myapp --base git:https://github.com/org/template
   scaffold init myapp --base file:~/templates/base --add file:./modules/postgres`,
 	Version: fmt.Sprintf("%s (commit: %s)", Version, Commit),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case quiet:
+			log.Level = logger.Quiet
+		case verbose:
+			log.Level = logger.Verbose
+		}
+		if retries < 1 {
+			return fmt.Errorf("--retries must be at least 1")
+		}
+		if cloneDepth < 0 {
+			return fmt.Errorf("--depth must not be negative")
+		}
+		return loadUserConfig()
+	},
 }
 
-// Execute runs the root command
+// Execute runs the root command. An interrupt or termination signal cancels
+// the command's context, so clones and processing stop and partial output is
+// removed.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// commandContext returns the context of a running command, or a background
+// context when the command was invoked directly
+func commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// loadUserConfig reads the user and project config files
+func loadUserConfig() error {
+	home, _ := os.UserHomeDir()
+	cwd, _ := os.Getwd()
+
+	cfg, err := config.LoadUserConfig(home, cwd)
+	if err != nil {
+		return err
+	}
+	userConfig = cfg
+	return nil
+}
+
+// cacheDirEnv overrides the cache directory, after --cache-dir
+const cacheDirEnv = "SCAFFOLD_CACHE_DIR"
+
+// cacheDir returns the cache directory from --cache-dir, SCAFFOLD_CACHE_DIR or
+// cache_dir in the config, in that order, or "" for the default
+func cacheDir() string {
+	for _, dir := range []string{cacheDirFlag, os.Getenv(cacheDirEnv), userConfig.CacheDir} {
+		if dir != "" {
+			return absPath(dir)
+		}
+	}
+	return ""
+}
+
+// cacheSubdir returns a subdirectory of the configured cache directory, or ""
+// for the registry's or fetcher's default. Indexes and templates are cached
+// apart so that neither mistakes the other's files for its own.
+func cacheSubdir(name string) string {
+	dir := cacheDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
+
+// newRegistry returns a registry using the configured cache, extra indexes,
+// index public key, log level, retries and timeout
+func newRegistry() (*registry.Registry, error) {
+	reg, err := registry.New(cacheSubdir(registry.CacheSubdir))
+	if err != nil {
+		return nil, err
+	}
+	reg.Log = log
+	reg.Retry.Attempts = retries
+	reg.Timeout = timeout
+	reg.NoEmbeddedFallback = noEmbeddedFallback || userConfig.NoEmbeddedFallback
+	reg.NoCache = noCache
+
+	if userConfig.IndexPublicKey != "" {
+		key, err := registry.ParsePublicKey(userConfig.IndexPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		reg.PublicKey = key
+	}
+
+	for _, idx := range userConfig.Indexes {
+		src := registry.IndexSource{URL: idx.URL, Override: idx.Override}
+		if idx.TTL != "" {
+			ttl, err := parseAge(idx.TTL)
+			if err != nil {
+				log.Warn("index %s: %v, using the default", idx.URL, err)
+			}
+			src.TTL = ttl
+		}
+		reg.AddIndex(src)
+	}
+	return reg, nil
+}
+
+// newFetcher returns a fetcher using the configured cache, log level, retries
+// and timeout
+func newFetcher() *source.Fetcher {
+	fetcher := source.NewFetcher(cacheSubdir(source.CacheSubdir))
+	fetcher.Log = log
+	fetcher.Retry.Attempts = retries
+	fetcher.Timeout = timeout
+	fetcher.Depth = cloneDepth
+	fetcher.Submodules = submodules
+	fetcher.NoCache = noCache
+	switch c := fetcher.Cloner.(type) {
+	case *source.ExecCloner:
+		c.Log = log
+		c.Timeout = timeout
+	case *source.GoGitCloner:
+		c.Timeout = timeout
+	}
+	return fetcher
 }
 
 func init() {
 	rootCmd.SetOut(os.Stdout)
 	rootCmd.SetErr(os.Stderr)
-}
 
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only show errors")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Show debug output")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", retry.DefaultAttempts, "Attempts for each network fetch, 1 to disable retrying")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Limit for each network fetch, e.g. 30s (0 for the defaults)")
+	rootCmd.PersistentFlags().IntVar(&cloneDepth, "depth", 1, "Git clone depth, 0 for full history (commits the server won't fetch by SHA are cloned in full)")
+	rootCmd.PersistentFlags().BoolVar(&submodules, "recurse-submodules", false, "Check out the submodules of git templates")
+	rootCmd.PersistentFlags().StringVar(&cacheDirFlag, "cache-dir", "", "Cache directory for indexes and templates (default ~/.scaffold/cache)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Fetch template indexes and templates again instead of using the cache, leaving it as it is")
+	rootCmd.PersistentFlags().BoolVar(&noEmbeddedFallback, "no-embedded-fallback", false, "Fail when the template index can't be fetched or read from the cache, instead of using the built-in copy")
+}
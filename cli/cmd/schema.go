@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for scaffold.yaml",
+	Long: `Print a JSON Schema describing the scaffold.yaml manifest format.
+
+Point your editor's YAML language server at the output for autocomplete
+and validation while authoring templates.`,
+	Example: `  scaffold schema > scaffold.schema.json`,
+	Args:    cobra.NoArgs,
+	RunE:    runSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	data, err := json.MarshalIndent(config.ManifestSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	fmt.Println(string(data))
+	return nil
+}
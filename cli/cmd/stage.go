@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// stageDir creates an empty staging directory next to outDir, so that moving
+// it into place is a rename on the same filesystem
+func stageDir(outDir string) (string, error) {
+	parent := filepath.Dir(filepath.Clean(outDir))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, ".scaffold-stage-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return dir, nil
+}
+
+// commitStaged moves a fully generated staging directory to outDir. A new
+// output directory is a single rename. An existing one has each staged
+// directory created and each staged file moved in, with the files it replaces
+// kept aside until every move succeeded so that a failure puts the directory
+// back as it was.
+func commitStaged(staging, outDir string) error {
+	if _, err := os.Stat(outDir); os.IsNotExist(err) {
+		if err := os.Rename(staging, outDir); err != nil {
+			return fmt.Errorf("failed to move project into place: %w", err)
+		}
+		return nil
+	}
+
+	var dirs, files []string
+	err := filepath.WalkDir(staging, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == staging {
+			return err
+		}
+		rel, err := filepath.Rel(staging, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, rel)
+		} else {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read staged files: %w", err)
+	}
+
+	backup, err := os.MkdirTemp(filepath.Dir(filepath.Clean(outDir)), ".scaffold-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	defer os.RemoveAll(backup)
+
+	var created, moved, replaced []string
+	rollback := func() {
+		for _, rel := range moved {
+			os.Remove(filepath.Join(outDir, rel))
+		}
+		for _, rel := range replaced {
+			os.Rename(filepath.Join(backup, rel), filepath.Join(outDir, rel))
+		}
+		for i := len(created) - 1; i >= 0; i-- {
+			os.Remove(filepath.Join(outDir, created[i]))
+		}
+	}
+
+	// Directories come before their contents, so each one's parent exists
+	for _, rel := range dirs {
+		dest := filepath.Join(outDir, rel)
+		if _, err := os.Lstat(dest); err == nil {
+			continue
+		}
+		if err := os.Mkdir(dest, 0755); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create %s: %w", rel, err)
+		}
+		created = append(created, rel)
+	}
+
+	for _, rel := range files {
+		dest := filepath.Join(outDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			rollback()
+			return fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		if _, err := os.Lstat(dest); err == nil {
+			kept := filepath.Join(backup, rel)
+			if err := os.MkdirAll(filepath.Dir(kept), 0755); err != nil {
+				rollback()
+				return fmt.Errorf("failed to back up %s: %w", rel, err)
+			}
+			if err := os.Rename(dest, kept); err != nil {
+				rollback()
+				return fmt.Errorf("failed to back up %s: %w", rel, err)
+			}
+			replaced = append(replaced, rel)
+		}
+		if err := os.Rename(filepath.Join(staging, rel), dest); err != nil {
+			rollback()
+			return fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		moved = append(moved, rel)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/makemore/scaffold/internal/condition"
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var validateLint bool
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [dir]",
+	Short: "Check a template for mistakes before publishing it",
+	Long: `Check the template in a directory, the current one by default: its
+scaffold.yaml must parse and pass validation, and its conditions and file
+patterns must be well-formed. Errors fail the command.
+
+With --lint, also warn about likely mistakes that don't stop the template
+from working: variables declared but never used, variables files use that
+have neither a default nor required set (they stay unset with --no-prompt),
+command actions without a command and choice variables with a single
+choice.`,
+	Example: `  scaffold validate
+  scaffold validate ./templates/api --lint`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().BoolVar(&validateLint, "lint", false, "Also warn about likely mistakes")
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	manifest, count, warnings, err := validateTemplate(dir, validateLint)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		log.Warn("%s", w)
+	}
+	name := manifest.Name
+	if name == "" {
+		name = dir
+	}
+	log.Result("✅ %s is valid (%d files)", name, count.Files)
+	if len(warnings) > 0 {
+		log.Result("   %d lint warnings", len(warnings))
+	}
+	return nil
+}
+
+// validateTemplate loads and checks the template in dir, returning its
+// manifest, its file count and, with lint, the warnings about it
+func validateTemplate(dir string, lint bool) (*config.Manifest, template.FileCount, []string, error) {
+	var count template.FileCount
+	manifest, err := config.LoadManifest(dir)
+	if err != nil {
+		return nil, count, nil, err
+	}
+	if err := checkConditions(manifest); err != nil {
+		return nil, count, nil, err
+	}
+	count, err = template.NewProcessor(manifest, dir, "").Count()
+	if err != nil {
+		return nil, count, nil, err
+	}
+	if !lint {
+		return manifest, count, nil, nil
+	}
+
+	warnings, err := template.Lint(manifest, dir)
+	if err != nil {
+		return nil, count, nil, err
+	}
+	return manifest, count, warnings, nil
+}
+
+// checkConditions parses every condition in the manifest, which otherwise
+// only fails once a generation evaluates it
+func checkConditions(m *config.Manifest) error {
+	check := func(what, expr string) error {
+		if _, err := condition.Evaluate(expr, nil); err != nil {
+			return fmt.Errorf("%s: %w", what, err)
+		}
+		return nil
+	}
+
+	for _, v := range m.Variables {
+		if err := check("variable "+v.Name+" show_if", v.ShowIf); err != nil {
+			return err
+		}
+	}
+	patterns := make([]string, 0, len(m.Files.Conditions))
+	for pattern := range m.Files.Conditions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if err := check("file condition "+pattern, m.Files.Conditions[pattern]); err != nil {
+			return err
+		}
+	}
+	patterns = patterns[:0]
+	for pattern := range m.Files.DirConditions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if err := check("directory condition "+pattern, m.Files.DirConditions[pattern]); err != nil {
+			return err
+		}
+	}
+	for _, a := range m.Actions {
+		if err := check("action "+a.Name+" condition", a.Condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		manifest     string
+		lint         bool
+		wantErr      string
+		wantWarnings []string
+	}{
+		{
+			name: "valid",
+			manifest: `
+name: api
+variables:
+  - name: org
+    default: acme
+  - name: license
+`,
+		},
+		{
+			name: "lint",
+			manifest: `
+name: api
+variables:
+  - name: org
+  - name: license
+    default: MIT
+actions:
+  - name: install
+    type: command
+`,
+			lint: true,
+			wantWarnings: []string{
+				"action install has no command",
+				"README.md uses org, which has no default and isn't required, so it is left unset with --no-prompt",
+				"variable license is declared but never used",
+			},
+		},
+		{
+			name:     "unknown field",
+			manifest: "name: api\nvariabels: []\n",
+			wantErr:  "variabels",
+		},
+		{
+			name: "bad condition",
+			manifest: `
+name: api
+files:
+  conditions:
+    "docker/*": "use_docker &&"
+`,
+			wantErr: "file condition docker/*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "api")
+			writeTemplate(t, dir, tt.manifest, map[string]string{"README.md": "# {{ org }}"})
+
+			_, count, warnings, err := validateTemplate(dir, tt.lint)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validateTemplate() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateTemplate() error = %v", err)
+			}
+			if count.Files != 1 {
+				t.Errorf("files = %d, want 1", count.Files)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings =\n%q\nwant\n%q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,507 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/template"
+)
+
+// envVarPrefix is the prefix for environment variables that seed template variables
+const envVarPrefix = "SCAFFOLD_VAR_"
+
+// defaultsFileEnv names the shared defaults file, overriding defaults_file
+const defaultsFileEnv = "SCAFFOLD_DEFAULTS_FILE"
+
+// collectVariables builds the variable map. Precedence (highest first):
+//  1. --var flags, then --set flags, the last of each for a name winning,
+//     then --var-file flags, which can't name a variable given with either
+//  2. project_name from the project name
+//  3. --profile values
+//  4. --vars-file values
+//  5. SCAFFOLD_VAR_<NAME> environment variables
+//  6. config file defaults
+//  7. the shared defaults file
+//  8. the user's git config, for variables with source: git_config
+//  9. project_slug derived from project_name
+//  10. manifest defaults
+func collectVariables(manifest *config.Manifest, projectName string, configVars map[string]string) (map[string]string, error) {
+	vars := make(map[string]string)
+	file, prompting := replaySession()
+
+	// Parse --var flags
+	for _, v := range variables {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 {
+			value, err := flagValue(parts[0], parts[1])
+			if err != nil {
+				return nil, err
+			}
+			vars[parts[0]] = value
+		}
+	}
+
+	// Apply --set flags, whose dotted names gotemplate files see nested. A
+	// later --set overrides an earlier one, as with --var, but not a --var.
+	fromVar := make(map[string]bool, len(vars))
+	for name := range vars {
+		fromVar[name] = true
+	}
+	fromSet := make(map[string]bool)
+	for _, s := range setValues {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || !dottedName.MatchString(name) {
+			return nil, fmt.Errorf("invalid --set %q: want name.key=value", s)
+		}
+		fromSet[name] = true
+		if !fromVar[name] {
+			vars[name] = value
+		}
+	}
+
+	// Apply --var-file flags. A value given with --var or --set as well is
+	// likely a mistake, so neither is picked over the other.
+	for _, v := range varFiles {
+		name, path, ok := strings.Cut(v, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid --var-file %q: want name=path", v)
+		}
+		switch {
+		case fromVar[name]:
+			return nil, fmt.Errorf("variable %s is given with both --var and --var-file", name)
+		case fromSet[name]:
+			return nil, fmt.Errorf("variable %s is given with both --set and --var-file", name)
+		}
+		value, err := readVarFile(name, path)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+
+	// The project name given on the command line wins over files of defaults
+	setMissing(vars, map[string]string{"project_name": projectName})
+
+	// Apply --profile values over the --vars-file values
+	if profile != "" {
+		profileVars, err := profileVariables(profile, file)
+		if err != nil {
+			return nil, err
+		}
+		setMissing(vars, profileVars)
+	}
+
+	// Apply --vars-file values
+	if file != "" {
+		fileVars, err := config.LoadVarsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		setMissing(vars, fileVars)
+	}
+
+	// Apply environment variables
+	setMissing(vars, envVariables(manifest, os.Environ()))
+
+	// Apply config file defaults. They are shared by every template, so one
+	// that isn't among a choice variable's choices is left out.
+	setMissing(vars, withoutInvalidChoices(manifest, configVars))
+
+	// Apply the shared defaults file, likewise shared by every template
+	sharedVars, err := sharedDefaults()
+	if err != nil {
+		return nil, err
+	}
+	setMissing(vars, withoutInvalidChoices(manifest, sharedVars))
+
+	// Apply values from the user's git config
+	setMissing(vars, withoutInvalidChoices(manifest, gitConfigDefaults(manifest, gitConfig)))
+
+	// Derive project_slug unless given. It follows a project_name given with
+	// --var.
+	setMissing(vars, map[string]string{"project_slug": template.Slug(vars["project_name"])})
+
+	// Apply defaults for missing variables. A default referencing a
+	// variable that will only be known once prompted is left to the prompt.
+	for _, v := range manifest.Variables {
+		if _, ok := vars[v.Name]; ok || v.Default == "" {
+			continue
+		}
+		def, err := template.ResolveDefault(v.Default, vars)
+		if err != nil {
+			if !prompting {
+				return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+			continue
+		}
+		vars[v.Name] = def
+	}
+
+	if err := checkChoices(manifest, vars); err != nil {
+		return nil, err
+	}
+	if _, err := template.Nest(vars); err != nil {
+		return nil, fmt.Errorf("conflicting variables: %w", err)
+	}
+	return vars, nil
+}
+
+// dottedName matches the variable names --set accepts: identifiers joined
+// by dots
+var dottedName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// flagValue returns the value of a --var flag. A value of @path is read from
+// the file at path, and a leading @@ stands for a literal @.
+func flagValue(name, value string) (string, error) {
+	if rest, ok := strings.CutPrefix(value, "@@"); ok {
+		return "@" + rest, nil
+	}
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		return readVarFile(name, path)
+	}
+	return value, nil
+}
+
+// readVarFile reads a variable's value from a file. A single trailing line
+// break is dropped, as a file written by an editor or echo ends with one.
+func readVarFile(name, path string) (string, error) {
+	data, err := os.ReadFile(absPath(path))
+	if err != nil {
+		return "", fmt.Errorf("variable %s: failed to read value: %w", name, err)
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
+
+// profileVariables loads the values of a profile: scaffold.<profile>.yaml,
+// next to the vars file when there is one and else in the working
+// directory, merged over the section of the same name in the vars file's
+// profiles. One of them must exist.
+func profileVariables(profile, varsFile string) (map[string]string, error) {
+	if profile == "." || profile == ".." || strings.ContainsAny(profile, `/\`) {
+		return nil, fmt.Errorf("invalid profile name %q", profile)
+	}
+
+	vars := make(map[string]string)
+	found := false
+	dir := "."
+	if varsFile != "" {
+		dir = filepath.Dir(varsFile)
+	}
+	path := filepath.Join(dir, config.ProfileFile(profile))
+	if _, err := os.Stat(path); err == nil {
+		fileVars, err := config.LoadVarsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		setMissing(vars, fileVars)
+		found = true
+	}
+
+	if varsFile != "" {
+		section, ok, err := config.LoadVarsProfile(varsFile, profile)
+		if err != nil {
+			return nil, err
+		}
+		setMissing(vars, section)
+		found = found || ok
+	}
+
+	switch {
+	case !found && varsFile != "":
+		return nil, fmt.Errorf("profile %s not found: no %s and no %s.%s in %s", profile, path, config.ProfilesKey, profile, varsFile)
+	case !found:
+		return nil, fmt.Errorf("profile %s not found: no %s", profile, path)
+	}
+	return vars, nil
+}
+
+// sharedDefaults loads the shared defaults file named by
+// SCAFFOLD_DEFAULTS_FILE or defaults_file in the config, or else
+// ~/.scaffold/defaults.yaml. Only the last may be missing.
+func sharedDefaults() (map[string]string, error) {
+	path := os.Getenv(defaultsFileEnv)
+	if path == "" {
+		path = userConfig.DefaultsFile
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, config.UserConfigDir, config.DefaultsFile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	return config.LoadVarsFile(absPath(path))
+}
+
+// gitConfig returns the value of a key in the user's git config, or "" when
+// it is unset or git isn't available. Tests replace it.
+var gitConfig = func(key string) string {
+	out, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitConfigDefaults looks up the variables that default from git config.
+// Keys that are unset are left out.
+func gitConfigDefaults(manifest *config.Manifest, lookup func(key string) string) map[string]string {
+	vars := make(map[string]string)
+	for _, v := range manifest.Variables {
+		key := v.GitConfigKey()
+		if key == "" {
+			continue
+		}
+		if value := lookup(key); value != "" {
+			vars[v.Name] = value
+		}
+	}
+	return vars
+}
+
+// checkChoices returns an error for a choice variable holding a value that
+// is not one of its choices
+func checkChoices(manifest *config.Manifest, vars map[string]string) error {
+	for _, v := range manifest.Variables {
+		if value, ok := vars[v.Name]; ok {
+			if err := v.CheckChoice(value); err != nil {
+				return fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// withoutInvalidChoices returns the values of layer that their variable, if
+// it is a choice variable, can take
+func withoutInvalidChoices(manifest *config.Manifest, layer map[string]string) map[string]string {
+	valid := make(map[string]string, len(layer))
+	for name, value := range layer {
+		valid[name] = value
+	}
+	for _, v := range manifest.Variables {
+		if value, ok := valid[v.Name]; ok && v.CheckChoice(value) != nil {
+			delete(valid, v.Name)
+		}
+	}
+	return valid
+}
+
+// projectModuleVar is the built-in computed Go module path
+const projectModuleVar = "project_module"
+
+// moduleHostVar names the variable holding the host project_module starts
+// with, and defaultModuleHost is used when it is unset
+const (
+	moduleHostVar     = "module_host"
+	defaultModuleHost = "github.com"
+)
+
+// setProjectModule derives project_module from module_host, org and
+// project_slug, as <module_host>/<org>/<project_slug>, or the bare slug when
+// there is no org. module_host is github.com unless set like any other
+// variable, such as in the config file. A value that is already set is kept.
+func setProjectModule(vars map[string]string) {
+	if _, ok := vars[projectModuleVar]; ok {
+		return
+	}
+	module := vars["project_slug"]
+	if org := vars["org"]; org != "" {
+		host := vars[moduleHostVar]
+		if host == "" {
+			host = defaultModuleHost
+		}
+		module = host + "/" + org + "/" + module
+	}
+	vars[projectModuleVar] = template.ModulePath(module)
+}
+
+// envVariables extracts SCAFFOLD_VAR_<NAME> entries from environ. Names are
+// matched case-insensitively against the manifest's variables; unmatched
+// names are lowercased.
+func envVariables(manifest *config.Manifest, environ []string) map[string]string {
+	vars := make(map[string]string)
+
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(key, envVarPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, envVarPrefix)
+		if name == "" {
+			continue
+		}
+
+		resolved := strings.ToLower(name)
+		for _, v := range manifest.Variables {
+			if strings.EqualFold(v.Name, name) {
+				resolved = v.Name
+				break
+			}
+		}
+		vars[resolved] = value
+	}
+
+	return vars
+}
+
+// secretMask replaces secret values in output
+const secretMask = "********"
+
+// formatRecap lists the resolved variables in name order for confirmation,
+// masking the values of the secret variables
+func formatRecap(vars map[string]string, secrets map[string]bool) string {
+	names := make([]string, 0, len(vars))
+	width := 0
+	for name := range vars {
+		names = append(names, name)
+		width = max(width, len(name))
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("📋 Variables:\n")
+	for _, name := range names {
+		value := vars[name]
+		if secrets[name] {
+			value = secretMask
+		}
+		fmt.Fprintf(&b, "  %-*s = %s\n", width, name, value)
+	}
+	return b.String()
+}
+
+// reaskVariables prepares a second prompt round: every declared variable is
+// asked again with its current value as the default, and only undeclared
+// values such as project_name are kept
+func reaskVariables(manifest *config.Manifest, vars map[string]string) (*config.Manifest, map[string]string) {
+	declared := make(map[string]bool)
+	reask := &config.Manifest{}
+	for _, v := range manifest.Variables {
+		declared[v.Name] = true
+		if value, ok := vars[v.Name]; ok {
+			v.Default = value
+		}
+		reask.Variables = append(reask.Variables, v)
+	}
+
+	kept := make(map[string]string)
+	for name, value := range vars {
+		if !declared[name] {
+			kept[name] = value
+		}
+	}
+	return reask, kept
+}
+
+// editVariable prepares to change one answer: the named variable is asked
+// again with its current value as the default, and every other value is kept
+func editVariable(manifest *config.Manifest, vars map[string]string, name string) (*config.Manifest, map[string]string) {
+	edit := &config.Manifest{}
+	for _, v := range manifest.Variables {
+		if v.Name == name {
+			if value, ok := vars[name]; ok {
+				v.Default = value
+			}
+			edit.Variables = append(edit.Variables, v)
+		}
+	}
+
+	kept := make(map[string]string, len(vars))
+	for k, v := range vars {
+		if k != name {
+			kept[k] = v
+		}
+	}
+	return edit, kept
+}
+
+// variableOptions labels the declared variables with their current values,
+// masking secrets, for picking one to change
+func variableOptions(manifest *config.Manifest, vars map[string]string, secrets map[string]bool) []string {
+	options := make([]string, 0, len(manifest.Variables))
+	for _, v := range manifest.Variables {
+		value, ok := vars[v.Name]
+		switch {
+		case !ok:
+			value = "(unset)"
+		case secrets[v.Name]:
+			value = secretMask
+		}
+		options = append(options, fmt.Sprintf("%s = %s", v.Name, value))
+	}
+	return options
+}
+
+// replaySession returns the vars file to read and whether to prompt for
+// missing values. --replay reads its file as the --vars-file, without
+// prompting.
+func replaySession() (string, bool) {
+	if replayFile != "" {
+		return replayFile, false
+	}
+	return varsFile, !noPrompt
+}
+
+// recordSession writes the variable values to a vars file for --replay.
+// Secrets are left out, as from the lockfile, and have to be given again.
+// project_name and project_slug are left out too, so that a replay names
+// the project after its own arguments.
+func recordSession(path string, vars map[string]string, secrets map[string]bool) error {
+	recorded := withoutSecrets(vars, secrets)
+	delete(recorded, "project_name")
+	delete(recorded, "project_slug")
+	if err := config.WriteVarsFile(path, recorded); err != nil {
+		return err
+	}
+	log.Progress("💾 Recorded the variables in %s", path)
+	var skipped []string
+	for name := range secrets {
+		if _, ok := vars[name]; ok {
+			skipped = append(skipped, name)
+		}
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		log.Warn("secret %s is not recorded; pass it with --var when replaying", name)
+	}
+	return nil
+}
+
+// hiddenSecrets returns the secrets to mask in output, none with --show-secrets
+func hiddenSecrets(secrets map[string]bool) map[string]bool {
+	if showSecrets {
+		return nil
+	}
+	return secrets
+}
+
+// withoutSecrets returns a copy of vars without the secret variables
+func withoutSecrets(vars map[string]string, secrets map[string]bool) map[string]string {
+	public := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if !secrets[name] {
+			public[name] = value
+		}
+	}
+	return public
+}
+
+// setMissing copies values from layer into vars where vars has no value yet
+func setMissing(vars, layer map[string]string) {
+	for k, v := range layer {
+		if _, ok := vars[k]; !ok {
+			vars[k] = v
+		}
+	}
+}
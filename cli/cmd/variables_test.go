@@ -0,0 +1,759 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+)
+
+func TestCollectVariables_Precedence(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "author", Default: "Anonymous"},
+			{Name: "license", Default: "MIT"},
+		},
+	}
+	configVars := map[string]string{
+		"org":    "configorg",
+		"author": "Config Author",
+	}
+
+	variables = []string{"org=flagorg"}
+	defer func() { variables = nil }()
+
+	vars, err := collectVariables(manifest, "my-app", configVars)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+
+	want := map[string]string{
+		"project_name": "my-app",
+		"project_slug": "my_app",
+		"org":          "flagorg",
+		"author":       "Config Author",
+		"license":      "MIT",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+		}
+	}
+}
+
+func TestCollectVariables_ProjectSlug(t *testing.T) {
+	for name, want := range map[string]string{
+		"my-app":   "my_app",
+		"My App 2": "my_app_2",
+		"3d-tools": "project_3d_tools",
+		"Café":     "cafe",
+	} {
+		vars, err := collectVariables(&config.Manifest{}, name, nil)
+		if err != nil {
+			t.Fatalf("collectVariables() error = %v", err)
+		}
+		if vars["project_slug"] != want {
+			t.Errorf("project_slug for %q = %q, want %q", name, vars["project_slug"], want)
+		}
+	}
+}
+
+func TestCollectVariables_ProjectNameFromArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(path, []byte("project_name: old-app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write vars file: %v", err)
+	}
+	varsFile = path
+	defer func() { varsFile = "" }()
+
+	vars, err := collectVariables(&config.Manifest{}, "my-app", map[string]string{"project_name": "config-app"})
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+	if vars["project_name"] != "my-app" || vars["project_slug"] != "my_app" {
+		t.Errorf("project_name, project_slug = %q, %q, want the name given", vars["project_name"], vars["project_slug"])
+	}
+}
+
+func TestCollectVariables_ProjectOverrides(t *testing.T) {
+	t.Cleanup(func() { variables = nil })
+
+	tests := []struct {
+		name     string
+		vars     []string
+		env      string
+		wantName string
+		wantSlug string
+	}{
+		{name: "derived", wantName: "my-app", wantSlug: "my_app"},
+		{name: "slug flag", vars: []string{"project_slug=foo"}, wantName: "my-app", wantSlug: "foo"},
+		{name: "slug from environment", env: "bar", wantName: "my-app", wantSlug: "bar"},
+		{name: "name flag", vars: []string{"project_name=Acme API"}, wantName: "Acme API", wantSlug: "acme_api"},
+		{name: "both flags", vars: []string{"project_name=Acme API", "project_slug=acme"}, wantName: "Acme API", wantSlug: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variables = tt.vars
+			t.Setenv("SCAFFOLD_VAR_PROJECT_SLUG", tt.env)
+			if tt.env == "" {
+				os.Unsetenv("SCAFFOLD_VAR_PROJECT_SLUG")
+			}
+
+			vars, err := collectVariables(&config.Manifest{}, "my-app", nil)
+			if err != nil {
+				t.Fatalf("collectVariables() error = %v", err)
+			}
+			if vars["project_name"] != tt.wantName || vars["project_slug"] != tt.wantSlug {
+				t.Errorf("project_name, project_slug = %q, %q, want %q, %q",
+					vars["project_name"], vars["project_slug"], tt.wantName, tt.wantSlug)
+			}
+		})
+	}
+}
+
+func TestCollectVariables_Choices(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "database", Type: "choice", Choices: []string{"postgres", "sqlite"}, Default: "sqlite"},
+			{Name: "region", Type: "choice", Choices: []string{"eu", "us"}},
+		},
+	}
+	defer func() { variables = nil }()
+
+	variables = []string{"database=mongo"}
+	_, err := collectVariables(manifest, "my-app", nil)
+	if err == nil || !strings.Contains(err.Error(), `variable database: "mongo" is not one of postgres, sqlite`) {
+		t.Errorf("collectVariables() error = %v, want choice error", err)
+	}
+
+	// A config default the template doesn't offer falls back to the manifest
+	variables = []string{"region=us"}
+	vars, err := collectVariables(manifest, "my-app", map[string]string{"database": "mysql"})
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+	if vars["database"] != "sqlite" || vars["region"] != "us" {
+		t.Errorf("database, region = %s, %s, want sqlite, us", vars["database"], vars["region"])
+	}
+}
+
+func TestCollectVariables_Environment(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "dbName", Default: "app"},
+			{Name: "region", Default: "us-east1"},
+		},
+	}
+
+	t.Setenv("SCAFFOLD_VAR_ORG", "envorg")
+	t.Setenv("SCAFFOLD_VAR_DBNAME", "envdb")
+	t.Setenv("SCAFFOLD_VAR_REGION", "europe-west2")
+	t.Setenv("SCAFFOLD_VAR_EXTRA", "extra")
+
+	variables = []string{"region=us-central1"}
+	defer func() { variables = nil }()
+
+	vars, err := collectVariables(manifest, "my-app", map[string]string{"org": "configorg"})
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+
+	want := map[string]string{
+		"org":    "envorg",      // env overrides config and manifest default
+		"dbName": "envdb",       // matched case-insensitively
+		"region": "us-central1", // --var overrides env
+		"extra":  "extra",       // unknown names are lowercased
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+		}
+	}
+}
+
+func TestCollectVariables_VarsFile(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "region", Default: "us-east1"},
+			{Name: "license", Default: "MIT"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(path, []byte("org: fileorg\nregion: europe-west2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write vars file: %v", err)
+	}
+
+	t.Setenv("SCAFFOLD_VAR_ORG", "envorg")
+
+	varsFile = path
+	variables = []string{"region=us-central1"}
+	defer func() { varsFile, variables = "", nil }()
+
+	vars, err := collectVariables(manifest, "my-app", nil)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+
+	want := map[string]string{
+		"org":     "fileorg",     // vars file overrides env
+		"region":  "us-central1", // --var overrides vars file
+		"license": "MIT",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+		}
+	}
+}
+
+func TestCollectVariables_Profile(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "region", Default: "us-east1"},
+			{Name: "replicas", Default: "1"},
+			{Name: "debug", Default: "true"},
+		},
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vars.yaml")
+	content := `org: fileorg
+profiles:
+  prod:
+    replicas: 3
+    debug: false
+  staging:
+    region: europe-west2
+    replicas: 2
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write vars file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scaffold.staging.yaml"), []byte("replicas: 4\n"), 0644); err != nil {
+		t.Fatalf("Failed to write profile file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		flags   []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "no profile",
+			want: map[string]string{"org": "fileorg", "region": "us-east1", "replicas": "1", "debug": "true"},
+		},
+		{
+			name:    "profiles section",
+			profile: "prod",
+			want:    map[string]string{"org": "fileorg", "region": "us-east1", "replicas": "3", "debug": "false"},
+		},
+		{
+			name:    "profile file over the profiles section",
+			profile: "staging",
+			want:    map[string]string{"org": "fileorg", "region": "europe-west2", "replicas": "4", "debug": "true"},
+		},
+		{
+			name:    "--var over the profile",
+			profile: "prod",
+			flags:   []string{"replicas=5"},
+			want:    map[string]string{"replicas": "5", "debug": "false"},
+		},
+		{
+			name:    "unknown profile",
+			profile: "qa",
+			wantErr: "profile qa not found",
+		},
+		{
+			name:    "invalid profile name",
+			profile: "../prod",
+			wantErr: "invalid profile name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			varsFile, profile, variables = path, tt.profile, tt.flags
+			defer func() { varsFile, profile, variables = "", "", nil }()
+
+			vars, err := collectVariables(manifest, "my-app", nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("collectVariables() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("collectVariables() error = %v", err)
+			}
+			for k, v := range tt.want {
+				if vars[k] != v {
+					t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectVariables_Set(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{{Name: "org", Default: "defaultorg"}},
+	}
+
+	tests := []struct {
+		name    string
+		sets    []string
+		flags   []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "nested values merged",
+			sets: []string{"database.host=localhost", "database.port=5432", "cache.url=redis://cache:6379"},
+			want: map[string]string{"database.host": "localhost", "database.port": "5432", "cache.url": "redis://cache:6379", "org": "defaultorg"},
+		},
+		{
+			name: "later --set wins for the same name",
+			sets: []string{"database.host=a", "database.host=b"},
+			want: map[string]string{"database.host": "b", "org": "defaultorg"},
+		},
+		{
+			name:  "--var wins for the same name",
+			sets:  []string{"database.host=localhost"},
+			flags: []string{"database.host=db"},
+			want:  map[string]string{"database.host": "db"},
+		},
+		{
+			name:    "flat --var conflicts with --set",
+			sets:    []string{"database.host=localhost"},
+			flags:   []string{"database=postgres"},
+			wantErr: "database.host conflicts with database",
+		},
+		{
+			name:    "manifest variable conflicts with --set",
+			sets:    []string{"org.name=acme"},
+			wantErr: "org.name conflicts with org",
+		},
+		{
+			name:    "invalid name",
+			sets:    []string{"database..host=localhost"},
+			wantErr: "invalid --set",
+		},
+		{
+			name:    "missing value",
+			sets:    []string{"database.host"},
+			wantErr: "invalid --set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setValues, variables = tt.sets, tt.flags
+			defer func() { setValues, variables = nil, nil }()
+
+			vars, err := collectVariables(manifest, "my-app", nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("collectVariables() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("collectVariables() error = %v", err)
+			}
+			for k, v := range tt.want {
+				if vars[k] != v {
+					t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordSession_Replays(t *testing.T) {
+	useLogger(t, logger.Quiet)
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org"},
+			{Name: "region", Default: "us-east1"},
+			{Name: "database", Type: "choice", Choices: []string{"postgres", "mysql"}, Default: "postgres"},
+			{Name: "port", Default: "8080"},
+			{Name: "api_token", Secret: true},
+		},
+	}
+	oldVarsFile, oldNoPrompt := varsFile, noPrompt
+	t.Cleanup(func() { varsFile, noPrompt, replayFile = oldVarsFile, oldNoPrompt, "" })
+
+	// An interactive session: defaults first, then the answers to the prompts
+	vars, err := collectVariables(manifest, "my-app", nil)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+	for name, answer := range map[string]string{"org": "acme", "database": "mysql", "port": "9090", "api_token": "s3cret"} {
+		vars[name] = answer
+	}
+	path := filepath.Join(t.TempDir(), "session.yaml")
+	secrets := map[string]bool{"api_token": true}
+	if err := recordSession(path, vars, secrets); err != nil {
+		t.Fatalf("recordSession() error = %v", err)
+	}
+
+	replayFile = path
+	if file, prompting := replaySession(); file != path || prompting {
+		t.Errorf("replaySession() = %q, %v, want the replay file without prompting", file, prompting)
+	}
+	if varsFile != oldVarsFile || noPrompt != oldNoPrompt {
+		t.Error("replaySession() should leave the flags as they are")
+	}
+	replayed, err := collectVariables(manifest, "my-app", nil)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+	if want := withoutSecrets(vars, secrets); !reflect.DeepEqual(replayed, want) {
+		t.Errorf("replayed = %v, want %v", replayed, want)
+	}
+
+	// The project is named after the replaying run's arguments
+	replayed, err = collectVariables(manifest, "other-app", nil)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+	if replayed["project_name"] != "other-app" || replayed["project_slug"] != "other_app" {
+		t.Errorf("project_name, project_slug = %q, %q, want the replaying name", replayed["project_name"], replayed["project_slug"])
+	}
+}
+
+func TestCollectVariables_MalformedVarsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.json")
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write vars file: %v", err)
+	}
+
+	varsFile = path
+	defer func() { varsFile = "" }()
+
+	if _, err := collectVariables(&config.Manifest{}, "my-app", nil); err == nil {
+		t.Error("collectVariables() should return error for malformed vars file")
+	}
+}
+
+func TestCollectVariables_FromFiles(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id.pub")
+	if err := os.WriteFile(key, []byte("ssh-ed25519 AAAA user@host\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	snippet := filepath.Join(dir, "nginx.conf")
+	if err := os.WriteFile(snippet, []byte("server {\n  listen 80;\n}\n\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	t.Cleanup(func() { variables, setValues, varFiles = nil, nil, nil })
+
+	variables = []string{"ssh_key=@" + key, "handle=@@acme", "org=acme"}
+	varFiles = []string{"nginx=" + snippet}
+	vars, err := collectVariables(&config.Manifest{}, "my-app", nil)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+
+	want := map[string]string{
+		"ssh_key": "ssh-ed25519 AAAA user@host", // Trailing line break dropped
+		"nginx":   "server {\n  listen 80;\n}\n",
+		"handle":  "@acme",
+		"org":     "acme",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %q, want %q", k, vars[k], v)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing.pem")
+	for _, tt := range []struct {
+		vars, sets, files []string
+		wantErr           string
+	}{
+		{vars: []string{"cert=@" + missing}, wantErr: "variable cert: failed to read value"},
+		{files: []string{"cert=" + missing}, wantErr: "variable cert: failed to read value"},
+		{files: []string{"cert"}, wantErr: "want name=path"},
+		{vars: []string{"org=acme"}, files: []string{"org=" + key}, wantErr: "org is given with both --var and --var-file"},
+		{sets: []string{"db.host=localhost"}, files: []string{"db.host=" + key}, wantErr: "db.host is given with both --set and --var-file"},
+	} {
+		variables, setValues, varFiles = tt.vars, tt.sets, tt.files
+		if _, err := collectVariables(&config.Manifest{}, "my-app", nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("collectVariables(%v, %v) error = %v, want %q", tt.vars, tt.files, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCollectVariables_SharedDefaults(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "author", Default: "Anonymous"},
+			{Name: "team", Default: "none"},
+			{Name: "license", Default: "MIT"},
+			{Name: "database", Type: "choice", Choices: []string{"postgres", "sqlite"}, Default: "sqlite"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	if err := os.WriteFile(path, []byte("org: acme\nauthor: Acme\nteam: core\nlicense: Apache-2.0\ndatabase: mongo\n"), 0644); err != nil {
+		t.Fatalf("Failed to write defaults file: %v", err)
+	}
+	t.Setenv(defaultsFileEnv, path)
+	t.Setenv("SCAFFOLD_VAR_TEAM", "platform")
+
+	variables = []string{"org=flagorg"}
+	defer func() { variables = nil }()
+
+	vars, err := collectVariables(manifest, "my-app", map[string]string{"author": "Config Author"})
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+
+	want := map[string]string{
+		"org":      "flagorg",       // --var wins
+		"team":     "platform",      // then the environment
+		"author":   "Config Author", // then the config file
+		"license":  "Apache-2.0",    // then the shared defaults
+		"database": "sqlite",        // whose invalid choices fall back to the manifest
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+		}
+	}
+}
+
+func TestCollectVariables_GitConfig(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "author", Source: config.GitConfigSource, Default: "Anonymous"},
+			{Name: "author_email", Source: config.GitConfigSource, Default: "you@example.com"},
+			{Name: "github_user", Source: config.GitConfigSource},
+			{Name: "signing_key", Source: "git_config:user.signingkey", Default: "none"},
+		},
+	}
+	oldGitConfig := gitConfig
+	t.Cleanup(func() { gitConfig = oldGitConfig })
+	gitConfig = func(key string) string {
+		return map[string]string{
+			"user.name":   "Ada Lovelace",
+			"user.email":  "ada@example.com",
+			"github.user": "ada",
+		}[key]
+	}
+
+	tests := []struct {
+		name       string
+		flags      []string
+		configVars map[string]string
+		want       map[string]string
+	}{
+		{
+			name: "defaults from git config",
+			want: map[string]string{
+				"author":       "Ada Lovelace",
+				"author_email": "ada@example.com",
+				"github_user":  "ada",
+				"signing_key":  "none", // Unset in git config, the manifest default applies
+			},
+		},
+		{
+			name:       "explicit values win",
+			flags:      []string{"author=Grace Hopper"},
+			configVars: map[string]string{"github_user": "grace"},
+			want: map[string]string{
+				"author":       "Grace Hopper",
+				"author_email": "ada@example.com",
+				"github_user":  "grace",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variables = tt.flags
+			defer func() { variables = nil }()
+
+			vars, err := collectVariables(manifest, "my-app", tt.configVars)
+			if err != nil {
+				t.Fatalf("collectVariables() error = %v", err)
+			}
+			for k, v := range tt.want {
+				if vars[k] != v {
+					t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestSharedDefaults_Location(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(defaultsFileEnv, "")
+	oldConfig := userConfig
+	t.Cleanup(func() { userConfig = oldConfig })
+	userConfig = &config.UserConfig{}
+
+	// No defaults file at the default location is fine
+	if got, err := sharedDefaults(); err != nil || got != nil {
+		t.Fatalf("sharedDefaults() = %v, %v, want none", got, err)
+	}
+
+	dir := filepath.Join(home, config.UserConfigDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.DefaultsFile), []byte("org: home\n"), 0644); err != nil {
+		t.Fatalf("Failed to write defaults file: %v", err)
+	}
+	if got, err := sharedDefaults(); err != nil || got["org"] != "home" {
+		t.Errorf("sharedDefaults() = %v, %v, want org from ~/.scaffold/defaults.yaml", got, err)
+	}
+
+	// A configured file must exist
+	userConfig.DefaultsFile = filepath.Join(home, "missing.yaml")
+	if _, err := sharedDefaults(); err == nil {
+		t.Error("sharedDefaults() should fail for a missing defaults_file")
+	}
+}
+
+func TestCollectVariables_InterpolatedDefaults(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "db_name", Default: "{{ project_slug }}_db"},
+			{Name: "db_user", Default: "{{ db_name }}_owner"},
+			{Name: "repo", Default: "github.com/{{ org }}/{{ project_slug }}"},
+		},
+	}
+
+	vars, err := collectVariables(manifest, "my-app", nil)
+	if err != nil {
+		t.Fatalf("collectVariables() error = %v", err)
+	}
+	if vars["db_name"] != "my_app_db" || vars["db_user"] != "my_app_db_owner" {
+		t.Errorf("db_name, db_user = %q, %q, want my_app_db, my_app_db_owner", vars["db_name"], vars["db_user"])
+	}
+	// org is only known once prompted, so repo is left to the prompt
+	if _, ok := vars["repo"]; ok {
+		t.Errorf("repo = %q, want it left unset until org is known", vars["repo"])
+	}
+
+	oldNoPrompt := noPrompt
+	t.Cleanup(func() { noPrompt = oldNoPrompt })
+	noPrompt = true
+	if _, err := collectVariables(manifest, "my-app", nil); err == nil {
+		t.Error("collectVariables() should return error for an unresolved default without prompts")
+	}
+}
+
+func TestFormatRecap(t *testing.T) {
+	got := formatRecap(map[string]string{
+		"project_name": "my-app",
+		"org":          "acme",
+		"database":     "postgres",
+		"api_key":      "s3cret",
+	}, map[string]bool{"api_key": true})
+
+	want := "📋 Variables:\n" +
+		"  api_key      = ********\n" +
+		"  database     = postgres\n" +
+		"  org          = acme\n" +
+		"  project_name = my-app\n"
+	if got != want {
+		t.Errorf("formatRecap() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSetProjectModule(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{"org and slug", map[string]string{"org": "acme", "project_slug": "my_app"}, "github.com/acme/my_app"},
+		{"sanitized org", map[string]string{"org": "Acme Corp!", "project_slug": "api"}, "github.com/acme-corp/api"},
+		{"module host", map[string]string{"module_host": "gitlab.example.com", "org": "acme", "project_slug": "api"}, "gitlab.example.com/acme/api"},
+		{"no org", map[string]string{"project_slug": "my_app"}, "my_app"},
+		{"explicit value", map[string]string{"org": "acme", "project_slug": "api", "project_module": "example.com/api"}, "example.com/api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setProjectModule(tt.vars)
+			if got := tt.vars["project_module"]; got != tt.want {
+				t.Errorf("project_module = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaskVariables(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "license"},
+		},
+	}
+	vars := map[string]string{"project_name": "my-app", "org": "acm", "license": "MIT"}
+
+	reask, kept := reaskVariables(manifest, vars)
+
+	if len(kept) != 1 || kept["project_name"] != "my-app" {
+		t.Errorf("kept = %v, want only project_name", kept)
+	}
+	if reask.Variables[0].Default != "acm" || reask.Variables[1].Default != "MIT" {
+		t.Errorf("defaults = %q, %q, want the current values", reask.Variables[0].Default, reask.Variables[1].Default)
+	}
+	if manifest.Variables[0].Default != "defaultorg" {
+		t.Error("reaskVariables() should not modify the manifest")
+	}
+}
+
+func TestEditVariable(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org", Default: "defaultorg"},
+			{Name: "license", Type: "choice", Choices: []string{"MIT", "Apache-2.0"}},
+		},
+	}
+	vars := map[string]string{"project_name": "my-app", "org": "acm", "license": "MIT"}
+
+	edit, kept := editVariable(manifest, vars, "license")
+
+	if len(edit.Variables) != 1 || edit.Variables[0].Name != "license" || edit.Variables[0].Default != "MIT" {
+		t.Errorf("edit = %+v, want only license defaulting to MIT", edit.Variables)
+	}
+	if want := map[string]string{"project_name": "my-app", "org": "acm"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	if manifest.Variables[1].Default != "" || vars["license"] != "MIT" {
+		t.Error("editVariable() should not modify the manifest or the values")
+	}
+}
+
+func TestVariableOptions(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{{Name: "org"}, {Name: "token", Secret: true}, {Name: "region"}},
+	}
+	vars := map[string]string{"org": "acme", "token": "s3cret"}
+
+	got := variableOptions(manifest, vars, map[string]bool{"token": true})
+	want := []string{"org = acme", "token = " + secretMask, "region = (unset)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("variableOptions() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+// Package action runs post-generation actions in a generated project
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/condition"
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/runner"
+	"github.com/makemore/scaffold/internal/template"
+)
+
+// Action types
+const (
+	TypeMessage = "message"
+	TypeCommand = "command"
+	TypeGitInit = "git_init"
+	TypeOpen    = "open"
+)
+
+// DefaultCommitMessage is used by git_init when no commit message is set
+const DefaultCommitMessage = "Initial commit from scaffold"
+
+// Executor runs actions in a project directory
+type Executor struct {
+	Dir  string            // Project directory
+	Vars map[string]string // Variables for conditions and templates
+	Log  *logger.Logger
+	// Confirm asks whether to run an optional action. Optional actions run
+	// without asking when nil.
+	Confirm func(message string) (bool, error)
+
+	// AllowCommands runs every command action without asking
+	AllowCommands bool
+	// AllowedCommands lists programs that run without asking
+	AllowedCommands []string
+	// ConsentCommands shows the commands that are not allowed and asks once
+	// whether to run them. They are skipped when nil.
+	ConsentCommands func(commands []string) (bool, error)
+
+	// Interactive is set when a user is at the terminal. Open actions are
+	// skipped without one.
+	Interactive bool
+	// Editor is the configured editor command, preferred over $VISUAL and
+	// $EDITOR
+	Editor string
+
+	// Runner runs command actions and git, with os/exec when nil. Editors
+	// run with os/exec regardless, as they need the terminal.
+	Runner runner.Runner
+}
+
+// Run runs each action whose condition holds, in order. Message actions are
+// left to the caller to display. Command actions that are not allowed need
+// consent first, which for an optional one is the answer to Confirm. A
+// failing optional action is reported as a warning rather than stopping the
+// run.
+func (e *Executor) Run(actions []config.Action) error {
+	var active []config.Action
+	for _, a := range actions {
+		if a.Condition != "" {
+			ok, err := condition.Evaluate(a.Condition, e.Vars)
+			if err != nil {
+				return fmt.Errorf("action %s: %w", a.Name, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		active = append(active, a)
+	}
+
+	skip, err := e.consentToCommands(active)
+	if err != nil {
+		return err
+	}
+
+	for i, a := range active {
+		var run func(config.Action) error
+		switch a.Type {
+		case TypeGitInit:
+			run = e.gitInit
+		case TypeOpen:
+			run = e.openEditor
+		case TypeCommand:
+			if skip[i] {
+				continue
+			}
+			run = e.runCommand
+		default:
+			continue
+		}
+
+		if a.Optional && e.Confirm != nil {
+			ok, err := e.Confirm(e.confirmMessage(a))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if err := run(a); err != nil {
+			if a.Optional {
+				e.Log.Warn("action %s failed: %v", a.Name, err)
+				continue
+			}
+			return fmt.Errorf("action %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+func describe(a config.Action) string {
+	if a.Description != "" {
+		return a.Description
+	}
+	return "Run " + a.Name + "?"
+}
+
+// gitInit creates a repository in the project directory with an initial
+// commit of everything generated. It does nothing when git is missing or the
+// directory is already inside a repository.
+func (e *Executor) gitInit(a config.Action) error {
+	if _, err := exec.LookPath("git"); e.Runner == nil && err != nil {
+		e.Log.Warn("git is not installed, skipping %s", a.Name)
+		return nil
+	}
+	if err := e.git("rev-parse", "--is-inside-work-tree"); err == nil {
+		e.Log.Progress("📁 %s is already inside a git repository, skipping %s", e.Dir, a.Name)
+		return nil
+	}
+
+	message := a.CommitMessage
+	if message == "" {
+		message = DefaultCommitMessage
+	}
+	message = template.Substitute(message, e.Vars)
+
+	e.Log.Progress("🌱 Initializing git repository")
+	if err := e.git("init", "--quiet"); err != nil {
+		return err
+	}
+	if err := e.git("add", "--all"); err != nil {
+		return err
+	}
+
+	// Commit without a configured identity rather than failing
+	var env []string
+	if out, _ := e.gitOutput("config", "user.email"); strings.TrimSpace(string(out)) == "" {
+		env = []string{
+			"GIT_AUTHOR_NAME=scaffold", "GIT_AUTHOR_EMAIL=scaffold@localhost",
+			"GIT_COMMITTER_NAME=scaffold", "GIT_COMMITTER_EMAIL=scaffold@localhost",
+		}
+	}
+	return e.gitEnv(env, "commit", "--quiet", "--message", message)
+}
+
+// git runs a git command in the project directory
+func (e *Executor) git(args ...string) error {
+	return e.gitEnv(nil, args...)
+}
+
+// gitOutput runs a git command in the project directory and returns its
+// output
+func (e *Executor) gitOutput(args ...string) ([]byte, error) {
+	return runner.Or(e.Runner).Run(context.Background(), "git", append([]string{"-C", e.Dir}, args...), runner.Options{})
+}
+
+// gitEnv runs a git command in the project directory with extra environment
+func (e *Executor) gitEnv(env []string, args ...string) error {
+	out, err := runner.Or(e.Runner).Run(context.Background(), "git", append([]string{"-C", e.Dir}, args...), runner.Options{
+		Env: append(os.Environ(), env...),
+	})
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			return fmt.Errorf("git %s failed: %w", args[0], err)
+		}
+		return fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	return nil
+}
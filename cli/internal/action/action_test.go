@@ -0,0 +1,165 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/runner"
+)
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// fakeRunner records the programs it is asked to run. Runs whose command
+// line starts with a key of fail return an error.
+type fakeRunner struct {
+	calls []fakeCall
+	fail  map[string]bool
+}
+
+type fakeCall struct {
+	line string
+	opts runner.Options
+}
+
+func (r *fakeRunner) Run(ctx context.Context, name string, args []string, opts runner.Options) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	r.calls = append(r.calls, fakeCall{line: line, opts: opts})
+	for prefix := range r.fail {
+		if strings.HasPrefix(line, prefix) {
+			return nil, errors.New("exit status 1")
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRunner) lines() []string {
+	var lines []string
+	for _, c := range r.calls {
+		lines = append(lines, c.line)
+	}
+	return lines
+}
+
+func TestExecutor_GitInitRunner(t *testing.T) {
+	dir := t.TempDir()
+	fake := &fakeRunner{fail: map[string]bool{
+		"git -C " + dir + " rev-parse":         true, // Not inside a repository
+		"git -C " + dir + " config user.email": true, // No identity configured
+	}}
+	e := &Executor{Dir: dir, Vars: map[string]string{"project_name": "app"}, Runner: fake}
+	if err := e.Run([]config.Action{{Name: "git", Type: TypeGitInit, CommitMessage: "Start {{ project_name }}"}}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []string{
+		"git -C " + dir + " rev-parse --is-inside-work-tree",
+		"git -C " + dir + " init --quiet",
+		"git -C " + dir + " add --all",
+		"git -C " + dir + " config user.email",
+		"git -C " + dir + " commit --quiet --message Start app",
+	}
+	if got := fake.lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("git calls =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	commitEnv := strings.Join(fake.calls[4].opts.Env, "\n")
+	if !strings.Contains(commitEnv, "GIT_AUTHOR_EMAIL=scaffold@localhost") {
+		t.Error("commit should run with the fallback identity")
+	}
+}
+
+func TestExecutor_GitInit(t *testing.T) {
+	requireGit(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# app"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	e := &Executor{Dir: dir, Vars: map[string]string{"project_name": "app"}}
+	err := e.Run([]config.Action{{Name: "git", Type: TypeGitInit, CommitMessage: "Start {{ project_name }}"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, ".git")); err != nil || !info.IsDir() {
+		t.Fatalf(".git should be a directory: %v", err)
+	}
+	if got := gitOutput(t, dir, "rev-list", "--count", "HEAD"); got != "1" {
+		t.Errorf("commit count = %s, want 1", got)
+	}
+	if got := gitOutput(t, dir, "log", "-1", "--format=%s"); got != "Start app" {
+		t.Errorf("commit message = %q, want %q", got, "Start app")
+	}
+	if got := gitOutput(t, dir, "ls-files"); got != "README.md" {
+		t.Errorf("committed files = %q, want README.md", got)
+	}
+}
+
+func TestExecutor_GitInitInsideRepo(t *testing.T) {
+	requireGit(t)
+
+	repo := t.TempDir()
+	gitOutput(t, repo, "init", "--quiet")
+	project := filepath.Join(repo, "app")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	e := &Executor{Dir: project}
+	if err := e.Run([]config.Action{{Name: "git", Type: TypeGitInit}}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(project, ".git")); !os.IsNotExist(err) {
+		t.Error("git_init should skip a directory already inside a repository")
+	}
+}
+
+func TestExecutor_ConditionAndOptional(t *testing.T) {
+	requireGit(t)
+
+	dir := t.TempDir()
+	asked := 0
+	e := &Executor{
+		Dir:  dir,
+		Vars: map[string]string{"use_git": "false"},
+		Confirm: func(string) (bool, error) {
+			asked++
+			return false, nil
+		},
+	}
+	actions := []config.Action{
+		{Name: "conditional", Type: TypeGitInit, Condition: "use_git"},
+		{Name: "optional", Type: TypeGitInit, Optional: true},
+	}
+	if err := e.Run(actions); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if asked != 1 {
+		t.Errorf("asked %d times, want 1 (only the optional action)", asked)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+		t.Error("declined and false-condition actions should not run")
+	}
+}
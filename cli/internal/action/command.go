@@ -0,0 +1,165 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/runner"
+	"github.com/makemore/scaffold/internal/template"
+)
+
+// commandLine returns the program and arguments of a command action with
+// variables substituted. A command given without args is split on spaces.
+func (e *Executor) commandLine(a config.Action) (string, []string) {
+	command := template.Substitute(a.Command, e.Vars)
+	args := make([]string, len(a.Args))
+	for i, arg := range a.Args {
+		args[i] = template.Substitute(arg, e.Vars)
+	}
+	if len(args) == 0 {
+		if fields := strings.Fields(command); len(fields) > 0 {
+			return fields[0], fields[1:]
+		}
+	}
+	return command, args
+}
+
+// commandDir returns the directory a command action runs in: its work_dir,
+// with variables substituted, inside the project directory
+func (e *Executor) commandDir(a config.Action) (string, error) {
+	if a.WorkDir == "" {
+		return e.Dir, nil
+	}
+
+	rel := filepath.Clean(filepath.FromSlash(template.Substitute(a.WorkDir, e.Vars)))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("work_dir %s is outside the project", a.WorkDir)
+	}
+	return filepath.Join(e.Dir, rel), nil
+}
+
+// commandEnv returns the environment for a command action: scaffold's own
+// environment plus the action's env, with variables substituted
+func (e *Executor) commandEnv(a config.Action) []string {
+	if len(a.Env) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(a.Env))
+	for name := range a.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := os.Environ()
+	for _, name := range names {
+		env = append(env, name+"="+template.Substitute(a.Env[name], e.Vars))
+	}
+	return env
+}
+
+// formatCommand renders a command line for display
+func formatCommand(program string, args []string) string {
+	return strings.TrimSpace(program + " " + strings.Join(args, " "))
+}
+
+// commandAllowed reports whether a program may run without consent: every
+// program with AllowCommands, otherwise only those in AllowedCommands.
+// Entries match the program exactly as written, so an allowed "npm" doesn't
+// allow a template's own "bin/npm".
+func (e *Executor) commandAllowed(program string) bool {
+	if e.AllowCommands {
+		return true
+	}
+	for _, allowed := range e.AllowedCommands {
+		if program == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// consentToCommands decides which command actions may run. Commands that are
+// not allowed are listed and need a single consent for the whole run; without
+// a way to ask they are skipped. Optional commands that Confirm asks about
+// are left out, as answering that prompt is their consent. It returns the
+// indexes of the actions to skip.
+func (e *Executor) consentToCommands(actions []config.Action) (map[int]bool, error) {
+	var pending []int
+	var commands []string
+	for i, a := range actions {
+		if a.Type != TypeCommand || a.Optional && e.Confirm != nil {
+			continue
+		}
+		program, args := e.commandLine(a)
+		if !e.commandAllowed(program) {
+			pending = append(pending, i)
+			commands = append(commands, formatCommand(program, args))
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	consented := false
+	if e.ConsentCommands != nil {
+		ok, err := e.ConsentCommands(commands)
+		if err != nil {
+			return nil, err
+		}
+		consented = ok
+	}
+	if consented {
+		return nil, nil
+	}
+
+	skip := make(map[int]bool)
+	for i, index := range pending {
+		skip[index] = true
+		e.Log.Warn("skipping command %q (allow it with --allow-commands)", commands[i])
+	}
+	return skip, nil
+}
+
+// confirmMessage is what Confirm asks for an optional action. A command that
+// is not allowed is shown as it will run, since the answer is its consent.
+func (e *Executor) confirmMessage(a config.Action) string {
+	if a.Type != TypeCommand {
+		return describe(a)
+	}
+	program, args := e.commandLine(a)
+	if e.commandAllowed(program) {
+		return describe(a)
+	}
+	return fmt.Sprintf("%s (runs %s)", describe(a), formatCommand(program, args))
+}
+
+// runCommand runs a command action in its directory, streaming its output as
+// progress
+func (e *Executor) runCommand(a config.Action) error {
+	program, args := e.commandLine(a)
+	if program == "" {
+		return fmt.Errorf("no command given")
+	}
+
+	dir, err := e.commandDir(a)
+	if err != nil {
+		return err
+	}
+
+	e.Log.Progress("⚙️  Running %s", formatCommand(program, args))
+	_, err = runner.Or(e.Runner).Run(context.Background(), program, args, runner.Options{
+		Dir:    dir,
+		Env:    e.commandEnv(a),
+		Output: e.Log.ProgressWriter(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", program, err)
+	}
+	return nil
+}
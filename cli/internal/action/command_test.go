@@ -0,0 +1,207 @@
+package action
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestExecutor_CommandConsent(t *testing.T) {
+	if _, err := exec.LookPath("touch"); err != nil {
+		t.Skip("touch not available")
+	}
+
+	actions := []config.Action{
+		{Name: "first", Type: TypeCommand, Command: "touch", Args: []string{"{{ name }}-first"}},
+		{Name: "second", Type: TypeCommand, Command: "touch app-second"},
+	}
+	both := []string{"touch app-first", "touch app-second"}
+
+	tests := []struct {
+		name        string
+		executor    Executor
+		consent     *bool // Answer to the consent prompt, nil for no prompt
+		wantAsked   []string
+		wantCreated []string
+	}{
+		{name: "no prompt skips", wantCreated: nil},
+		{name: "consent given", consent: ptr(true), wantAsked: both, wantCreated: []string{"app-first", "app-second"}},
+		{name: "consent declined", consent: ptr(false), wantAsked: both},
+		{name: "allow flag", executor: Executor{AllowCommands: true}, consent: ptr(false), wantCreated: []string{"app-first", "app-second"}},
+		{name: "allowlisted program", executor: Executor{AllowedCommands: []string{"touch"}}, wantCreated: []string{"app-first", "app-second"}},
+		{name: "allowlist matches exactly", executor: Executor{AllowedCommands: []string{"/usr/bin/touch"}}, consent: ptr(false), wantAsked: both},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			e := tt.executor
+			e.Dir = dir
+			e.Vars = map[string]string{"name": "app"}
+
+			var asked []string
+			if tt.consent != nil {
+				e.ConsentCommands = func(commands []string) (bool, error) {
+					asked = append(asked, commands...)
+					return *tt.consent, nil
+				}
+			}
+
+			if err := e.Run(actions); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(asked, tt.wantAsked) {
+				t.Errorf("consent asked for %v, want %v", asked, tt.wantAsked)
+			}
+			var created []string
+			for _, name := range []string{"app-first", "app-second"} {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					created = append(created, name)
+				}
+			}
+			if !reflect.DeepEqual(created, tt.wantCreated) {
+				t.Errorf("created %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestExecutor_OptionalCommandAsksOnce(t *testing.T) {
+	if _, err := exec.LookPath("touch"); err != nil {
+		t.Skip("touch not available")
+	}
+
+	actions := []config.Action{{Name: "touch", Type: TypeCommand, Command: "touch app", Optional: true}}
+	for _, answer := range []bool{true, false} {
+		dir := t.TempDir()
+		var confirms []string
+		consents := 0
+		e := Executor{
+			Dir: dir,
+			Confirm: func(message string) (bool, error) {
+				confirms = append(confirms, message)
+				return answer, nil
+			},
+			ConsentCommands: func([]string) (bool, error) {
+				consents++
+				return true, nil
+			},
+		}
+		if err := e.Run(actions); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if want := []string{"Run touch? (runs touch app)"}; !reflect.DeepEqual(confirms, want) || consents != 0 {
+			t.Errorf("asked %v and %d consents, want %v only", confirms, consents, want)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "app")); (err == nil) != answer {
+			t.Errorf("answer %v: command ran = %v", answer, err == nil)
+		}
+	}
+}
+
+func TestExecutor_CommandFailure(t *testing.T) {
+	e := &Executor{Dir: t.TempDir(), AllowCommands: true}
+
+	optional := []config.Action{{Name: "lint", Type: TypeCommand, Command: "scaffold-no-such-program", Optional: true}}
+	if err := e.Run(optional); err != nil {
+		t.Errorf("Run() error = %v, want an optional failure to be a warning", err)
+	}
+
+	required := []config.Action{{Name: "build", Type: TypeCommand, Command: "scaffold-no-such-program"}}
+	if err := e.Run(required); err == nil {
+		t.Error("Run() should return error for a failing required command")
+	}
+}
+
+func TestExecutor_CommandDirAndEnv(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "app", "web"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	e := &Executor{Dir: dir, Vars: map[string]string{"name": "app"}, AllowCommands: true}
+
+	actions := []config.Action{{
+		Name:    "install",
+		Type:    TypeCommand,
+		Command: "sh",
+		Args:    []string{"-c", `echo "$GREETING from $(basename "$PWD")" > out.txt`},
+		WorkDir: "{{ name }}/web",
+		Env:     map[string]string{"GREETING": "hello {{ name }}"},
+	}}
+	if err := e.Run(actions); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "app", "web", "out.txt"))
+	if err != nil {
+		t.Fatalf("command should run in app/web: %v", err)
+	}
+	if string(got) != "hello app from web\n" {
+		t.Errorf("out.txt = %q, want %q", got, "hello app from web\n")
+	}
+}
+
+func TestExecutor_CommandRunner(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "web"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	fake := &fakeRunner{}
+	e := &Executor{Dir: dir, Vars: map[string]string{"name": "app"}, AllowCommands: true, Runner: fake}
+
+	actions := []config.Action{{
+		Name:    "install",
+		Type:    TypeCommand,
+		Command: "npm install --prefix {{ name }}",
+		WorkDir: "web",
+		Env:     map[string]string{"NODE_ENV": "{{ name }}-dev"},
+	}}
+	if err := e.Run(actions); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(fake.calls) != 1 {
+		t.Fatalf("runs = %v, want one", fake.lines())
+	}
+	call := fake.calls[0]
+	if call.line != "npm install --prefix app" {
+		t.Errorf("command = %q, want %q", call.line, "npm install --prefix app")
+	}
+	if call.opts.Dir != filepath.Join(dir, "web") {
+		t.Errorf("dir = %q, want %q", call.opts.Dir, filepath.Join(dir, "web"))
+	}
+	if env := call.opts.Env; len(env) == 0 || env[len(env)-1] != "NODE_ENV=app-dev" {
+		t.Errorf("env should end with NODE_ENV=app-dev, got %v", env)
+	}
+
+	fake.fail = map[string]bool{"npm": true}
+	if err := e.Run(actions); err == nil || !strings.Contains(err.Error(), "npm failed") {
+		t.Errorf("Run() error = %v, want npm failed", err)
+	}
+}
+
+func TestExecutor_CommandDirOutsideProject(t *testing.T) {
+	e := &Executor{Dir: t.TempDir(), AllowCommands: true}
+
+	for _, workDir := range []string{"..", "../elsewhere", "/tmp"} {
+		actions := []config.Action{{Name: "escape", Type: TypeCommand, Command: "true", WorkDir: workDir}}
+		if err := e.Run(actions); err == nil {
+			t.Errorf("Run() with work_dir %s should return error", workDir)
+		}
+	}
+}
+
+func ptr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,54 @@
+package action
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+// editorEnv names the environment variables that may hold the user's editor,
+// in order of preference
+var editorEnv = []string{"VISUAL", "EDITOR"}
+
+// resolveEditor returns the editor command line: the configured editor, then
+// $VISUAL, then $EDITOR. It is empty when none is set.
+func resolveEditor(configured string, getenv func(string) string) []string {
+	if fields := strings.Fields(configured); len(fields) > 0 {
+		return fields
+	}
+	for _, name := range editorEnv {
+		if fields := strings.Fields(getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return nil
+}
+
+// openEditor opens the project directory in the user's editor. It does
+// nothing when the session is not interactive or no editor is configured.
+func (e *Executor) openEditor(a config.Action) error {
+	if !e.Interactive {
+		e.Log.Debug("not interactive, skipping %s", a.Name)
+		return nil
+	}
+	editor := resolveEditor(e.Editor, os.Getenv)
+	if len(editor) == 0 {
+		e.Log.Progress("📝 No editor configured, skipping %s (set $VISUAL, $EDITOR or editor in config.yaml)", a.Name)
+		return nil
+	}
+
+	e.Log.Progress("📝 Opening %s in %s", e.Dir, editor[0])
+	cmd := exec.Command(editor[0], append(editor[1:], ".")...)
+	cmd.Dir = e.Dir
+	// Terminal editors need the terminal
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", editor[0], err)
+	}
+	return nil
+}
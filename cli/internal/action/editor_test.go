@@ -0,0 +1,77 @@
+package action
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestResolveEditor(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		env        map[string]string
+		want       []string
+	}{
+		{name: "configured first", configured: "code --wait", env: map[string]string{"VISUAL": "vim", "EDITOR": "nano"}, want: []string{"code", "--wait"}},
+		{name: "visual before editor", env: map[string]string{"VISUAL": "vim", "EDITOR": "nano"}, want: []string{"vim"}},
+		{name: "editor", env: map[string]string{"EDITOR": "emacs -nw"}, want: []string{"emacs", "-nw"}},
+		{name: "blank values skipped", configured: " ", env: map[string]string{"VISUAL": " ", "EDITOR": "nano"}, want: []string{"nano"}},
+		{name: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveEditor(tt.configured, func(name string) string { return tt.env[name] })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveEditor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutor_Open(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// The editor records the directory it was asked to open
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncd \"$1\" && pwd > opened.txt\n"
+	if err := os.WriteFile(filepath.Join(bin, "fake-editor"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write editor: %v", err)
+	}
+	actions := []config.Action{{Name: "open", Type: TypeOpen}}
+
+	tests := []struct {
+		name        string
+		interactive bool
+		editor      string
+		wantOpened  bool
+	}{
+		{name: "opens", interactive: true, editor: filepath.Join(bin, "fake-editor"), wantOpened: true},
+		{name: "not interactive", editor: filepath.Join(bin, "fake-editor")},
+		{name: "no editor", interactive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", "")
+			t.Setenv("EDITOR", "")
+			dir := t.TempDir()
+			e := &Executor{Dir: dir, Interactive: tt.interactive, Editor: tt.editor}
+			if err := e.Run(actions); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			_, err := os.Stat(filepath.Join(dir, "opened.txt"))
+			if opened := err == nil; opened != tt.wantOpened {
+				t.Errorf("opened = %v, want %v", opened, tt.wantOpened)
+			}
+		})
+	}
+}
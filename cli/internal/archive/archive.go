@@ -0,0 +1,258 @@
+// Package archive packs directories into tar.gz and zip archives and unpacks
+// tar (gzip, bzip2 or xz compressed) and zip archives
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Format is an archive format
+type Format string
+
+const (
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+// ParseFormat validates an archive format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatTarGz, FormatZip:
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported archive format %q (use tar.gz or zip)", s)
+}
+
+// Create packs the contents of srcDir into an archive at dest, with every
+// entry under the prefix directory. File modes are preserved and entries are
+// written in a fixed order. A partially written archive is removed on error.
+func Create(format Format, srcDir, dest, prefix string) (err error) {
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+
+	switch format {
+	case FormatTarGz:
+		return writeTarGz(f, srcDir, prefix)
+	case FormatZip:
+		return writeZip(f, srcDir, prefix)
+	}
+	return fmt.Errorf("unsupported archive format %q", format)
+}
+
+// entry is a file or directory found under the source directory
+type entry struct {
+	path string // On disk
+	name string // In the archive, slash-separated
+	info fs.FileInfo
+}
+
+// walk calls fn for srcDir and everything under it, depth-first with the
+// entries of each directory in bytewise name order, so archives of the same
+// tree always list their entries in the same order
+func walk(srcDir, prefix string, fn func(e entry) error) error {
+	return filepath.Walk(srcDir, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		if rel == "." {
+			if prefix == "" {
+				return nil
+			}
+			name = prefix
+		}
+		return fn(entry{path: p, name: name, info: info})
+	})
+}
+
+func writeTarGz(w io.Writer, srcDir, prefix string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := walk(srcDir, prefix, func(e entry) error {
+		link := ""
+		if e.info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(e.path)
+			if err != nil {
+				return err
+			}
+			link = target
+		}
+
+		hdr, err := tar.FileInfoHeader(e.info, link)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e.name, err)
+		}
+		hdr.Name = e.name
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e.name, err)
+		}
+		if !e.info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFrom(tw, e.path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(w io.Writer, srcDir, prefix string) error {
+	zw := zip.NewWriter(w)
+
+	err := walk(srcDir, prefix, func(e entry) error {
+		hdr, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e.name, err)
+		}
+		hdr.Name = e.name
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		} else {
+			hdr.Method = zip.Deflate
+		}
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e.name, err)
+		}
+		switch {
+		case e.info.Mode()&fs.ModeSymlink != 0:
+			// Zip stores a symlink's target as its content
+			target, err := os.Readlink(e.path)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(fw, target)
+			return err
+		case e.info.Mode().IsRegular():
+			return copyFrom(fw, e.path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func copyFrom(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// ExtractTarGz unpacks a gzip-compressed tarball into dest, preserving file
+// modes. Entries that would land outside dest are rejected, and entries other
+// than files and directories are skipped.
+func ExtractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err == io.EOF {
+		return fmt.Errorf("archive is empty")
+	}
+	if err != nil {
+		return fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	return extractTar(gz, dest)
+}
+
+// extractTar unpacks an uncompressed tar stream into dest
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+
+		target, err := entryPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		mode := hdr.FileInfo().Mode()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeEntry(tr, target, mode.Perm()); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+			}
+		}
+	}
+}
+
+// entryPath resolves an archive entry name inside dest
+func entryPath(dest, name string) (string, error) {
+	if path.IsAbs(name) || hasDotDot(name) {
+		return "", fmt.Errorf("archive entry %q escapes the destination", name)
+	}
+	return filepath.Join(dest, filepath.FromSlash(path.Clean(name))), nil
+}
+
+func hasDotDot(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func writeEntry(r io.Reader, target string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,205 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// projectDir creates a small project with a plain file and an executable
+func projectDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	files := map[string]fs.FileMode{"README.md": 0644, "bin/run.sh": 0755}
+	for name, mode := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), mode); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		// Undo the umask so the modes are exact
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatalf("Failed to chmod file: %v", err)
+		}
+	}
+	return dir
+}
+
+var wantEntries = map[string]fs.FileMode{
+	"myapp/":           fs.ModeDir | 0755,
+	"myapp/README.md":  0644,
+	"myapp/bin/":       fs.ModeDir | 0755,
+	"myapp/bin/run.sh": 0755,
+}
+
+func TestCreate_TarGz(t *testing.T) {
+	src := projectDir(t)
+	os.Chmod(src, 0755)
+	dest := filepath.Join(t.TempDir(), "myapp.tar.gz")
+
+	if err := Create(FormatTarGz, src, dest, "myapp"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+
+	got := make(map[string]fs.FileMode)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar.Next() error = %v", err)
+		}
+		got[hdr.Name] = hdr.FileInfo().Mode()
+		if hdr.Name == "myapp/bin/run.sh" {
+			content, _ := io.ReadAll(tr)
+			if string(content) != "bin/run.sh" {
+				t.Errorf("run.sh content = %q, want bin/run.sh", content)
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, wantEntries) {
+		t.Errorf("entries = %v, want %v", got, wantEntries)
+	}
+}
+
+func TestCreate_Zip(t *testing.T) {
+	src := projectDir(t)
+	os.Chmod(src, 0755)
+	dest := filepath.Join(t.TempDir(), "myapp.zip")
+
+	if err := Create(FormatZip, src, dest, "myapp"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	zr, err := zip.OpenReader(dest)
+	if err != nil {
+		t.Fatalf("zip.OpenReader() error = %v", err)
+	}
+	defer zr.Close()
+
+	got := make(map[string]fs.FileMode)
+	for _, f := range zr.File {
+		got[f.Name] = f.Mode()
+	}
+	if !reflect.DeepEqual(got, wantEntries) {
+		t.Errorf("entries = %v, want %v", got, wantEntries)
+	}
+}
+
+// zipNames returns the entry names of a zip archive in the order written
+func zipNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("zip.OpenReader() error = %v", err)
+	}
+	defer zr.Close()
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestCreate_EntryOrder(t *testing.T) {
+	src := projectDir(t)
+	for _, name := range []string{"zeta.txt", "bin/a.sh", "app.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	var runs [][]string
+	for i := 0; i < 2; i++ {
+		dest := filepath.Join(t.TempDir(), "myapp.zip")
+		if err := Create(FormatZip, src, dest, "myapp"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		runs = append(runs, zipNames(t, dest))
+	}
+
+	want := []string{"myapp/", "myapp/README.md", "myapp/app.txt", "myapp/bin/", "myapp/bin/a.sh", "myapp/bin/run.sh", "myapp/zeta.txt"}
+	for i, got := range runs {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("run %d entries = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"tar.gz", "zip"} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("rar"); err == nil {
+		t.Error("ParseFormat(rar) should return error")
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	src := projectDir(t)
+	tarball := filepath.Join(t.TempDir(), "myapp.tar.gz")
+	if err := Create(FormatTarGz, src, tarball, "myapp"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	f, err := os.Open(tarball)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+
+	dest := t.TempDir()
+	if err := ExtractTarGz(f, dest); err != nil {
+		t.Fatalf("ExtractTarGz() error = %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dest, "myapp", "bin", "run.sh"))
+	if err != nil {
+		t.Fatalf("run.sh should be extracted: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh mode = %v, want 0755", info.Mode().Perm())
+	}
+}
+
+func TestExtractTarGz_RejectsEscape(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	gz.Close()
+
+	dest := filepath.Join(t.TempDir(), "out")
+	if err := ExtractTarGz(&buf, dest); err == nil {
+		t.Error("ExtractTarGz() should reject entries outside the destination")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil.txt")); !os.IsNotExist(err) {
+		t.Error("evil.txt should not be written")
+	}
+}
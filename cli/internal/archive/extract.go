@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Formats that can be extracted but not created
+const (
+	FormatTar    Format = "tar"
+	FormatTarBz2 Format = "tar.bz2"
+	FormatTarXz  Format = "tar.xz"
+)
+
+// extractFormats lists the formats Extract handles, for error messages
+const extractFormats = "tar, tar.gz, tar.bz2, tar.xz, zip"
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZip   = []byte("PK\x03\x04")
+	magicTar   = []byte("ustar")
+)
+
+// tarMagicOffset is where the ustar magic sits in a tar header
+const tarMagicOffset = 257
+
+// Extract unpacks the archive read from r into dest. The format is detected
+// from the leading magic bytes, falling back to the extension of name. Tar
+// archives are decompressed as they are read; zip archives, which need
+// random access, are spooled to a temporary file.
+func Extract(r io.Reader, name, dest string) error {
+	br := bufio.NewReaderSize(r, tarMagicOffset+len(magicTar))
+	header, err := br.Peek(tarMagicOffset + len(magicTar))
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	if len(header) == 0 {
+		return fmt.Errorf("archive is empty")
+	}
+
+	format := DetectFormat(header)
+	if format == "" {
+		format = FormatFromName(name)
+	}
+
+	switch format {
+	case FormatTar:
+		return extractTar(br, dest)
+	case FormatTarGz:
+		return ExtractTarGz(br, dest)
+	case FormatTarBz2:
+		return extractTar(bzip2.NewReader(br), dest)
+	case FormatTarXz:
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("not an xz archive: %w", err)
+		}
+		return extractTar(xr, dest)
+	case FormatZip:
+		return extractZipStream(br, dest)
+	}
+	return fmt.Errorf("unsupported archive format (supported: %s)", extractFormats)
+}
+
+// DetectFormat identifies an archive from its first bytes, returning "" when
+// they match no supported format
+func DetectFormat(header []byte) Format {
+	switch {
+	case bytes.HasPrefix(header, magicGzip):
+		return FormatTarGz
+	case bytes.HasPrefix(header, magicBzip2):
+		return FormatTarBz2
+	case bytes.HasPrefix(header, magicXz):
+		return FormatTarXz
+	case bytes.HasPrefix(header, magicZip):
+		return FormatZip
+	case len(header) >= tarMagicOffset+len(magicTar) &&
+		bytes.Equal(header[tarMagicOffset:tarMagicOffset+len(magicTar)], magicTar):
+		return FormatTar
+	}
+	return ""
+}
+
+// FormatFromName identifies an archive from its file extension, returning ""
+// for unknown extensions
+func FormatFromName(name string) Format {
+	name = strings.ToLower(name)
+	for _, ext := range []struct {
+		suffix string
+		format Format
+	}{
+		{".tar.gz", FormatTarGz},
+		{".tgz", FormatTarGz},
+		{".tar.bz2", FormatTarBz2},
+		{".tbz2", FormatTarBz2},
+		{".tar.xz", FormatTarXz},
+		{".txz", FormatTarXz},
+		{".zip", FormatZip},
+		{".tar", FormatTar},
+	} {
+		if strings.HasSuffix(name, ext.suffix) {
+			return ext.format
+		}
+	}
+	return ""
+}
+
+// extractZipStream spools a zip archive to a temporary file and unpacks it
+func extractZipStream(r io.Reader, dest string) error {
+	tmp, err := os.CreateTemp("", "scaffold-archive-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+	for _, zf := range zr.File {
+		if err := extractZipEntry(zf, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(zf *zip.File, dest string) error {
+	target, err := entryPath(dest, zf.Name)
+	if err != nil {
+		return err
+	}
+	mode := zf.Mode()
+
+	switch {
+	case mode.IsDir():
+		return os.MkdirAll(target, mode.Perm()|0700)
+	case mode.IsRegular():
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", zf.Name, err)
+		}
+		defer rc.Close()
+		if err := writeEntry(rc, target, mode.Perm()); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", zf.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// plainTar returns an uncompressed tar of projectDir under myapp/
+func plainTar(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := walk(projectDir(t), "myapp", func(e entry) error {
+		hdr, err := tar.FileInfoHeader(e.info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = e.name
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if e.info.IsDir() {
+			return nil
+		}
+		return copyFrom(tw, e.path)
+	})
+	if err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	tw.Close()
+
+	path := filepath.Join(t.TempDir(), "myapp.tar")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	return path
+}
+
+func created(t *testing.T, format Format) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "myapp."+string(format))
+	if err := Create(format, projectDir(t), path, "myapp"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return path
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive func(t *testing.T) string
+		hint    string
+	}{
+		{name: "tar.bz2", archive: func(*testing.T) string { return "testdata/myapp.tar.bz2" }},
+		{name: "tar.xz", archive: func(*testing.T) string { return "testdata/myapp.tar.xz" }},
+		{name: "tar.gz", archive: func(t *testing.T) string { return created(t, FormatTarGz) }},
+		{name: "zip", archive: func(t *testing.T) string { return created(t, FormatZip) }},
+		{name: "tar", archive: plainTar},
+		// Magic bytes win over a misleading extension
+		{name: "xz named zip", archive: func(*testing.T) string { return "testdata/myapp.tar.xz" }, hint: "myapp.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.archive(t)
+			hint := tt.hint
+			if hint == "" {
+				hint = path
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Failed to open archive: %v", err)
+			}
+			defer f.Close()
+
+			dest := t.TempDir()
+			if err := Extract(f, hint, dest); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dest, "myapp", "README.md"))
+			if err != nil || string(data) != "README.md" {
+				t.Errorf("README.md = %q, %v; want its contents", data, err)
+			}
+			info, err := os.Stat(filepath.Join(dest, "myapp", "bin", "run.sh"))
+			if err != nil {
+				t.Fatalf("run.sh should be extracted: %v", err)
+			}
+			if info.Mode().Perm() != 0755 {
+				t.Errorf("run.sh mode = %v, want 0755", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestExtract_Unsupported(t *testing.T) {
+	err := Extract(strings.NewReader("Rar!\x1a\x07\x00"), "myapp.rar", t.TempDir())
+	if err == nil {
+		t.Fatal("Extract() should reject an unknown format")
+	}
+	if !strings.Contains(err.Error(), extractFormats) {
+		t.Errorf("error = %v, want the supported formats listed", err)
+	}
+
+	if err := Extract(strings.NewReader(""), "myapp.tar.gz", t.TempDir()); err == nil {
+		t.Error("Extract() should reject an empty archive")
+	}
+}
+
+func TestFormatFromName(t *testing.T) {
+	tests := map[string]Format{
+		"v1.0.tar.gz":   FormatTarGz,
+		"v1.0.TGZ":      FormatTarGz,
+		"v1.0.tar.bz2":  FormatTarBz2,
+		"v1.0.tbz2":     FormatTarBz2,
+		"v1.0.tar.xz":   FormatTarXz,
+		"v1.0.txz":      FormatTarXz,
+		"v1.0.zip":      FormatZip,
+		"v1.0.tar":      FormatTar,
+		"v1.0.7z":       "",
+		"/download/tpl": "",
+	}
+	for name, want := range tests {
+		if got := FormatFromName(name); got != want {
+			t.Errorf("FormatFromName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
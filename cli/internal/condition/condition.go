@@ -0,0 +1,204 @@
+// Package condition evaluates variable-based conditions used in manifests
+package condition
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Evaluate reports whether expr holds for the given variables.
+//
+// Grammar:
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | comparison
+//	comparison = name [ ( "==" | "!=" ) value ]
+//
+// A bare name is true when the variable is set to a truthy value. Values may
+// be quoted with single or double quotes. An empty expression is always true.
+func Evaluate(expr string, vars map[string]string) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return false, err
+	}
+
+	p := &parser{tokens: tokens, vars: vars}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("invalid condition %q: unexpected %q", expr, p.tokens[p.pos])
+	}
+	return result, nil
+}
+
+// IsTruthy reports whether a variable value counts as true
+func IsTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "0", "no", "n", "off":
+		return false
+	}
+	return true
+}
+
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string in condition %q", expr)
+			}
+			// Keep the opening quote so the parser can tell literals from names
+			tokens = append(tokens, expr[i:i+1+end])
+			i += end + 2
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\n!()&|=\"'", rune(expr[i])) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected character %q in condition %q", c, expr)
+			}
+			tokens = append(tokens, expr[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+	vars   map[string]string
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *parser) parseOr() (bool, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		left = left || right
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (bool, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return false, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return false, err
+		}
+		left = left && right
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (bool, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		val, err := p.parseUnary()
+		return !val, err
+	case "(":
+		p.next()
+		val, err := p.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if p.next() != ")" {
+			return false, fmt.Errorf("missing closing parenthesis")
+		}
+		return val, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (bool, error) {
+	name := p.next()
+	if !isOperand(name) {
+		return false, fmt.Errorf("expected variable name, got %q", name)
+	}
+	value := p.operandValue(name)
+
+	op := p.peek()
+	if op != "==" && op != "!=" {
+		return IsTruthy(value), nil
+	}
+	p.next()
+
+	literal := p.next()
+	if !isOperand(literal) {
+		return false, fmt.Errorf("expected value after %s", op)
+	}
+	want := literal
+	if isQuoted(literal) {
+		want = literal[1:]
+	}
+
+	if op == "==" {
+		return value == want, nil
+	}
+	return value != want, nil
+}
+
+// operandValue returns a variable's value, or the literal itself if quoted
+func (p *parser) operandValue(tok string) string {
+	if isQuoted(tok) {
+		return tok[1:]
+	}
+	return p.vars[tok]
+}
+
+func isQuoted(tok string) bool {
+	return strings.HasPrefix(tok, `"`) || strings.HasPrefix(tok, "'")
+}
+
+func isOperand(tok string) bool {
+	switch tok {
+	case "", "&&", "||", "==", "!=", "!", "(", ")":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package condition
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	vars := map[string]string{
+		"database":   "postgres",
+		"use_docker": "true",
+		"use_celery": "false",
+		"name":       "my app",
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "empty", expr: "", want: true},
+		{name: "equals", expr: "database == postgres", want: true},
+		{name: "equals false", expr: "database == sqlite", want: false},
+		{name: "not equals", expr: "database != none", want: true},
+		{name: "quoted value", expr: `name == "my app"`, want: true},
+		{name: "single quoted value", expr: "database == 'postgres'", want: true},
+		{name: "truthy", expr: "use_docker", want: true},
+		{name: "falsy", expr: "use_celery", want: false},
+		{name: "missing is falsy", expr: "unknown", want: false},
+		{name: "missing equals empty", expr: `unknown == ""`, want: true},
+		{name: "negation", expr: "!use_celery", want: true},
+		{name: "and", expr: "use_docker && database == postgres", want: true},
+		{name: "or", expr: "use_celery || database == sqlite", want: false},
+		{name: "parentheses", expr: "!(use_celery || database == sqlite)", want: true},
+		{name: "bool literal", expr: "use_docker == true", want: true},
+		{name: "dangling operator", expr: "database ==", wantErr: true},
+		{name: "unbalanced parentheses", expr: "(use_docker", wantErr: true},
+		{name: "unterminated string", expr: `name == "my app`, wantErr: true},
+		{name: "trailing tokens", expr: "use_docker use_celery", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.expr, vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
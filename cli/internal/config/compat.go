@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/makemore/scaffold/internal/semver"
+)
+
+// DevVersion is the CLI version of development builds, which satisfies any
+// minimum version
+const DevVersion = "dev"
+
+// ReleasesURL is where newer CLI versions can be downloaded
+const ReleasesURL = "https://github.com/makemore/scaffold/releases"
+
+// CheckScaffoldVersion returns an error when the manifest's
+// min_scaffold_version is newer than cliVersion. Development builds and CLI
+// versions that are not semver satisfy every minimum.
+func (m *Manifest) CheckScaffoldVersion(cliVersion string) error {
+	if m.MinScaffoldVersion == "" {
+		return nil
+	}
+	min, err := semver.Parse(m.MinScaffoldVersion)
+	if err != nil {
+		return fmt.Errorf("invalid min_scaffold_version: %w", err)
+	}
+
+	if cliVersion == DevVersion {
+		return nil
+	}
+	current, err := semver.Parse(cliVersion)
+	if err != nil {
+		return nil
+	}
+
+	if current.Compare(min) < 0 {
+		return fmt.Errorf("requires scaffold %s or newer, but this is %s; please upgrade (%s)",
+			m.MinScaffoldVersion, cliVersion, ReleasesURL)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifest_CheckScaffoldVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		min     string
+		cli     string
+		wantErr string
+	}{
+		{name: "no minimum", min: "", cli: "0.1.0"},
+		{name: "satisfied", min: "1.2.0", cli: "1.3.0"},
+		{name: "equal", min: "v1.2.0", cli: "1.2.0"},
+		{name: "unsatisfied", min: "1.2.0", cli: "1.1.9", wantErr: "requires scaffold 1.2.0 or newer"},
+		{name: "prerelease is older", min: "2.0.0", cli: "2.0.0-rc.1", wantErr: "please upgrade"},
+		{name: "dev", min: "99.0.0", cli: DevVersion},
+		{name: "invalid minimum", min: "soon", cli: "1.0.0", wantErr: "invalid min_scaffold_version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manifest{Name: "t", MinScaffoldVersion: tt.min}
+			err := m.CheckScaffoldVersion(tt.cli)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckScaffoldVersion() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckScaffoldVersion() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
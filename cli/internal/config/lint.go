@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lint reports settings that are valid but likely mistakes: command actions
+// without a command, and choice variables offering a single choice. Unlike
+// Validate's errors, these don't stop a template from being used.
+func (m *Manifest) Lint() []string {
+	var warnings []string
+	for _, v := range m.Variables {
+		if (v.Type == "choice" || v.Type == "select") && len(v.Choices) == 1 {
+			warnings = append(warnings, fmt.Sprintf("variable %s has a single choice, %s", v.Name, v.Choices[0]))
+		}
+	}
+	for _, a := range m.Actions {
+		if a.Type == "command" && strings.TrimSpace(a.Command) == "" {
+			warnings = append(warnings, fmt.Sprintf("action %s has no command", a.Name))
+		}
+	}
+	return warnings
+}
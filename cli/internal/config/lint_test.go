@@ -0,0 +1,40 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManifest_Lint(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		want     []string
+	}{
+		{
+			name: "clean",
+			manifest: Manifest{
+				Variables: []Variable{{Name: "db", Type: "choice", Choices: []string{"postgres", "mysql"}}},
+				Actions:   []Action{{Name: "install", Type: "command", Command: "npm install"}, {Name: "done", Type: "message"}},
+			},
+		},
+		{
+			name:     "single choice",
+			manifest: Manifest{Variables: []Variable{{Name: "db", Type: "choice", Choices: []string{"postgres"}}}},
+			want:     []string{"variable db has a single choice, postgres"},
+		},
+		{
+			name:     "empty command",
+			manifest: Manifest{Actions: []Action{{Name: "install", Type: "command", Command: " "}}},
+			want:     []string{"action install has no command"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.manifest.Lint(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,38 +1,105 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 
+	"github.com/makemore/scaffold/internal/suggest"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	ManifestFile = "scaffold.yaml"
-	LockFile     = "scaffold.lock"
+	ManifestFile    = "scaffold.yaml"
+	LockFile        = "scaffold.lock"
+	LockfileVersion = "1"
 )
 
-// LoadManifest loads a scaffold.yaml from the given directory
+// ErrNoManifest is returned by LoadManifest for a directory without a
+// scaffold.yaml
+var ErrNoManifest = errors.New("no " + ManifestFile + " found")
+
+// DefaultManifest stands in for the manifest of a template that has none: a
+// base template declaring nothing, whose files are all generated
+func DefaultManifest() *Manifest {
+	return &Manifest{Type: "base"}
+}
+
+// LoadManifest loads a scaffold.yaml from the given directory.
+// Unknown fields are reported as errors so that typos don't go unnoticed.
 func LoadManifest(dir string) (*Manifest, error) {
 	path := filepath.Join(dir, ManifestFile)
 
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("no %s found in %s", ManifestFile, dir)
+			return nil, fmt.Errorf("%w in %s", ErrNoManifest, dir)
 		}
 		return nil, fmt.Errorf("failed to read manifest: %w", err)
 	}
 
 	var manifest Manifest
-	if err := yaml.Unmarshal(data, &manifest); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse manifest: %w", explainUnknownFields(err))
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
 	}
 
 	return &manifest, nil
 }
 
+// unknownFieldPattern matches yaml.v3's unknown field error message
+var unknownFieldPattern = regexp.MustCompile(`field (\S+) not found in type config\.(\w+)`)
+
+// manifestTypes maps type names in yaml errors to the types they describe
+var manifestTypes = map[string]reflect.Type{
+	"Manifest":   reflect.TypeOf(Manifest{}),
+	"Variable":   reflect.TypeOf(Variable{}),
+	"FileConfig": reflect.TypeOf(FileConfig{}),
+	"Action":     reflect.TypeOf(Action{}),
+
+	"ExternalInclude": reflect.TypeOf(ExternalInclude{}),
+}
+
+// explainUnknownFields appends a suggestion of the likely intended field to
+// each unknown field error
+func explainUnknownFields(err error) error {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return err
+	}
+
+	messages := make([]string, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		messages[i] = msg
+		m := unknownFieldPattern.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		t, ok := manifestTypes[m[2]]
+		if !ok {
+			continue
+		}
+
+		var names []string
+		for _, f := range yamlFields(t) {
+			names = append(names, f.name)
+		}
+		if s := suggest.Closest(m[1], names, 3); s != "" {
+			messages[i] += fmt.Sprintf(" (did you mean %q?)", s)
+		}
+	}
+	return &yaml.TypeError{Errors: messages}
+}
+
 // SaveLockfile writes a lockfile to the given directory
 func SaveLockfile(dir string, lock *Lockfile) error {
 	path := filepath.Join(dir, LockFile)
@@ -68,4 +135,3 @@ func LoadLockfile(dir string) (*Lockfile, error) {
 
 	return &lock, nil
 }
-
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -134,3 +135,175 @@ func TestLoadManifest_InvalidYAML(t *testing.T) {
 	}
 }
 
+func TestLoadManifest_UnknownField(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scaffold-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifestContent := `
+name: test-template
+require:
+  - postgres
+variables:
+  - name: project_name
+    defualt: app
+`
+	manifestPath := filepath.Join(tmpDir, "scaffold.yaml")
+	if err := os.WriteFile(manifestPath, []byte(manifestContent), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	_, err = LoadManifest(tmpDir)
+	if err == nil {
+		t.Fatal("LoadManifest() should return error for unknown field")
+	}
+	for _, want := range []string{
+		`field require not found`,
+		`(did you mean "requires"?)`,
+		`field defualt not found`,
+		`(did you mean "default"?)`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadManifest_TypeMismatch(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scaffold-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifestPath := filepath.Join(tmpDir, "scaffold.yaml")
+	if err := os.WriteFile(manifestPath, []byte("name: test\nvariables: nope\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	if _, err := LoadManifest(tmpDir); err == nil {
+		t.Error("LoadManifest() should return error for type mismatch")
+	}
+}
+
+func TestLoadManifest_DefaultNotInChoices(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "default not a choice",
+			content: "name: test\nvariables:\n  - name: database\n    type: choice\n    choices: [postgres, sqlite]\n    default: mongo\n",
+			wantErr: `variable database: default "mongo" is not one of postgres, sqlite`,
+		},
+		{
+			name:    "default a choice",
+			content: "name: test\nvariables:\n  - name: database\n    type: select\n    choices: [postgres, sqlite]\n    default: sqlite\n",
+		},
+		{
+			name:    "interpolated default",
+			content: "name: test\nvariables:\n  - name: database\n    type: choice\n    choices: [postgres, sqlite]\n    default: \"{{ fallback_db }}\"\n",
+		},
+		{
+			name:    "list item not a choice",
+			content: "name: test\nvariables:\n  - name: stores\n    type: list\n    choices: [postgres, redis]\n    default: redis, mongo\n",
+			wantErr: `variable stores: default "mongo" is not one of postgres, redis`,
+		},
+		{
+			name:    "list items all choices",
+			content: "name: test\nvariables:\n  - name: stores\n    type: multiselect\n    choices: [postgres, redis]\n    default: redis,postgres\n",
+		},
+		{
+			name:    "string variable",
+			content: "name: test\nvariables:\n  - name: database\n    choices: [postgres]\n    default: mongo\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "scaffold.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write manifest: %v", err)
+			}
+
+			_, err := LoadManifest(tmpDir)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadManifest() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadManifest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadManifest_GitConfigSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantKey string
+		wantErr string
+	}{
+		{
+			name:    "well-known variable",
+			content: "name: test\nvariables:\n  - name: author_email\n    source: git_config\n",
+			wantKey: "user.email",
+		},
+		{
+			name:    "explicit key",
+			content: "name: test\nvariables:\n  - name: signing_key\n    source: git_config:user.signingkey\n",
+			wantKey: "user.signingkey",
+		},
+		{
+			name:    "unknown variable",
+			content: "name: test\nvariables:\n  - name: license\n    source: git_config\n",
+			wantErr: `variable license: source "git_config"`,
+		},
+		{
+			name:    "unknown source",
+			content: "name: test\nvariables:\n  - name: author\n    source: env\n",
+			wantErr: `variable author: source "env"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpDir, "scaffold.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write manifest: %v", err)
+			}
+
+			m, err := LoadManifest(tmpDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadManifest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadManifest() error = %v", err)
+			}
+			if got := m.Variables[0].GitConfigKey(); got != tt.wantKey {
+				t.Errorf("GitConfigKey() = %q, want %q", got, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestLoadManifest_LineEndings(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "scaffold.yaml"), []byte("name: test\nline_endings: cr\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	_, err := LoadManifest(tmpDir)
+	if err == nil || !strings.Contains(err.Error(), `line_endings "cr" is not one of lf, crlf, preserve`) {
+		t.Errorf("LoadManifest() error = %v, want an unknown line_endings error", err)
+	}
+}
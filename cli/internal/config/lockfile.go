@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HashFile returns the SHA-256 content hash of a file as "sha256:<hex>"
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// vcsDirs hold version control metadata, which HashTree leaves out
+var vcsDirs = map[string]bool{".git": true, ".hg": true, ".svn": true}
+
+// HashTree returns a SHA-256 content hash of the files under dir as
+// "sha256:<hex>". It combines the sorted slash-separated paths with the
+// hashes of their contents, so it only depends on what the files hold and
+// where, not on when they were written. Version control metadata is left
+// out.
+func HashTree(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && vcsDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		var hash string
+		if d.Type()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			hash = "symlink:" + filepath.ToSlash(target)
+		} else if hash, err = HashFile(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), hash)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", dir, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LockFiles hashes the given slash-separated paths relative to dir
+func LockFiles(dir string, paths []string) ([]LockedFile, error) {
+	files := make([]LockedFile, 0, len(paths))
+	for _, p := range paths {
+		hash, err := HashFile(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", p, err)
+		}
+		files = append(files, LockedFile{Path: p, Hash: hash})
+	}
+	return files, nil
+}
+
+// Sources returns the parents, the base and then the modules
+func (l *Lockfile) Sources() []*LockedSource {
+	var sources []*LockedSource
+	for i := range l.Parents {
+		sources = append(sources, &l.Parents[i])
+	}
+	sources = append(sources, &l.Base)
+	for i := range l.Modules {
+		sources = append(sources, &l.Modules[i])
+	}
+	return sources
+}
+
+// SameAs reports whether two lockfiles record the same sources, variables
+// and files, ignoring when they were generated
+func (l *Lockfile) SameAs(other *Lockfile) bool {
+	a, b := *l, *other
+	a.Generated, b.Generated = "", ""
+
+	// Compare as saved, so that empty and missing lists are equal
+	aData, aErr := yaml.Marshal(&a)
+	bData, bErr := yaml.Marshal(&b)
+	return aErr == nil && bErr == nil && bytes.Equal(aData, bData)
+}
+
+// Module returns the locked module with the given name or source, or nil
+func (l *Lockfile) Module(name string) *LockedSource {
+	for i := range l.Modules {
+		if l.Modules[i].Name == name || l.Modules[i].Source == name {
+			return &l.Modules[i]
+		}
+	}
+	return nil
+}
+
+// RemoveModule drops the named module from the lockfile
+func (l *Lockfile) RemoveModule(name string) {
+	modules := l.Modules[:0]
+	for _, m := range l.Modules {
+		if m.Name != name && m.Source != name {
+			modules = append(modules, m)
+		}
+	}
+	l.Modules = modules
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLockfile_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	lock := &Lockfile{
+		Version:   LockfileVersion,
+		Generated: "2024-01-01T00:00:00Z",
+		Base: LockedSource{
+			Name:   "django-base",
+			Source: "github:makemore/scaffold/templates/django-base",
+			Ref:    "v1.2.0",
+			Files: []LockedFile{
+				{Path: "README.md", Hash: "sha256:aa"},
+				{Path: "app/settings.py", Hash: "sha256:bb"},
+			},
+		},
+		Modules: []LockedSource{{
+			Name:   "postgres",
+			Source: "file:./postgres",
+			Files:  []LockedFile{{Path: "docker-compose.yml", Hash: "sha256:cc"}},
+		}},
+		Variables: map[string]string{"project_name": "myapp"},
+	}
+
+	if err := SaveLockfile(dir, lock); err != nil {
+		t.Fatalf("SaveLockfile() error = %v", err)
+	}
+
+	got, err := LoadLockfile(dir)
+	if err != nil {
+		t.Fatalf("LoadLockfile() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, lock) {
+		t.Errorf("LoadLockfile() = %+v, want %+v", got, lock)
+	}
+}
+
+func TestLoadLockfile_WithoutFiles(t *testing.T) {
+	dir := t.TempDir()
+	content := `version: "1"
+generated: "2024-01-01T00:00:00Z"
+base:
+  name: django-base
+  source: github:makemore/scaffold/templates/django-base
+modules:
+  - name: postgres
+    source: file:./postgres
+variables:
+  project_name: myapp
+`
+	if err := os.WriteFile(filepath.Join(dir, LockFile), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write lockfile: %v", err)
+	}
+
+	lock, err := LoadLockfile(dir)
+	if err != nil {
+		t.Fatalf("LoadLockfile() error = %v", err)
+	}
+	if lock.Base.Files != nil || lock.Modules[0].Files != nil {
+		t.Errorf("Files = %v, %v, want none", lock.Base.Files, lock.Modules[0].Files)
+	}
+	if lock.Module("postgres") == nil {
+		t.Error("Module(postgres) should be found")
+	}
+}
+
+func TestLockFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src/main.go"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	files, err := LockFiles(dir, []string{"src/main.go"})
+	if err != nil {
+		t.Fatalf("LockFiles() error = %v", err)
+	}
+
+	// sha256("hello")
+	want := "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if len(files) != 1 || files[0].Path != "src/main.go" || files[0].Hash != want {
+		t.Errorf("LockFiles() = %+v, want src/main.go with %s", files, want)
+	}
+
+	if _, err := LockFiles(dir, []string{"missing.txt"}); err == nil {
+		t.Error("LockFiles() should return error for a missing file")
+	}
+}
+
+func TestHashTree(t *testing.T) {
+	writeTree := func(files map[string]string) string {
+		t.Helper()
+		dir := t.TempDir()
+		for path, content := range files {
+			fullPath := filepath.Join(dir, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+		}
+		return dir
+	}
+	hash := func(dir string) string {
+		t.Helper()
+		h, err := HashTree(dir)
+		if err != nil {
+			t.Fatalf("HashTree() error = %v", err)
+		}
+		return h
+	}
+
+	files := map[string]string{"scaffold.yaml": "name: api", "src/main.go": "package main"}
+	want := hash(writeTree(files))
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		equal bool
+	}{
+		{"identical tree", files, true},
+		{"vcs metadata ignored", map[string]string{"scaffold.yaml": "name: api", "src/main.go": "package main", ".git/HEAD": "ref"}, true},
+		{"content changed", map[string]string{"scaffold.yaml": "name: api", "src/main.go": "package api"}, false},
+		{"file renamed", map[string]string{"scaffold.yaml": "name: api", "src/app.go": "package main"}, false},
+		{"file added", map[string]string{"scaffold.yaml": "name: api", "src/main.go": "package main", "README.md": ""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hash(writeTree(tt.files)); (got == want) != tt.equal {
+				t.Errorf("HashTree() = %s, equal to the original %s: %v, want %v", got, want, got == want, tt.equal)
+			}
+		})
+	}
+
+	if _, err := HashTree(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("HashTree() should return error for a missing directory")
+	}
+}
+
+func TestLockfile_SameAs(t *testing.T) {
+	lock := func() *Lockfile {
+		return &Lockfile{
+			Version:   LockfileVersion,
+			Generated: "2024-01-01T00:00:00Z",
+			Base: LockedSource{
+				Name:   "base",
+				Source: "file:./base",
+				Files:  []LockedFile{{Path: "README.md", Hash: "sha256:aa"}},
+			},
+			Modules:   []LockedSource{{Name: "postgres", Source: "file:./postgres", Files: []LockedFile{}}},
+			Variables: map[string]string{"project_name": "myapp"},
+		}
+	}
+
+	later := lock()
+	later.Generated = "2024-06-01T00:00:00Z"
+	later.Modules[0].Files = nil
+	if !lock().SameAs(later) {
+		t.Error("SameAs() = false, want true ignoring the time and empty lists")
+	}
+
+	changed := lock()
+	changed.Variables["project_name"] = "other"
+	if lock().SameAs(changed) {
+		t.Error("SameAs() = true for a changed variable")
+	}
+
+	rehashed := lock()
+	rehashed.Base.Files[0].Hash = "sha256:bb"
+	if lock().SameAs(rehashed) {
+		t.Error("SameAs() = true for a changed file hash")
+	}
+}
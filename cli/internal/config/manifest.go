@@ -1,28 +1,145 @@
 // Package config handles scaffold configuration files
 package config
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Manifest represents a scaffold.yaml configuration file
 type Manifest struct {
-	Name        string            `yaml:"name"`
-	Description string            `yaml:"description,omitempty"`
-	Type        string            `yaml:"type"` // "base" or "module"
-	Version     string            `yaml:"version,omitempty"`
-	Variables   []Variable        `yaml:"variables,omitempty"`
-	Files       FileConfig        `yaml:"files,omitempty"`
-	Actions     []Action          `yaml:"actions,omitempty"`
-	Requires    []string          `yaml:"requires,omitempty"` // Required modules
-	Conflicts   []string          `yaml:"conflicts,omitempty"` // Incompatible modules
+	Name           string            `yaml:"name"`
+	Description    string            `yaml:"description,omitempty"`
+	Type           string            `yaml:"type"`              // "base" or "module"
+	Extends        string            `yaml:"extends,omitempty"` // Parent template processed first
+	Version        string            `yaml:"version,omitempty"`
+	Priority       int               `yaml:"priority,omitempty"`         // Higher wins when sources write the same file
+	Engine         string            `yaml:"engine,omitempty"`           // "simple" (default) or "gotemplate"
+	TrimBlankLines bool              `yaml:"trim_blank_lines,omitempty"` // Collapse blank line runs after rendering
+	LineEndings    string            `yaml:"line_endings,omitempty"`     // "lf", "crlf" or "preserve" (default)
+	Variables      []Variable        `yaml:"variables,omitempty"`
+	Computed       map[string]string `yaml:"computed,omitempty"` // Derived variables (name -> template)
+	Files          FileConfig        `yaml:"files,omitempty"`
+	Actions        []Action          `yaml:"actions,omitempty"`
+	Requires       []string          `yaml:"requires,omitempty"`  // Required modules
+	Conflicts      []string          `yaml:"conflicts,omitempty"` // Incompatible modules
+
+	// MinScaffoldVersion is the oldest CLI version the template works with
+	MinScaffoldVersion string `yaml:"min_scaffold_version,omitempty"`
 }
 
 // Variable represents a template variable
 type Variable struct {
 	Name        string   `yaml:"name"`
 	Description string   `yaml:"description,omitempty"`
-	Type        string   `yaml:"type,omitempty"` // string, bool, choice
+	Help        string   `yaml:"help,omitempty"`    // Longer explanation, shown on ? in prompts
+	Example     string   `yaml:"example,omitempty"` // Sample value, offered as a suggestion
+	Type        string   `yaml:"type,omitempty"`    // string, bool, choice, list
 	Default     string   `yaml:"default,omitempty"`
 	Required    bool     `yaml:"required,omitempty"`
-	Choices     []string `yaml:"choices,omitempty"` // For type: choice
+	Choices     []string `yaml:"choices,omitempty"` // For type: choice, or list
 	Pattern     string   `yaml:"pattern,omitempty"` // Regex validation
+	Group       string   `yaml:"group,omitempty"`   // Prompt section label
+	ShowIf      string   `yaml:"show_if,omitempty"` // Only prompt when this condition holds
+	Secret      bool     `yaml:"secret,omitempty"`  // Masked when prompted, never written to the lockfile
+	Source      string   `yaml:"source,omitempty"`  // git_config or git_config:<key>, to default from the user's git config
+}
+
+// GitConfigSource defaults a variable from the user's git config
+const GitConfigSource = "git_config"
+
+// gitConfigKeys are the git config keys read for well-known variables with a
+// bare source: git_config
+var gitConfigKeys = map[string]string{
+	"author":       "user.name",
+	"author_name":  "user.name",
+	"author_email": "user.email",
+	"github_user":  "github.user",
+}
+
+// GitConfigKey returns the git config key a variable defaults from: the key
+// given as git_config:<key>, or for a bare git_config the key of a
+// well-known variable such as author or author_email. It returns "" when
+// the variable doesn't default from git config.
+func (v Variable) GitConfigKey() string {
+	if key, ok := strings.CutPrefix(v.Source, GitConfigSource+":"); ok {
+		return key
+	}
+	if v.Source == GitConfigSource {
+		return gitConfigKeys[v.Name]
+	}
+	return ""
+}
+
+// CheckChoice returns an error when value is not one of a choice variable's
+// choices, or holds an item that isn't one of a list variable's choices.
+// Other variables, and variables without choices, accept any value.
+func (v Variable) CheckChoice(value string) error {
+	if len(v.Choices) == 0 {
+		return nil
+	}
+	switch {
+	case v.Type == "choice" || v.Type == "select":
+		return checkChoice(value, v.Choices)
+	case v.IsList():
+		for _, item := range SplitList(value) {
+			if err := checkChoice(item, v.Choices); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkChoice(value string, choices []string) error {
+	for _, choice := range choices {
+		if value == choice {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", value, strings.Join(choices, ", "))
+}
+
+// IsList reports whether the variable holds a list of values, given as a
+// comma-separated string
+func (v Variable) IsList() bool {
+	return v.Type == "list" || v.Type == "multiselect"
+}
+
+// SplitList splits a list variable's value on commas, trimming spaces around
+// items and dropping empty ones
+func SplitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate checks the manifest for mistakes that parsing doesn't catch: a
+// choice variable's default must be one of its choices, a variable's source
+// must name a git config key, and line_endings a known style. Defaults built
+// from other variables are only known, and checked, once those are.
+func (m *Manifest) Validate() error {
+	for _, v := range m.Variables {
+		if v.Source != "" && v.GitConfigKey() == "" {
+			return fmt.Errorf("variable %s: source %q is not git_config:<key>, or git_config for author, author_name, author_email or github_user", v.Name, v.Source)
+		}
+		if v.Default == "" || strings.Contains(v.Default, "{{") {
+			continue
+		}
+		if err := v.CheckChoice(v.Default); err != nil {
+			return fmt.Errorf("variable %s: default %w", v.Name, err)
+		}
+	}
+	switch m.LineEndings {
+	case "", "lf", "crlf", "preserve":
+	default:
+		return fmt.Errorf("line_endings %q is not one of lf, crlf, preserve", m.LineEndings)
+	}
+	return nil
 }
 
 // FileConfig specifies file handling rules
@@ -30,35 +147,75 @@ type FileConfig struct {
 	Include []string          `yaml:"include,omitempty"` // Glob patterns to include
 	Exclude []string          `yaml:"exclude,omitempty"` // Glob patterns to exclude
 	Rename  map[string]string `yaml:"rename,omitempty"`  // File rename mappings
+	// StripSuffixes are removed from the names of generated files, so that
+	// main.go.tmpl becomes main.go. An exact rename of the file wins.
+	StripSuffixes []string `yaml:"strip_suffixes,omitempty"`
+	// Ignore lists file and directory names that are never generated, on
+	// top of VCS metadata such as .git
+	Ignore []string `yaml:"ignore,omitempty"`
+	// Conditions maps glob patterns to conditions; matching files and
+	// directories are only generated when the condition holds
+	Conditions map[string]string `yaml:"conditions,omitempty"`
+	// DirConditions maps glob patterns to conditions for directories only; a
+	// matching directory is left out with everything in it unless the
+	// condition holds. A guard in the name, as in k8s__if_deploy_target_k8s,
+	// does the same without the manifest.
+	DirConditions map[string]string `yaml:"dir_conditions,omitempty"`
+	// ExternalIncludes copies files from elsewhere in the fetched repository,
+	// outside the template's directory, such as config shared by the
+	// templates of a monorepo
+	ExternalIncludes []ExternalInclude `yaml:"external_includes,omitempty"`
+}
+
+// ExternalInclude is a file or directory copied from outside the template
+// directory. It must stay within the fetched repository.
+type ExternalInclude struct {
+	From string `yaml:"from"`         // Relative to the template directory, e.g. ../shared/.editorconfig
+	To   string `yaml:"to,omitempty"` // Relative to the output, may use variables; the name of from when empty
 }
 
 // Action represents a post-generation action
 type Action struct {
 	Name        string   `yaml:"name"`
 	Description string   `yaml:"description,omitempty"`
-	Type        string   `yaml:"type"` // command, message
+	Type        string   `yaml:"type"` // command, message, git_init, open
 	Command     string   `yaml:"command,omitempty"`
 	Args        []string `yaml:"args,omitempty"`
 	Message     string   `yaml:"message,omitempty"`
 	Condition   string   `yaml:"condition,omitempty"` // Variable-based condition
 	Optional    bool     `yaml:"optional,omitempty"`  // User can skip
+
+	// WorkDir is where a command runs, relative to the output directory
+	WorkDir string `yaml:"work_dir,omitempty"`
+	// Env adds environment variables for a command; values may use variables
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// CommitMessage is the git_init commit message template
+	CommitMessage string `yaml:"commit_message,omitempty"`
 }
 
 // Lockfile represents a scaffold.lock file for reproducibility
 type Lockfile struct {
-	Version   string       `yaml:"version"`
-	Generated string       `yaml:"generated"`
-	Base      LockedSource `yaml:"base"`
-	Modules   []LockedSource `yaml:"modules,omitempty"`
+	Version   string            `yaml:"version"`
+	Generated string            `yaml:"generated"`
+	Parents   []LockedSource    `yaml:"parents,omitempty"` // Templates the base extends, root first
+	Base      LockedSource      `yaml:"base"`
+	Modules   []LockedSource    `yaml:"modules,omitempty"`
 	Variables map[string]string `yaml:"variables"`
 }
 
 // LockedSource represents a locked template/module source
 type LockedSource struct {
-	Name   string `yaml:"name"`
-	Source string `yaml:"source"`
-	Ref    string `yaml:"ref,omitempty"`
-	Commit string `yaml:"commit,omitempty"` // Resolved commit SHA
-	Hash   string `yaml:"hash,omitempty"`   // Content hash for non-git sources
+	Name   string       `yaml:"name"`
+	Source string       `yaml:"source"`
+	Ref    string       `yaml:"ref,omitempty"`
+	Commit string       `yaml:"commit,omitempty"` // Resolved commit SHA
+	Hash   string       `yaml:"hash,omitempty"`   // Content hash for non-git sources
+	Files  []LockedFile `yaml:"files,omitempty"`  // Files produced by this source
 }
 
+// LockedFile records a generated file and its content hash at generation time
+type LockedFile struct {
+	Path string `yaml:"path"` // Slash-separated, relative to the project root
+	Hash string `yaml:"hash"`
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SchemaURI is the JSON Schema draft used by ManifestSchema
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// ManifestSchema returns a JSON Schema describing scaffold.yaml, derived from
+// the yaml tags on the Manifest type
+func ManifestSchema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(Manifest{}))
+	schema["$schema"] = SchemaURI
+	schema["title"] = "Scaffold manifest (" + ManifestFile + ")"
+	return schema
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem()),
+		}
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for _, field := range yamlFields(t) {
+			properties[field.name] = schemaFor(field.typ)
+			if !field.omitempty {
+				required = append(required, field.name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
+
+type yamlField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+}
+
+// yamlFields returns the yaml-visible fields of a struct type in declaration order
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fields = append(fields, yamlField{
+			name:      name,
+			typ:       f.Type,
+			omitempty: strings.Contains(opts, "omitempty"),
+		})
+	}
+	return fields
+}
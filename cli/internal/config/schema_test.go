@@ -0,0 +1,46 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestManifestSchema(t *testing.T) {
+	schema := ManifestSchema()
+
+	if schema["$schema"] != SchemaURI {
+		t.Errorf("$schema = %v, want %v", schema["$schema"], SchemaURI)
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("manifest schema should disallow additional properties")
+	}
+
+	props := schema["properties"].(map[string]interface{})
+	for _, name := range []string{"name", "description", "type", "version", "variables", "files", "actions", "requires", "conflicts"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("schema missing property %q", name)
+		}
+	}
+
+	variables := props["variables"].(map[string]interface{})
+	if variables["type"] != "array" {
+		t.Errorf("variables type = %v, want array", variables["type"])
+	}
+	item := variables["items"].(map[string]interface{})
+	itemProps := item["properties"].(map[string]interface{})
+	if itemProps["required"].(map[string]interface{})["type"] != "boolean" {
+		t.Errorf("variables[].required type = %v, want boolean", itemProps["required"])
+	}
+	if required := item["required"].([]string); len(required) != 1 || required[0] != "name" {
+		t.Errorf("variables[] required = %v, want [name]", required)
+	}
+
+	rename := props["files"].(map[string]interface{})["properties"].(map[string]interface{})["rename"].(map[string]interface{})
+	if rename["type"] != "object" {
+		t.Errorf("files.rename type = %v, want object", rename["type"])
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("schema should be JSON-encodable: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	UserConfigDir     = ".scaffold"
+	UserConfigFile    = "config.yaml"
+	ProjectConfigFile = ".scaffoldrc"
+	// DefaultsFile holds shared variable defaults, in the user config
+	// directory unless defaults_file points elsewhere
+	DefaultsFile = "defaults.yaml"
+)
+
+// UserConfig represents user defaults from ~/.scaffold/config.yaml and .scaffoldrc
+type UserConfig struct {
+	Variables map[string]string `yaml:"variables,omitempty"` // Default variable values
+	Base      string            `yaml:"base,omitempty"`      // Default base template
+	CacheDir  string            `yaml:"cache_dir,omitempty"` // Preferred cache directory
+	Providers map[string]string `yaml:"providers,omitempty"` // Git provider shorthands (name -> base URL)
+	Indexes   []IndexConfig     `yaml:"indexes,omitempty"`   // Extra template indexes
+	// DefaultsFile is a shared file of variable defaults, such as an
+	// organization's, used after Variables
+	DefaultsFile string `yaml:"defaults_file,omitempty"`
+	// Editor opens generated projects for open actions, before $VISUAL and
+	// $EDITOR. Open actions run it without asking, so it is only read from
+	// the user config.
+	Editor string `yaml:"editor,omitempty"`
+	// IndexPublicKey is a base64 ed25519 key that must have signed the
+	// remote official index. It is only read from the user config, so a
+	// project's .scaffoldrc can't swap in its own key.
+	IndexPublicKey string `yaml:"index_public_key,omitempty"`
+	// NoEmbeddedFallback fails when the official index can't be fetched or
+	// read from the cache, instead of using the possibly stale built-in copy
+	NoEmbeddedFallback bool `yaml:"no_embedded_fallback,omitempty"`
+
+	// AllowCommands runs template command actions without asking, and
+	// AllowedCommands lists programs that may. Both are only read from the
+	// user config, so a project's .scaffoldrc can't grant itself permission.
+	AllowCommands   bool     `yaml:"allow_commands,omitempty"`
+	AllowedCommands []string `yaml:"allowed_commands,omitempty"`
+}
+
+// IndexConfig configures an extra template index
+type IndexConfig struct {
+	URL      string `yaml:"url"`
+	TTL      string `yaml:"ttl,omitempty"`      // Cache lifetime, e.g. 12h or 7d
+	Override bool   `yaml:"override,omitempty"` // Allow replacing existing templates
+}
+
+// LoadUserConfig loads the user config from homeDir and the project config from workDir.
+// Values in the project-local .scaffoldrc take precedence over the user config.
+// Missing files are not an error.
+func LoadUserConfig(homeDir, workDir string) (*UserConfig, error) {
+	cfg := &UserConfig{
+		Variables: make(map[string]string),
+		Providers: make(map[string]string),
+	}
+
+	paths := []string{}
+	userPath := ""
+	if homeDir != "" {
+		userPath = filepath.Join(homeDir, UserConfigDir, UserConfigFile)
+		paths = append(paths, userPath)
+	}
+	if workDir != "" {
+		paths = append(paths, filepath.Join(workDir, ProjectConfigFile))
+	}
+
+	for _, path := range paths {
+		layer, err := loadUserConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if layer == nil {
+			continue
+		}
+		// Only the user's own config may allow template commands, choose
+		// the program open actions run or set the index signing key
+		if path != userPath {
+			layer.AllowCommands, layer.AllowedCommands = false, nil
+			layer.Editor = ""
+			layer.IndexPublicKey = ""
+		}
+		cfg.merge(layer)
+	}
+
+	return cfg, nil
+}
+
+func loadUserConfigFile(path string) (*UserConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var cfg UserConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// merge overlays non-empty values from other onto c
+func (c *UserConfig) merge(other *UserConfig) {
+	for k, v := range other.Variables {
+		c.Variables[k] = v
+	}
+	for k, v := range other.Providers {
+		c.Providers[k] = v
+	}
+	if other.Base != "" {
+		c.Base = other.Base
+	}
+	if other.CacheDir != "" {
+		c.CacheDir = other.CacheDir
+	}
+	if other.DefaultsFile != "" {
+		c.DefaultsFile = other.DefaultsFile
+	}
+	if other.Editor != "" {
+		c.Editor = other.Editor
+	}
+	c.Indexes = append(c.Indexes, other.Indexes...)
+	if other.IndexPublicKey != "" {
+		c.IndexPublicKey = other.IndexPublicKey
+	}
+	c.NoEmbeddedFallback = c.NoEmbeddedFallback || other.NoEmbeddedFallback
+	c.AllowCommands = c.AllowCommands || other.AllowCommands
+	c.AllowedCommands = append(c.AllowedCommands, other.AllowedCommands...)
+}
+
+// ExpandProvider rewrites a configured provider shorthand (e.g. "acme:org/repo")
+// into a git source URI. Other URIs are returned unchanged.
+func (c *UserConfig) ExpandProvider(uri string) string {
+	name, path, ok := strings.Cut(uri, ":")
+	if !ok {
+		return uri
+	}
+	base, ok := c.Providers[name]
+	if !ok {
+		return uri
+	}
+	return "git:" + strings.TrimSuffix(base, "/") + "/" + path
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadUserConfig_Precedence(t *testing.T) {
+	homeDir := t.TempDir()
+	workDir := t.TempDir()
+
+	userContent := `
+variables:
+  org: myorg
+  author: My Name
+base: django
+cache_dir: ~/scaffold-cache
+providers:
+  acme: https://git.acme.com
+`
+	if err := os.MkdirAll(filepath.Join(homeDir, UserConfigDir), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(homeDir, UserConfigDir, UserConfigFile), []byte(userContent), 0644); err != nil {
+		t.Fatalf("Failed to write user config: %v", err)
+	}
+
+	projectContent := `
+variables:
+  org: otherorg
+base: nextjs
+`
+	if err := os.WriteFile(filepath.Join(workDir, ProjectConfigFile), []byte(projectContent), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	cfg, err := LoadUserConfig(homeDir, workDir)
+	if err != nil {
+		t.Fatalf("LoadUserConfig() error = %v", err)
+	}
+
+	if cfg.Variables["org"] != "otherorg" {
+		t.Errorf("Variables[org] = %v, want %v", cfg.Variables["org"], "otherorg")
+	}
+	if cfg.Variables["author"] != "My Name" {
+		t.Errorf("Variables[author] = %v, want %v", cfg.Variables["author"], "My Name")
+	}
+	if cfg.Base != "nextjs" {
+		t.Errorf("Base = %v, want %v", cfg.Base, "nextjs")
+	}
+	if cfg.CacheDir != "~/scaffold-cache" {
+		t.Errorf("CacheDir = %v, want %v", cfg.CacheDir, "~/scaffold-cache")
+	}
+	if cfg.Providers["acme"] != "https://git.acme.com" {
+		t.Errorf("Providers[acme] = %v, want %v", cfg.Providers["acme"], "https://git.acme.com")
+	}
+}
+
+func TestLoadUserConfig_CommandPermissions(t *testing.T) {
+	homeDir := t.TempDir()
+	workDir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(homeDir, UserConfigDir), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	userContent := "allowed_commands: [npm, go]\n"
+	if err := os.WriteFile(filepath.Join(homeDir, UserConfigDir, UserConfigFile), []byte(userContent), 0644); err != nil {
+		t.Fatalf("Failed to write user config: %v", err)
+	}
+	projectContent := "allow_commands: true\nallowed_commands: [sh]\n"
+	if err := os.WriteFile(filepath.Join(workDir, ProjectConfigFile), []byte(projectContent), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	cfg, err := LoadUserConfig(homeDir, workDir)
+	if err != nil {
+		t.Fatalf("LoadUserConfig() error = %v", err)
+	}
+	if cfg.AllowCommands {
+		t.Error("AllowCommands should not be granted by .scaffoldrc")
+	}
+	if len(cfg.AllowedCommands) != 2 || cfg.AllowedCommands[0] != "npm" || cfg.AllowedCommands[1] != "go" {
+		t.Errorf("AllowedCommands = %v, want only the user config's [npm go]", cfg.AllowedCommands)
+	}
+}
+
+func TestLoadUserConfig_TrustedSettings(t *testing.T) {
+	homeDir := t.TempDir()
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, ProjectConfigFile), []byte("editor: ./payload.sh\nindex_public_key: attackerkey\n"), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	cfg, err := LoadUserConfig(homeDir, workDir)
+	if err != nil {
+		t.Fatalf("LoadUserConfig() error = %v", err)
+	}
+	if cfg.Editor != "" {
+		t.Errorf("Editor = %q, want none from .scaffoldrc", cfg.Editor)
+	}
+	if cfg.IndexPublicKey != "" {
+		t.Errorf("IndexPublicKey = %q, want none from .scaffoldrc", cfg.IndexPublicKey)
+	}
+
+	if err := os.MkdirAll(filepath.Join(homeDir, UserConfigDir), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(homeDir, UserConfigDir, UserConfigFile), []byte("editor: code --wait\nindex_public_key: userkey\n"), 0644); err != nil {
+		t.Fatalf("Failed to write user config: %v", err)
+	}
+	cfg, err = LoadUserConfig(homeDir, workDir)
+	if err != nil {
+		t.Fatalf("LoadUserConfig() error = %v", err)
+	}
+	if cfg.Editor != "code --wait" || cfg.IndexPublicKey != "userkey" {
+		t.Errorf("Editor, IndexPublicKey = %q, %q, want the user config's", cfg.Editor, cfg.IndexPublicKey)
+	}
+}
+
+func TestLoadUserConfig_Missing(t *testing.T) {
+	cfg, err := LoadUserConfig(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadUserConfig() error = %v", err)
+	}
+	if len(cfg.Variables) != 0 || cfg.Base != "" || cfg.CacheDir != "" {
+		t.Errorf("LoadUserConfig() = %+v, want empty config", cfg)
+	}
+}
+
+func TestLoadUserConfig_InvalidYAML(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, ProjectConfigFile), []byte("invalid: yaml: content:"), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	if _, err := LoadUserConfig("", workDir); err == nil {
+		t.Error("LoadUserConfig() should return error for invalid YAML")
+	}
+}
+
+func TestUserConfig_ExpandProvider(t *testing.T) {
+	cfg := &UserConfig{Providers: map[string]string{"acme": "https://git.acme.com/"}}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"acme:team/repo", "git:https://git.acme.com/team/repo"},
+		{"github:org/repo", "github:org/repo"},
+		{"django", "django"},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.ExpandProvider(tt.input); got != tt.want {
+			t.Errorf("ExpandProvider(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
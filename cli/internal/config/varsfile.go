@@ -0,0 +1,114 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfilesKey is the section of a vars file holding named profiles: sets of
+// values for one environment, such as dev or prod, selected with --profile
+const ProfilesKey = "profiles"
+
+// ProfileFile returns the name of the vars file of a profile, such as
+// scaffold.prod.yaml
+func ProfileFile(profile string) string {
+	return "scaffold." + profile + ".yaml"
+}
+
+// LoadVarsFile loads a flat map of variable values from a YAML or JSON file.
+// JSON is detected by the .json extension; everything else is parsed as YAML.
+// Scalar values are converted to strings. The profiles section is left out.
+func LoadVarsFile(path string) (map[string]string, error) {
+	raw, err := readVarsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := raw[ProfilesKey].(map[string]interface{}); ok {
+		delete(raw, ProfilesKey)
+	}
+	return scalarVars(path, raw)
+}
+
+// LoadVarsProfile loads the values of a profile from the profiles section
+// of a vars file. It reports false when the file has no such profile.
+func LoadVarsProfile(path, profile string) (map[string]string, bool, error) {
+	raw, err := readVarsFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	profiles, _ := raw[ProfilesKey].(map[string]interface{})
+	values, ok := profiles[profile]
+	if !ok {
+		return nil, false, nil
+	}
+	section, ok := values.(map[string]interface{})
+	if !ok && values != nil {
+		return nil, false, fmt.Errorf("invalid vars file %s: profile %s must map variables to values", path, profile)
+	}
+	vars, err := scalarVars(path, section)
+	if err != nil {
+		return nil, false, err
+	}
+	return vars, true, nil
+}
+
+// readVarsFile parses a vars file without converting its values
+func readVarsFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vars file %s: %w", path, err)
+	}
+
+	raw := make(map[string]interface{})
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &raw)
+	} else {
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vars file %s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// scalarVars converts the values of a vars file to strings, rejecting any
+// that are not scalars
+func scalarVars(path string, raw map[string]interface{}) (map[string]string, error) {
+	vars := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case nil:
+			vars[k] = ""
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("invalid vars file %s: variable %s must be a scalar value", path, k)
+		default:
+			vars[k] = fmt.Sprint(v)
+		}
+	}
+	return vars, nil
+}
+
+// WriteVarsFile writes variable values as a file LoadVarsFile reads back:
+// JSON for the .json extension, YAML otherwise, with names in sorted order
+func WriteVarsFile(path string, vars map[string]string) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(vars, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(vars)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode vars file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write vars file %s: %w", path, err)
+	}
+	return nil
+}
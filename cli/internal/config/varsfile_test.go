@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadVarsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "yaml",
+			file:    "vars.yaml",
+			content: "org: acme\nport: 8080\nuse_docker: true\nempty:\n",
+			want:    map[string]string{"org": "acme", "port": "8080", "use_docker": "true", "empty": ""},
+		},
+		{
+			name:    "json",
+			file:    "vars.json",
+			content: `{"org": "acme", "port": 8080, "use_docker": false}`,
+			want:    map[string]string{"org": "acme", "port": "8080", "use_docker": "false"},
+		},
+		{
+			name:    "malformed json",
+			file:    "vars.json",
+			content: `{"org": `,
+			wantErr: "vars.json",
+		},
+		{
+			name:    "malformed yaml",
+			file:    "vars.yaml",
+			content: "invalid: yaml: content:",
+			wantErr: "vars.yaml",
+		},
+		{
+			name:    "nested value",
+			file:    "vars.yaml",
+			content: "database:\n  host: localhost\n",
+			wantErr: "must be a scalar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write vars file: %v", err)
+			}
+
+			got, err := LoadVarsFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadVarsFile() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadVarsFile() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("LoadVarsFile() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("vars[%s] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadVarsProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	content := "org: acme\nprofiles:\n  prod:\n    replicas: 3\n  empty:\n  broken: [a, b]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write vars file: %v", err)
+	}
+
+	// The profiles section is not a variable
+	if got, err := LoadVarsFile(path); err != nil || len(got) != 1 || got["org"] != "acme" {
+		t.Errorf("LoadVarsFile() = %v, %v, want only org", got, err)
+	}
+
+	tests := []struct {
+		profile string
+		want    map[string]string
+		wantOK  bool
+		wantErr bool
+	}{
+		{profile: "prod", want: map[string]string{"replicas": "3"}, wantOK: true},
+		{profile: "empty", want: map[string]string{}, wantOK: true},
+		{profile: "dev"},
+		{profile: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := LoadVarsProfile(path, tt.profile)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("LoadVarsProfile(%s) error = %v, wantErr %v", tt.profile, err, tt.wantErr)
+		}
+		if ok != tt.wantOK || len(got) != len(tt.want) || got["replicas"] != tt.want["replicas"] {
+			t.Errorf("LoadVarsProfile(%s) = %v, %v, want %v, %v", tt.profile, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoadVarsFile_Missing(t *testing.T) {
+	_, err := LoadVarsFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("LoadVarsFile() error = %v, want error naming the path", err)
+	}
+}
+
+func TestWriteVarsFile(t *testing.T) {
+	vars := map[string]string{"org": "acme", "port": "8080", "debug": "true", "note": "a: b", "empty": ""}
+	for _, name := range []string{"vars.yaml", "vars.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := WriteVarsFile(path, vars); err != nil {
+				t.Fatalf("WriteVarsFile() error = %v", err)
+			}
+			got, err := LoadVarsFile(path)
+			if err != nil {
+				t.Fatalf("LoadVarsFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, vars) {
+				t.Errorf("read back %v, want %v", got, vars)
+			}
+		})
+	}
+}
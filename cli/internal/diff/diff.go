@@ -0,0 +1,190 @@
+// Package diff produces line-based unified diffs
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// Op is the kind of an edit
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Edit is a single line of an edit script
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// Lines splits content into lines, keeping a final line without a newline
+func Lines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Compute returns an edit script turning a into b, based on the longest
+// common subsequence of lines
+func Compute(a, b []string) []Edit {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]Edit, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, Edit{Delete, a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Insert, b[j]})
+	}
+	return edits
+}
+
+// Count returns the number of inserted and deleted lines in an edit script
+func Count(edits []Edit) (inserted, deleted int) {
+	for _, e := range edits {
+		switch e.Op {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
+
+// Unified returns a unified diff from a to b with the given file labels, or
+// an empty string if the contents are equal
+func Unified(fromName, toName, a, b string) string {
+	edits := Compute(Lines(a), Lines(b))
+
+	var sb strings.Builder
+	for _, h := range hunks(edits, DefaultContext) {
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.fromLine, h.fromCount), hunkRange(h.toLine, h.toCount))
+		for _, e := range h.edits {
+			sb.WriteByte(byte(e.Op))
+			sb.WriteString(e.Line)
+			if !strings.HasSuffix(e.Line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+type hunk struct {
+	fromLine, fromCount int
+	toLine, toCount     int
+	edits               []Edit
+}
+
+// hunks groups changes with up to context lines of surrounding equal lines,
+// merging changes that are close enough to share context
+func hunks(edits []Edit, context int) []hunk {
+	var result []hunk
+	var current *hunk
+	fromLine, toLine := 1, 1
+	lastChange := -1
+
+	// appendEqual adds unchanged lines to the current hunk
+	appendEqual := func(lines []Edit) {
+		current.edits = append(current.edits, lines...)
+		current.fromCount += len(lines)
+		current.toCount += len(lines)
+	}
+	// flush closes the current hunk with its trailing context
+	flush := func() {
+		appendEqual(edits[lastChange+1 : min(lastChange+1+context, len(edits))])
+		result = append(result, *current)
+	}
+
+	for i, e := range edits {
+		if e.Op != Equal {
+			if current == nil || i-lastChange > 2*context {
+				if current != nil {
+					flush()
+				}
+				start := max(i-context, lastChange+1)
+				lead := i - start
+				current = &hunk{fromLine: fromLine - lead, toLine: toLine - lead}
+				appendEqual(edits[start:i])
+			} else {
+				// Equal lines between two nearby changes
+				appendEqual(edits[lastChange+1 : i])
+			}
+
+			current.edits = append(current.edits, e)
+			if e.Op == Delete {
+				current.fromCount++
+			} else {
+				current.toCount++
+			}
+			lastChange = i
+		}
+
+		if e.Op != Insert {
+			fromLine++
+		}
+		if e.Op != Delete {
+			toLine++
+		}
+	}
+
+	if current != nil {
+		flush()
+	}
+	return result
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before the change
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
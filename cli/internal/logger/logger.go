@@ -0,0 +1,87 @@
+// Package logger provides leveled output for the CLI. Progress and debug
+// messages go to one writer (normally stderr) so that results written to the
+// other (normally stdout) can be piped cleanly.
+package logger
+
+import (
+	"fmt"
+	"io"
+)
+
+// Level controls how much output is shown
+type Level int
+
+const (
+	// Quiet shows errors only
+	Quiet Level = iota
+	// Normal shows progress and results
+	Normal
+	// Verbose additionally shows debug details such as resolved URLs,
+	// cache hits and per-file writes
+	Verbose
+)
+
+// Logger writes leveled output. A nil *Logger discards everything.
+type Logger struct {
+	Level Level
+	Out   io.Writer // Results
+	Err   io.Writer // Progress, debug and errors
+}
+
+// New creates a logger writing results to out and everything else to err
+func New(out, err io.Writer, level Level) *Logger {
+	return &Logger{Level: level, Out: out, Err: err}
+}
+
+// Progress reports what the CLI is doing, unless quiet
+func (l *Logger) Progress(format string, args ...interface{}) {
+	if l != nil && l.Level >= Normal {
+		fmt.Fprintf(l.Err, format+"\n", args...)
+	}
+}
+
+// Debug reports details only shown when verbose
+func (l *Logger) Debug(format string, args ...interface{}) {
+	if l != nil && l.Level >= Verbose {
+		fmt.Fprintf(l.Err, "  · "+format+"\n", args...)
+	}
+}
+
+// Result prints command output, unless quiet
+func (l *Logger) Result(format string, args ...interface{}) {
+	if l != nil && l.Level >= Normal {
+		fmt.Fprintf(l.Out, format+"\n", args...)
+	}
+}
+
+// Warn reports a potential problem, unless quiet
+func (l *Logger) Warn(format string, args ...interface{}) {
+	if l != nil && l.Level >= Normal {
+		fmt.Fprintf(l.Err, "⚠️  "+format+"\n", args...)
+	}
+}
+
+// Error reports a problem at every level
+func (l *Logger) Error(format string, args ...interface{}) {
+	if l != nil {
+		fmt.Fprintf(l.Err, format+"\n", args...)
+	}
+}
+
+// DebugWriter returns a writer for detailed output from subprocesses, which
+// discards unless verbose
+func (l *Logger) DebugWriter() io.Writer {
+	if l == nil || l.Level < Verbose {
+		return io.Discard
+	}
+	return l.Err
+}
+
+// ProgressWriter returns a writer for progress output from subprocesses,
+// which discards when quiet
+func (l *Logger) ProgressWriter() io.Writer {
+	if l == nil || l.Level < Normal {
+		return io.Discard
+	}
+	return l.Err
+}
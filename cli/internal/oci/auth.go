@@ -0,0 +1,183 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// authorize answers a WWW-Authenticate challenge with the docker
+// credentials for the registry. Bearer challenges are exchanged for a token
+// at the realm, anonymously when there are no credentials.
+func (c *Client) authorize(ctx context.Context, ref Reference, challenge string) (string, error) {
+	user, secret, err := DockerCredentials(ref.Registry)
+	if err != nil {
+		c.Log.Debug("no docker credentials for %s: %v", ref.Registry, err)
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if user == "" && secret == "" {
+			return "", fmt.Errorf("registry %s requires credentials; run docker login %s", ref.Registry, ref.Registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+secret)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	if params["scope"] == "" {
+		query.Set("scope", "repository:"+ref.Repository+":pull")
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if user != "" || secret != "" {
+		req.SetBasicAuth(user, secret)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %s failed: HTTP %d", realm.Host, resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("token response from %s has no token", realm.Host)
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://auth.example.com/token",service="registry"` into
+// its scheme and parameters
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
+
+// dockerConfig is the part of docker's config.json that holds credentials
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+// DockerCredentials returns the login for a registry host from docker's
+// config.json, in $DOCKER_CONFIG or ~/.docker: from the host's credential
+// helper, the default credential store, or the auths entry, in that order.
+// Both are empty when there is no login.
+func DockerCredentials(host string) (user, secret string, err error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", fmt.Errorf("failed to parse docker config: %w", err)
+	}
+
+	helper := config.CredHelpers[host]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		return credentialHelper(helper, host)
+	}
+
+	auth, ok := config.Auths[host]
+	if !ok || auth.Auth == "" {
+		return "", "", nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid auth for %s in docker config: %w", host, err)
+	}
+	user, secret, _ = strings.Cut(string(decoded), ":")
+	return user, secret, nil
+}
+
+// credentialHelper asks docker-credential-<helper> for the login of a host.
+// A helper that has none reports it as an error, which is not one here.
+func credentialHelper(helper, host string) (string, string, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(host)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(string(out)+stderr.String(), "credentials not found") {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("docker-credential-%s failed: %w", helper, err)
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return "", "", fmt.Errorf("docker-credential-%s: invalid output: %w", helper, err)
+	}
+	return creds.Username, creds.Secret, nil
+}
@@ -0,0 +1,227 @@
+// Package oci pulls manifests and blobs from OCI registries, such as ghcr.io
+// or a local registry:2, using the distribution API
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/retry"
+)
+
+// Scheme prefixes OCI references, given as oci://registry/repository:tag or
+// oci://registry/repository@sha256:<hex>
+const Scheme = "oci://"
+
+// TitleAnnotation names the file a layer holds, as set by oras push
+const TitleAnnotation = "org.opencontainers.image.title"
+
+// manifestMediaTypes are the image manifest formats accepted when pulling
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference identifies a manifest in a registry
+type Reference struct {
+	Registry   string // Host, with the port if any
+	Repository string
+	Reference  string // Tag or digest
+}
+
+// ParseReference parses an oci:// reference. The tag defaults to latest.
+func ParseReference(s string) (Reference, error) {
+	rest, ok := strings.CutPrefix(s, Scheme)
+	if !ok {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q: missing %s", s, Scheme)
+	}
+	host, repo, ok := strings.Cut(rest, "/")
+	if !ok || host == "" || repo == "" {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q: want %sregistry/repository:tag", s, Scheme)
+	}
+
+	ref := Reference{Registry: host, Repository: repo, Reference: "latest"}
+	if name, digest, ok := strings.Cut(repo, "@"); ok {
+		ref.Repository, ref.Reference = name, digest
+	} else if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		ref.Repository, ref.Reference = repo[:i], repo[i+1:]
+	}
+	if ref.Repository == "" || ref.Reference == "" {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q", s)
+	}
+	return ref, nil
+}
+
+// String returns the reference in oci:// form
+func (r Reference) String() string {
+	sep := ":"
+	if IsDigest(r.Reference) {
+		sep = "@"
+	}
+	return Scheme + r.Registry + "/" + r.Repository + sep + r.Reference
+}
+
+// IsDigest reports whether a reference is a digest rather than a tag
+func IsDigest(ref string) bool {
+	return strings.Contains(ref, ":")
+}
+
+// baseURL returns the repository's API root. Registries on the local
+// machine are spoken to over plain HTTP, as docker does.
+func (r Reference) baseURL() string {
+	host := r.Registry
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.HasPrefix(host, "[") {
+		host = h
+	}
+	switch {
+	case host == "localhost", host == "127.0.0.1", host == "[::1]", strings.HasSuffix(host, ".localhost"):
+		return "http://" + r.Registry + "/v2/" + r.Repository
+	}
+	return "https://" + r.Registry + "/v2/" + r.Repository
+}
+
+// Manifest is the part of an image manifest needed to pull an artifact
+type Manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []Descriptor `json:"layers"`
+}
+
+// Descriptor points to a blob
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Title returns the file name the layer was pushed from, if recorded
+func (d Descriptor) Title() string {
+	return d.Annotations[TitleAnnotation]
+}
+
+// Client pulls from registries, logging in with docker's credentials when a
+// registry asks for them. Tokens are kept per repository.
+type Client struct {
+	HTTP *http.Client
+	Log  *logger.Logger
+	auth map[string]string // Authorization header by registry/repository
+}
+
+// NewClient creates a Client whose requests time out after timeout, or never
+// when it is 0
+func NewClient(timeout time.Duration, log *logger.Logger) *Client {
+	return &Client{HTTP: &http.Client{Timeout: timeout}, Log: log}
+}
+
+// Manifest fetches the manifest ref points to and returns it with its
+// digest. A manifest pulled by digest is verified against it. Server errors
+// are marked as transient.
+func (c *Client) Manifest(ctx context.Context, ref Reference) (*Manifest, string, error) {
+	data, err := c.get(ctx, ref, "/manifests/"+ref.Reference, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	digest := Digest(data)
+	if IsDigest(ref.Reference) && digest != ref.Reference {
+		return nil, "", fmt.Errorf("manifest digest is %s, want %s", digest, ref.Reference)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, digest, nil
+}
+
+// Blob downloads a blob of ref's repository and verifies its digest
+func (c *Client) Blob(ctx context.Context, ref Reference, digest string) ([]byte, error) {
+	data, err := c.get(ctx, ref, "/blobs/"+digest, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blob %s: %w", digest, err)
+	}
+	if err := CheckDigest(data, digest); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Digest returns the sha256 digest of data
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// CheckDigest verifies data against a sha256:<hex> digest
+func CheckDigest(data []byte, digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported digest %q", digest)
+	}
+	if got := Digest(data); got != digest {
+		return fmt.Errorf("blob digest is %s, want %s", got, digest)
+	}
+	return nil
+}
+
+// get fetches a path below the repository, answering an authentication
+// challenge once
+func (c *Client) get(ctx context.Context, ref Reference, path, accept string) ([]byte, error) {
+	key := ref.Registry + "/" + ref.Repository
+	resp, err := c.do(ctx, ref, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.auth[key] == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		auth, err := c.authorize(ctx, ref, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if c.auth == nil {
+			c.auth = make(map[string]string)
+		}
+		c.auth[key] = auth
+		if resp, err = c.do(ctx, ref, path, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			return nil, retry.Transient(err)
+		}
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) do(ctx context.Context, ref Reference, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref.baseURL()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if auth := c.auth[ref.Registry+"/"+ref.Repository]; auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return c.httpClient().Do(req)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+	return c.HTTP
+}
@@ -0,0 +1,124 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Reference
+		wantErr bool
+	}{
+		{"oci://ghcr.io/acme/templates:v1", Reference{"ghcr.io", "acme/templates", "v1"}, false},
+		{"oci://ghcr.io/acme/templates", Reference{"ghcr.io", "acme/templates", "latest"}, false},
+		{"oci://localhost:5000/index:2024", Reference{"localhost:5000", "index", "2024"}, false},
+		{"oci://localhost:5000/index", Reference{"localhost:5000", "index", "latest"}, false},
+		{"oci://ghcr.io/acme/templates@sha256:abc", Reference{"ghcr.io", "acme/templates", "sha256:abc"}, false},
+		{"oci://ghcr.io", Reference{}, true},
+		{"oci://ghcr.io/acme:", Reference{}, true},
+		{"https://ghcr.io/acme/templates", Reference{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseReference(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:acme/index:pull"`)
+	if scheme != "Bearer" {
+		t.Errorf("scheme = %q, want Bearer", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:acme/index:pull",
+	}
+	for key, value := range want {
+		if params[key] != value {
+			t.Errorf("%s = %q, want %q", key, params[key], value)
+		}
+	}
+}
+
+func TestReference_String(t *testing.T) {
+	for _, s := range []string{"oci://ghcr.io/acme/templates:v1", "oci://localhost:5000/index@sha256:abc"} {
+		ref, err := ParseReference(s)
+		if err != nil {
+			t.Fatalf("ParseReference(%s) error = %v", s, err)
+		}
+		if got := ref.String(); got != s {
+			t.Errorf("String() = %q, want %q", got, s)
+		}
+	}
+}
+
+func TestCheckDigest(t *testing.T) {
+	data := []byte("version: \"1\"\n")
+	sum := sha256.Sum256(data)
+	if err := CheckDigest(data, "sha256:"+hex.EncodeToString(sum[:])); err != nil {
+		t.Errorf("CheckDigest() error = %v", err)
+	}
+	if err := CheckDigest([]byte("tampered"), "sha256:"+hex.EncodeToString(sum[:])); err == nil {
+		t.Error("CheckDigest() should reject a mismatched blob")
+	}
+	if err := CheckDigest(data, "md5:abc"); err == nil {
+		t.Error("CheckDigest() should reject unsupported algorithms")
+	}
+}
+
+// useDockerConfig points DOCKER_CONFIG at a config.json with the given
+// content
+func useDockerConfig(t *testing.T, content string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write docker config: %v", err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+}
+
+func TestDockerCredentials_Helper(t *testing.T) {
+	bin := t.TempDir()
+	helper := "#!/bin/sh\nread host\n" +
+		"if [ \"$host\" = ghcr.io ]; then echo '{\"Username\":\"alice\",\"Secret\":\"s3cret\"}'; exit 0; fi\n" +
+		"echo 'credentials not found in native keychain'; exit 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker-credential-stub"), []byte(helper), 0755); err != nil {
+		t.Fatalf("Failed to write helper: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	useDockerConfig(t, `{"credHelpers": {"ghcr.io": "stub", "quay.io": "stub"}, "auths": {"ghcr.io": {"auth": "ignored"}}}`)
+
+	tests := []struct {
+		host       string
+		wantUser   string
+		wantSecret string
+	}{
+		{"ghcr.io", "alice", "s3cret"},
+		{"quay.io", "", ""},
+		{"docker.io", "", ""},
+	}
+	for _, tt := range tests {
+		user, secret, err := DockerCredentials(tt.host)
+		if err != nil {
+			t.Fatalf("DockerCredentials(%s) error = %v", tt.host, err)
+		}
+		if user != tt.wantUser || secret != tt.wantSecret {
+			t.Errorf("DockerCredentials(%s) = %q, %q, want %q, %q", tt.host, user, secret, tt.wantUser, tt.wantSecret)
+		}
+	}
+}
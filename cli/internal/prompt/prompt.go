@@ -3,12 +3,20 @@ package prompt
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/makemore/scaffold/internal/condition"
 	"github.com/makemore/scaffold/internal/config"
+	"github.com/makemore/scaffold/internal/template"
 )
 
+// askOne is the survey entry point, replaceable in tests
+var askOne = survey.AskOne
+
 // PromptForVariables prompts the user for each variable defined in the manifest
 func PromptForVariables(cfg *config.Manifest, existingVars map[string]string) (map[string]string, error) {
 	result := make(map[string]string)
@@ -18,54 +26,221 @@ func PromptForVariables(cfg *config.Manifest, existingVars map[string]string) (m
 		result[k] = v
 	}
 
-	for _, v := range cfg.Variables {
-		// Skip if already provided
-		if _, exists := result[v.Name]; exists {
-			continue
-		}
+	for _, group := range GroupVariables(cfg.Variables) {
+		headerShown := false
 
-		value, err := promptForVariable(v)
-		if err != nil {
-			return nil, err
+		for _, v := range group.Variables {
+			// Skip if already provided
+			if _, exists := result[v.Name]; exists {
+				continue
+			}
+
+			// Render the default against the answers so far
+			def, err := template.ResolveDefault(v.Default, result)
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+			}
+			v.Default = def
+
+			// Skip if the show_if condition fails given the answers so far
+			show, err := shouldPrompt(v, result)
+			if err != nil {
+				return nil, err
+			}
+			if !show {
+				if v.Default != "" {
+					result[v.Name] = v.Default
+				}
+				continue
+			}
+
+			if group.Name != "" && !headerShown {
+				fmt.Printf("\n%s\n", group.Name)
+				headerShown = true
+			}
+
+			value, err := promptForVariable(v)
+			if err != nil {
+				return nil, err
+			}
+			result[v.Name] = value
 		}
-		result[v.Name] = value
 	}
 
 	return result, nil
 }
 
+// shouldPrompt evaluates a variable's show_if condition against known values
+func shouldPrompt(v config.Variable, vars map[string]string) (bool, error) {
+	show, err := condition.Evaluate(v.ShowIf, vars)
+	if err != nil {
+		return false, fmt.Errorf("variable %s: %w", v.Name, err)
+	}
+	return show, nil
+}
+
+// VariableGroup is a labeled set of variables prompted together
+type VariableGroup struct {
+	Name      string
+	Variables []config.Variable
+}
+
+// GroupVariables partitions variables by their Group label. Groups are ordered
+// by first appearance and manifest order is preserved within each group.
+// Ungrouped variables form a group with an empty name.
+func GroupVariables(vars []config.Variable) []VariableGroup {
+	var groups []VariableGroup
+	index := make(map[string]int)
+
+	for _, v := range vars {
+		i, ok := index[v.Group]
+		if !ok {
+			i = len(groups)
+			index[v.Group] = i
+			groups = append(groups, VariableGroup{Name: v.Group})
+		}
+		groups[i].Variables = append(groups[i].Variables, v)
+	}
+
+	return groups
+}
+
 func promptForVariable(v config.Variable) (string, error) {
 	// Build the prompt message
 	message := v.Name
 	if v.Description != "" {
 		message = v.Description
 	}
+	help := variableHelp(v)
 
 	switch v.Type {
 	case "select", "choice":
-		return promptSelect(message, v.Choices, v.Default)
-	case "confirm", "boolean":
-		return promptConfirm(message, v.Default == "true")
+		return promptSelect(message, help, v.Choices, v.Default)
+	case "confirm", "boolean", "bool":
+		return promptConfirm(message, help, v.Default == "true")
+	case "list", "multiselect":
+		if len(v.Choices) > 0 {
+			return promptMultiSelect(message, help, v.Choices, v.Default)
+		}
+		validators, err := Validators(v)
+		if err != nil {
+			return "", err
+		}
+		return promptInput(message+" (comma-separated)", help, v.Example, v.Default, validators...)
 	default:
-		return promptInput(message, v.Default)
+		validators, err := Validators(v)
+		if err != nil {
+			return "", err
+		}
+		if v.Secret {
+			return promptSecret(message, help, v.Default, validators...)
+		}
+		return promptInput(message, help, v.Example, v.Default, validators...)
 	}
 }
 
-func promptInput(message, defaultValue string) (string, error) {
-	var result string
+// variableHelp returns the text shown when ? is pressed at a variable's
+// prompt: its help followed by its example value
+func variableHelp(v config.Variable) string {
+	var lines []string
+	if v.Help != "" {
+		lines = append(lines, v.Help)
+	}
+	if v.Example != "" {
+		lines = append(lines, "Example: "+v.Example)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validators builds the checks for a variable's required, pattern and type
+// settings. A pattern must match the whole value.
+func Validators(v config.Variable) ([]survey.Validator, error) {
+	var validators []survey.Validator
+	if v.Required {
+		validators = append(validators, survey.Required)
+	}
+
+	if v.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + v.Pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: invalid pattern: %w", v.Name, err)
+		}
+		validators = append(validators, func(ans interface{}) error {
+			if s, _ := ans.(string); s != "" && !re.MatchString(s) {
+				return fmt.Errorf("must match %s", v.Pattern)
+			}
+			return nil
+		})
+	}
+
+	switch v.Type {
+	case "int", "integer", "number":
+		validators = append(validators, func(ans interface{}) error {
+			if s, _ := ans.(string); s != "" {
+				if _, err := strconv.Atoi(s); err != nil {
+					return fmt.Errorf("must be a whole number")
+				}
+			}
+			return nil
+		})
+	}
+
+	return validators, nil
+}
+
+// promptInput asks for a value until it passes every validator, showing why
+// a rejected value was invalid. A non-empty example is offered as a tab
+// completion.
+func promptInput(message, help, example, defaultValue string, validators ...survey.Validator) (string, error) {
 	prompt := &survey.Input{
 		Message: message,
 		Default: defaultValue,
+		Help:    help,
 	}
-	if err := survey.AskOne(prompt, &result); err != nil {
-		return "", err
+	if example != "" {
+		prompt.Suggest = func(string) []string { return []string{example} }
 	}
-	return result, nil
+	return askUntilValid(prompt, "", validators)
+}
+
+// promptSecret asks for a value with masked input. An empty answer keeps the
+// default, which is never displayed.
+func promptSecret(message, help, defaultValue string, validators ...survey.Validator) (string, error) {
+	return askUntilValid(&survey.Password{Message: message, Help: help}, defaultValue, validators)
+}
+
+// askUntilValid repeats prompt until the answer, or fallback when the answer
+// is empty, passes every validator
+func askUntilValid(prompt survey.Prompt, fallback string, validators []survey.Validator) (string, error) {
+	for {
+		var result string
+		if err := askOne(prompt, &result); err != nil {
+			return "", err
+		}
+		if result == "" {
+			result = fallback
+		}
+
+		if err := validate(result, validators); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			continue
+		}
+		return result, nil
+	}
+}
+
+func validate(value string, validators []survey.Validator) error {
+	for _, validator := range validators {
+		if err := validator(value); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func promptSelect(message string, options []string, defaultValue string) (string, error) {
+func promptSelect(message, help string, options []string, defaultValue string) (string, error) {
 	if len(options) == 0 {
-		return promptInput(message, defaultValue)
+		return promptInput(message, help, "", defaultValue)
 	}
 
 	var result string
@@ -73,20 +248,38 @@ func promptSelect(message string, options []string, defaultValue string) (string
 		Message: message,
 		Options: options,
 		Default: defaultValue,
+		Help:    help,
 	}
-	if err := survey.AskOne(prompt, &result); err != nil {
+	if err := askOne(prompt, &result); err != nil {
 		return "", err
 	}
 	return result, nil
 }
 
-func promptConfirm(message string, defaultValue bool) (string, error) {
+// promptMultiSelect asks for any number of options, returned comma-separated.
+// The default is a comma-separated list too.
+func promptMultiSelect(message, help string, options []string, defaultValue string) (string, error) {
+	var result []string
+	prompt := &survey.MultiSelect{
+		Message: message,
+		Options: options,
+		Default: config.SplitList(defaultValue),
+		Help:    help,
+	}
+	if err := askOne(prompt, &result); err != nil {
+		return "", err
+	}
+	return strings.Join(result, ","), nil
+}
+
+func promptConfirm(message, help string, defaultValue bool) (string, error) {
 	var result bool
 	prompt := &survey.Confirm{
 		Message: message,
 		Default: defaultValue,
+		Help:    help,
 	}
-	if err := survey.AskOne(prompt, &result); err != nil {
+	if err := askOne(prompt, &result); err != nil {
 		return "", err
 	}
 	if result {
@@ -101,23 +294,27 @@ func PromptForTemplate(templates map[string]string) (string, error) {
 		return "", fmt.Errorf("no templates available")
 	}
 
-	options := make([]string, 0, len(templates))
-	for name, desc := range templates {
-		options = append(options, fmt.Sprintf("%s - %s", name, desc))
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	var result string
+	// The selection is mapped back by index, so labels are never parsed
+	options := make([]string, len(names))
+	for i, name := range names {
+		options[i] = fmt.Sprintf("%s - %s", name, templates[name])
+	}
+
+	var selected int
 	prompt := &survey.Select{
 		Message: "Select a template:",
 		Options: options,
 	}
-	if err := survey.AskOne(prompt, &result); err != nil {
+	if err := askOne(prompt, &selected); err != nil {
 		return "", err
 	}
-
-	// Extract template name from selection
-	parts := strings.SplitN(result, " - ", 2)
-	return parts[0], nil
+	return names[selected], nil
 }
 
 // PromptForProjectName prompts for project name if not provided
@@ -126,9 +323,8 @@ func PromptForProjectName() (string, error) {
 	prompt := &survey.Input{
 		Message: "Project name:",
 	}
-	if err := survey.AskOne(prompt, &result, survey.WithValidator(survey.Required)); err != nil {
+	if err := askOne(prompt, &result, survey.WithValidator(survey.Required)); err != nil {
 		return "", err
 	}
 	return result, nil
 }
-
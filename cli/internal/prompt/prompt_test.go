@@ -0,0 +1,423 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/makemore/scaffold/internal/config"
+)
+
+// stubAsk replaces askOne with a stub that answers prompts by message and
+// returns the list of messages asked. A []string answer is consumed one
+// element per prompt.
+func stubAsk(t *testing.T, answers map[string]interface{}) *[]string {
+	t.Helper()
+
+	asked := []string{}
+	orig := askOne
+	askOne = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		msg := promptMessage(p)
+		asked = append(asked, msg)
+
+		answer, ok := answers[msg]
+		if !ok {
+			t.Fatalf("unexpected prompt %q", msg)
+		}
+		// A list answers repeated prompts in turn
+		if seq, ok := answer.([]string); ok {
+			if len(seq) == 0 {
+				t.Fatalf("prompt %q asked too many times", msg)
+			}
+			answer, answers[msg] = seq[0], seq[1:]
+		}
+		switch r := response.(type) {
+		case *string:
+			*r = answer.(string)
+		case *bool:
+			*r = answer.(bool)
+		case *int:
+			*r = answer.(int)
+		}
+		return nil
+	}
+	t.Cleanup(func() { askOne = orig })
+
+	return &asked
+}
+
+func promptMessage(p survey.Prompt) string {
+	switch p := p.(type) {
+	case *survey.Input:
+		return p.Message
+	case *survey.Select:
+		return p.Message
+	case *survey.Confirm:
+		return p.Message
+	case *survey.Password:
+		return p.Message
+	case *survey.MultiSelect:
+		return p.Message
+	}
+	return ""
+}
+
+func TestGroupVariables(t *testing.T) {
+	vars := []config.Variable{
+		{Name: "project_name"},
+		{Name: "database", Group: "Database"},
+		{Name: "region", Group: "Deployment"},
+		{Name: "db_user", Group: "Database"},
+		{Name: "author"},
+		{Name: "db_password", Group: "Database"},
+	}
+
+	groups := GroupVariables(vars)
+
+	want := []struct {
+		name  string
+		names []string
+	}{
+		{"", []string{"project_name", "author"}},
+		{"Database", []string{"database", "db_user", "db_password"}},
+		{"Deployment", []string{"region"}},
+	}
+
+	if len(groups) != len(want) {
+		t.Fatalf("len(groups) = %d, want %d", len(groups), len(want))
+	}
+
+	for i, w := range want {
+		if groups[i].Name != w.name {
+			t.Errorf("groups[%d].Name = %q, want %q", i, groups[i].Name, w.name)
+		}
+		if len(groups[i].Variables) != len(w.names) {
+			t.Errorf("len(groups[%d].Variables) = %d, want %d", i, len(groups[i].Variables), len(w.names))
+			continue
+		}
+		for j, name := range w.names {
+			if groups[i].Variables[j].Name != name {
+				t.Errorf("groups[%d].Variables[%d] = %v, want %v", i, j, groups[i].Variables[j].Name, name)
+			}
+		}
+	}
+}
+
+func TestGroupVariables_Empty(t *testing.T) {
+	if groups := GroupVariables(nil); len(groups) != 0 {
+		t.Errorf("GroupVariables(nil) = %v, want empty", groups)
+	}
+}
+
+func TestPromptForVariables_ShowIfChain(t *testing.T) {
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "database", Type: "choice", Choices: []string{"postgres", "none"}},
+			{Name: "db_password", ShowIf: "database != none"},
+			{Name: "db_backup", Type: "confirm", ShowIf: `db_password != ""`},
+			{Name: "db_backup_bucket", ShowIf: "db_backup", Default: "backups"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		answers   map[string]interface{}
+		wantAsked []string
+		want      map[string]string
+	}{
+		{
+			name: "all enabled",
+			answers: map[string]interface{}{
+				"database":         "postgres",
+				"db_password":      "secret",
+				"db_backup":        true,
+				"db_backup_bucket": "nightly",
+			},
+			wantAsked: []string{"database", "db_password", "db_backup", "db_backup_bucket"},
+			want: map[string]string{
+				"database":         "postgres",
+				"db_password":      "secret",
+				"db_backup":        "true",
+				"db_backup_bucket": "nightly",
+			},
+		},
+		{
+			name: "backup disabled",
+			answers: map[string]interface{}{
+				"database":    "postgres",
+				"db_password": "secret",
+				"db_backup":   false,
+			},
+			wantAsked: []string{"database", "db_password", "db_backup"},
+			want: map[string]string{
+				"database":         "postgres",
+				"db_password":      "secret",
+				"db_backup":        "false",
+				"db_backup_bucket": "backups",
+			},
+		},
+		{
+			name: "no database",
+			answers: map[string]interface{}{
+				"database": "none",
+			},
+			wantAsked: []string{"database"},
+			want: map[string]string{
+				"database":         "none",
+				"db_backup_bucket": "backups",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := stubAsk(t, tt.answers)
+
+			vars, err := PromptForVariables(manifest, nil)
+			if err != nil {
+				t.Fatalf("PromptForVariables() error = %v", err)
+			}
+			if !reflect.DeepEqual(*asked, tt.wantAsked) {
+				t.Errorf("asked = %v, want %v", *asked, tt.wantAsked)
+			}
+			if !reflect.DeepEqual(vars, tt.want) {
+				t.Errorf("vars = %v, want %v", vars, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptForVariables_InvalidShowIf(t *testing.T) {
+	stubAsk(t, nil)
+
+	manifest := &config.Manifest{
+		Variables: []config.Variable{{Name: "broken", ShowIf: "database =="}},
+	}
+	if _, err := PromptForVariables(manifest, nil); err == nil {
+		t.Error("PromptForVariables() should return error for invalid show_if")
+	}
+}
+
+func TestPromptForVariables_ReasksUntilValid(t *testing.T) {
+	asked := stubAsk(t, map[string]interface{}{
+		"Package name": []string{"", "My Package", "my-package"},
+		"Port":         []string{"eighty", "8080"},
+	})
+
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "package", Description: "Package name", Required: true, Pattern: "[a-z][a-z0-9-]*"},
+			{Name: "port", Description: "Port", Type: "int"},
+		},
+	}
+
+	got, err := PromptForVariables(manifest, map[string]string{})
+	if err != nil {
+		t.Fatalf("PromptForVariables() error = %v", err)
+	}
+
+	if got["package"] != "my-package" || got["port"] != "8080" {
+		t.Errorf("PromptForVariables() = %v, want the first valid answers", got)
+	}
+	if len(*asked) != 5 {
+		t.Errorf("asked %d times, want 5 (3 for package, 2 for port)", len(*asked))
+	}
+}
+
+func TestPromptForVariables_InterpolatedDefault(t *testing.T) {
+	var defaults []string
+	orig := askOne
+	askOne = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		input := p.(*survey.Input)
+		defaults = append(defaults, input.Default)
+		*response.(*string) = input.Default
+		return nil
+	}
+	t.Cleanup(func() { askOne = orig })
+
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "org"},
+			{Name: "repo", Default: "github.com/{{ org }}/{{ project_slug }}"},
+		},
+	}
+	got, err := PromptForVariables(manifest, map[string]string{"project_slug": "my_app", "org": "acme"})
+	if err != nil {
+		t.Fatalf("PromptForVariables() error = %v", err)
+	}
+	if got["repo"] != "github.com/acme/my_app" {
+		t.Errorf("repo = %q, want github.com/acme/my_app", got["repo"])
+	}
+	if !reflect.DeepEqual(defaults, []string{"github.com/acme/my_app"}) {
+		t.Errorf("prompt defaults = %v, want the rendered default", defaults)
+	}
+
+	manifest.Variables = []config.Variable{{Name: "db", Default: "{{ region }}-db"}}
+	if _, err := PromptForVariables(manifest, map[string]string{}); err == nil {
+		t.Error("PromptForVariables() should return error for an unresolved default")
+	}
+}
+
+func TestPromptForTemplate_DescriptionWithSeparator(t *testing.T) {
+	stubAsk(t, map[string]interface{}{"Select a template:": 1})
+
+	templates := map[string]string{
+		"django": "Django - REST API with auth",
+		"api":    "Minimal API",
+		"nextjs": "Next.js - TypeScript - Tailwind",
+	}
+	got, err := PromptForTemplate(templates)
+	if err != nil {
+		t.Fatalf("PromptForTemplate() error = %v", err)
+	}
+	if got != "django" {
+		t.Errorf("PromptForTemplate() = %q, want django", got)
+	}
+}
+
+func TestPromptForVariables_Secret(t *testing.T) {
+	var masked []string
+	orig := askOne
+	askOne = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		if pw, ok := p.(*survey.Password); ok {
+			masked = append(masked, pw.Message)
+		}
+		*response.(*string) = ""
+		return nil
+	}
+	t.Cleanup(func() { askOne = orig })
+
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "api_key", Secret: true, Default: "dev-key"},
+			{Name: "region", Default: "eu"},
+		},
+	}
+	got, err := PromptForVariables(manifest, map[string]string{})
+	if err != nil {
+		t.Fatalf("PromptForVariables() error = %v", err)
+	}
+	if !reflect.DeepEqual(masked, []string{"api_key"}) {
+		t.Errorf("masked prompts = %v, want only api_key", masked)
+	}
+	if got["api_key"] != "dev-key" {
+		t.Errorf("api_key = %q, want the default for an empty answer", got["api_key"])
+	}
+}
+
+func TestPromptForVariables_Help(t *testing.T) {
+	prompts := make(map[string]survey.Prompt)
+	orig := askOne
+	askOne = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		prompts[promptMessage(p)] = p
+		switch r := response.(type) {
+		case *string:
+			*r = "x"
+		case *bool:
+			*r = true
+		}
+		return nil
+	}
+	t.Cleanup(func() { askOne = orig })
+
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "module", Description: "Go module path", Help: "Used in go.mod and imports.", Example: "github.com/acme/api"},
+			{Name: "region", Type: "choice", Choices: []string{"x", "y"}, Help: "Where to deploy."},
+			{Name: "docker", Type: "confirm", Example: "true"},
+			{Name: "plain"},
+		},
+	}
+	if _, err := PromptForVariables(manifest, map[string]string{}); err != nil {
+		t.Fatalf("PromptForVariables() error = %v", err)
+	}
+
+	input, ok := prompts["Go module path"].(*survey.Input)
+	if !ok {
+		t.Fatalf("module prompt = %T, want *survey.Input with the description as message", prompts["Go module path"])
+	}
+	if want := "Used in go.mod and imports.\nExample: github.com/acme/api"; input.Help != want {
+		t.Errorf("module Help = %q, want %q", input.Help, want)
+	}
+	if input.Suggest == nil || !reflect.DeepEqual(input.Suggest(""), []string{"github.com/acme/api"}) {
+		t.Error("module prompt should suggest the example")
+	}
+	if got := prompts["region"].(*survey.Select).Help; got != "Where to deploy." {
+		t.Errorf("region Help = %q, want the help text", got)
+	}
+	if got := prompts["docker"].(*survey.Confirm).Help; got != "Example: true" {
+		t.Errorf("docker Help = %q, want the example", got)
+	}
+	plain := prompts["plain"].(*survey.Input)
+	if plain.Help != "" || plain.Suggest != nil {
+		t.Errorf("plain prompt has help %q or a suggestion, want neither", plain.Help)
+	}
+}
+
+func TestValidators(t *testing.T) {
+	v := config.Variable{Name: "slug", Required: true, Pattern: "[a-z_]+"}
+	validators, err := Validators(v)
+	if err != nil {
+		t.Fatalf("Validators() error = %v", err)
+	}
+
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"my_app", false},
+		{"", true},
+		{"my-app", true},
+		{"prefix my_app", true},
+	}
+	for _, tt := range tests {
+		if err := validate(tt.value, validators); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+
+	if _, err := Validators(config.Variable{Name: "x", Pattern: "("}); err == nil {
+		t.Error("Validators() should return error for an invalid pattern")
+	}
+}
+
+func TestPromptForVariables_List(t *testing.T) {
+	prompts := make(map[string]survey.Prompt)
+	orig := askOne
+	askOne = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		prompts[promptMessage(p)] = p
+		switch r := response.(type) {
+		case *string:
+			*r = "api, web"
+		case *[]string:
+			*r = []string{"redis", "postgres"}
+		}
+		return nil
+	}
+	t.Cleanup(func() { askOne = orig })
+
+	manifest := &config.Manifest{
+		Variables: []config.Variable{
+			{Name: "services", Type: "list"},
+			{Name: "stores", Type: "multiselect", Choices: []string{"postgres", "redis", "s3"}, Default: "postgres, s3"},
+		},
+	}
+	got, err := PromptForVariables(manifest, map[string]string{})
+	if err != nil {
+		t.Fatalf("PromptForVariables() error = %v", err)
+	}
+
+	if got["services"] != "api, web" || got["stores"] != "redis,postgres" {
+		t.Errorf("values = %v, want the list answers comma-separated", got)
+	}
+	if _, ok := prompts["services (comma-separated)"].(*survey.Input); !ok {
+		t.Errorf("services prompt = %v, want a comma-separated input", prompts)
+	}
+	multi, ok := prompts["stores"].(*survey.MultiSelect)
+	if !ok {
+		t.Fatalf("stores prompt = %T, want *survey.MultiSelect", prompts["stores"])
+	}
+	if !reflect.DeepEqual(multi.Default, []string{"postgres", "s3"}) {
+		t.Errorf("stores Default = %v, want the split default", multi.Default)
+	}
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/makemore/scaffold/internal/oci"
+)
+
+// IndexMediaType marks the layer holding the index in an OCI artifact. An
+// artifact with a single layer may use any media type.
+const IndexMediaType = "application/vnd.scaffold.index.v1+yaml"
+
+// ociGet pulls the index layer of an OCI artifact, retrying network errors
+// and server errors
+func (r *Registry) ociGet(ctx context.Context, source string) ([]byte, error) {
+	ref, err := oci.ParseReference(source)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := r.Retry
+	policy.OnRetry = func(err error, wait time.Duration) {
+		r.Log.Debug("fetching %s failed, retrying in %s: %v", source, wait.Round(time.Millisecond), err)
+	}
+
+	client := oci.NewClient(r.timeout(), r.Log)
+	var data []byte
+	err = policy.Do(ctx, func() error {
+		manifest, _, err := client.Manifest(ctx, ref)
+		if err != nil {
+			return err
+		}
+		layer, err := indexLayer(manifest)
+		if err != nil {
+			return err
+		}
+		data, err = client.Blob(ctx, ref, layer.Digest)
+		return err
+	})
+	return data, err
+}
+
+// indexLayer picks the layer of type IndexMediaType, or the only layer
+func indexLayer(manifest *oci.Manifest) (oci.Descriptor, error) {
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == IndexMediaType {
+			return layer, nil
+		}
+	}
+	if len(manifest.Layers) == 1 {
+		return manifest.Layers[0], nil
+	}
+	return oci.Descriptor{}, fmt.Errorf("artifact has %d layers and none of type %s", len(manifest.Layers), IndexMediaType)
+}
@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/oci"
+)
+
+// stubRegistry serves an index as a single-layer OCI artifact behind token
+// authentication for user alice
+func stubRegistry(t *testing.T, index string, layerType string) *httptest.Server {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(index))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        []map[string]string{{"mediaType": layerType, "digest": digest}},
+	})
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, password, _ := r.BasicAuth()
+			if user != "alice" || password != "s3cret" || r.URL.Query().Get("scope") != "repository:acme/index:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token": "tok"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="stub",scope="repository:acme/index:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/acme/index/manifests/v1":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(manifest)
+		case "/v2/acme/index/blobs/" + digest:
+			fmt.Fprint(w, index)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// useDockerConfig points DOCKER_CONFIG at a config.json with the given
+// content
+func useDockerConfig(t *testing.T, content string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write docker config: %v", err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+}
+
+func TestRegistry_OCIIndex(t *testing.T) {
+	t.Setenv("SCAFFOLD_INDEX", filepath.Join(t.TempDir(), "missing.yaml"))
+	server := stubRegistry(t, `
+version: "1"
+community:
+  acme-go:
+    source: "git:https://git.acme.internal/go-service"
+    description: "Acme Go service"
+`, IndexMediaType)
+	host := strings.TrimPrefix(server.URL, "http://")
+	useDockerConfig(t, `{"auths": {"`+host+`": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))+`"}}}`)
+
+	reg := newRegistry(t, t.TempDir())
+	reg.AddIndex(IndexSource{URL: oci.Scheme + host + "/acme/index:v1"})
+
+	got, err := reg.Resolve(context.Background(), "acme-go")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "git:https://git.acme.internal/go-service" {
+		t.Errorf("Resolve() = %q, want the entry from the OCI index", got)
+	}
+}
+
+func TestRegistry_OCIIndexErrors(t *testing.T) {
+	server := stubRegistry(t, "version: \"1\"\n", "application/octet-stream")
+	host := strings.TrimPrefix(server.URL, "http://")
+	login := `{"auths": {"` + host + `": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("alice:s3cret")) + `"}}}`
+
+	tests := []struct {
+		name    string
+		source  string
+		config  string
+		wantErr string
+	}{
+		{"no credentials", oci.Scheme + host + "/acme/index:v1", `{}`, "token request"},
+		{"unknown tag", oci.Scheme + host + "/acme/index:v2", login, "HTTP 404"},
+		{"single layer of any type", oci.Scheme + host + "/acme/index:v1", login, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDockerConfig(t, tt.config)
+			reg := newRegistry(t, t.TempDir())
+			reg.Retry.Attempts = 1
+
+			_, err := reg.ociGet(context.Background(), tt.source)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ociGet() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ociGet() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Cancelling the caller's context stops the pull
+	useDockerConfig(t, login)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := newRegistry(t, t.TempDir()).ociGet(ctx, oci.Scheme+host+"/acme/index:v1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ociGet() error = %v, want %v", err, context.Canceled)
+	}
+}
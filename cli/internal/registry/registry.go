@@ -2,14 +2,24 @@
 package registry
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/oci"
+	"github.com/makemore/scaffold/internal/retry"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,6 +31,11 @@ const (
 	RemoteIndexURL = "https://raw.githubusercontent.com/scaffold-dev/scaffold/main/templates.yaml"
 	// CacheExpiry is how long to cache the remote index
 	CacheExpiry = 24 * time.Hour
+	// DefaultTimeout bounds each index download
+	DefaultTimeout = 10 * time.Second
+	// CacheSubdir is where indexes are cached within the scaffold cache, apart
+	// from fetched templates
+	CacheSubdir = "indexes"
 )
 
 // Index represents the templates.yaml structure
@@ -35,27 +50,103 @@ type Index struct {
 type TemplateEntry struct {
 	Source      string `yaml:"source"`
 	Description string `yaml:"description"`
+	Version     string `yaml:"version,omitempty"` // Latest release or recommended ref
+	// Tags categorize the template; the first is its primary category
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// HasTags reports whether the entry carries every one of tags, ignoring case
+func (e TemplateEntry) HasTags(tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range e.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Category returns the entry's primary category, its first tag
+func (e TemplateEntry) Category() string {
+	if len(e.Tags) == 0 {
+		return ""
+	}
+	return e.Tags[0]
+}
+
+// ExtraIndexesEnv lists additional index URLs, comma-separated
+const ExtraIndexesEnv = "SCAFFOLD_EXTRA_INDEXES"
+
+// IndexSource is a template index to merge into the registry
+type IndexSource struct {
+	URL      string        // http(s) URL, oci:// reference or local path
+	TTL      time.Duration // How long the cached copy is used, CacheExpiry when 0
+	Override bool          // Replace existing entries of the same name
 }
 
 // Registry manages template lookups
 type Registry struct {
-	index    *Index
-	cacheDir string
+	index     *Index
+	cacheDir  string
+	sources   []IndexSource     // Extra indexes, merged after the official one
+	origins   map[string]string // URL of the extra index each merged template came from
+	remoteURL string
+	// PublicKey verifies the remote official index when set
+	PublicKey ed25519.PublicKey
+	Log       *logger.Logger
+	Retry     retry.Policy  // Retries for index downloads
+	Timeout   time.Duration // Per-request timeout, DefaultTimeout when 0
+	// NoEmbeddedFallback fails when neither the cache nor the remote has the
+	// official index, instead of using the copy built into the binary
+	NoEmbeddedFallback bool
+	// NoCache fetches the indexes again whatever their TTL. The fresh copies
+	// are still cached, and an extra index that fails falls back to its
+	// cached copy.
+	NoCache bool
 }
 
-// New creates a new Registry
-func New(cacheDir string) *Registry {
+// New creates a new Registry. Indexes listed in SCAFFOLD_EXTRA_INDEXES are
+// merged after the official index. It fails when the PinnedPublicKey set at
+// build time is invalid.
+func New(cacheDir string) (*Registry, error) {
 	if cacheDir == "" {
 		home, _ := os.UserHomeDir()
-		cacheDir = filepath.Join(home, ".scaffold", "cache")
+		cacheDir = filepath.Join(home, ".scaffold", "cache", CacheSubdir)
 	}
-	return &Registry{cacheDir: cacheDir}
+
+	r := &Registry{cacheDir: cacheDir, remoteURL: RemoteIndexURL, Retry: retry.DefaultPolicy()}
+	if PinnedPublicKey != "" {
+		key, err := ParsePublicKey(PinnedPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the pinned key: %w", err)
+		}
+		r.PublicKey = key
+	}
+	for _, url := range strings.Split(os.Getenv(ExtraIndexesEnv), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			r.AddIndex(IndexSource{URL: url})
+		}
+	}
+	return r, nil
+}
+
+// AddIndex adds an index to merge after those already added. Indexes can add
+// templates and aliases but only replace existing ones with Override set.
+func (r *Registry) AddIndex(src IndexSource) {
+	r.sources = append(r.sources, src)
+	r.index = nil
 }
 
 // Resolve looks up a shorthand name and returns the full source URI
 // Returns the original name if not found (allows pass-through of full URIs)
-func (r *Registry) Resolve(name string) (string, error) {
-	if err := r.ensureLoaded(); err != nil {
+func (r *Registry) Resolve(ctx context.Context, name string) (string, error) {
+	if err := r.ensureLoaded(ctx); err != nil {
 		return name, nil // Fall back to treating as URI
 	}
 
@@ -78,27 +169,121 @@ func (r *Registry) Resolve(name string) (string, error) {
 	return name, nil
 }
 
-// List returns all available templates
-func (r *Registry) List() (map[string]TemplateEntry, error) {
-	if err := r.ensureLoaded(); err != nil {
+// List returns all available templates carrying every given tag. Official
+// templates win over community templates of the same name.
+func (r *Registry) List(ctx context.Context, tags ...string) (map[string]TemplateEntry, error) {
+	templates, err := r.ListDetailed(ctx, tags...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]TemplateEntry, len(templates))
+	for _, t := range templates {
+		result[t.Name] = t.TemplateEntry
+	}
+	return result, nil
+}
+
+// Names returns the names of all templates in the index along with their
+// aliases, sorted
+func (r *Registry) Names(ctx context.Context) ([]string, error) {
+	if err := r.ensureLoaded(ctx); err != nil {
 		return nil, err
 	}
 
-	result := make(map[string]TemplateEntry)
-	for k, v := range r.index.Official {
-		result[k] = v
+	seen := make(map[string]bool)
+	for _, names := range []map[string]TemplateEntry{r.index.Official, r.index.Community} {
+		for name := range names {
+			seen[name] = true
+		}
+	}
+	for alias := range r.index.Aliases {
+		seen[alias] = true
 	}
-	for k, v := range r.index.Community {
-		result[k] = v
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Template origins
+const (
+	OriginOfficial  = "official"
+	OriginCommunity = "community"
+)
+
+// ListedTemplate is a template in the index along with where it is listed
+type ListedTemplate struct {
+	Name string
+	TemplateEntry
+	// Origin is OriginOfficial or OriginCommunity for the official index,
+	// and the URL of the index for a template from an extra index, whichever
+	// section it is listed in
+	Origin string
+}
+
+// ListDetailed returns all available templates carrying every given tag,
+// sorted by name and annotated with their origin. When a name is listed as
+// both official and community, the official entry wins and a warning is
+// logged.
+func (r *Registry) ListDetailed(ctx context.Context, tags ...string) ([]ListedTemplate, error) {
+	if err := r.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]ListedTemplate)
+	for name, entry := range r.index.Official {
+		byName[name] = ListedTemplate{Name: name, TemplateEntry: entry, Origin: OriginOfficial}
+	}
+	for name, entry := range r.index.Community {
+		if _, ok := byName[name]; ok {
+			r.Log.Warn("community template %s is shadowed by the official template of the same name", name)
+			continue
+		}
+		byName[name] = ListedTemplate{Name: name, TemplateEntry: entry, Origin: OriginCommunity}
+	}
+
+	result := make([]ListedTemplate, 0, len(byName))
+	for _, t := range byName {
+		if url, ok := r.origins[t.Name]; ok {
+			t.Origin = url
+		}
+		if t.HasTags(tags) {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
 	return result, nil
 }
 
-func (r *Registry) ensureLoaded() error {
+func (r *Registry) ensureLoaded(ctx context.Context) error {
 	if r.index != nil {
 		return nil
 	}
 
+	if err := r.loadOfficial(ctx); err != nil {
+		return err
+	}
+	r.origins = make(map[string]string)
+
+	for _, src := range r.sources {
+		idx, err := r.loadIndex(ctx, src)
+		if err != nil {
+			r.Log.Warn("skipping template index %s: %v", src.URL, err)
+			continue
+		}
+		r.merge(idx, src)
+	}
+	return nil
+}
+
+// loadOfficial loads the official index from the development override, the
+// cache, the remote or the embedded copy, in that order. The cache is skipped
+// with NoCache and the embedded copy with NoEmbeddedFallback.
+func (r *Registry) loadOfficial(ctx context.Context) error {
 	// Check for local index override (for development)
 	if localPath := os.Getenv("SCAFFOLD_INDEX"); localPath != "" {
 		if idx, err := r.loadFromFile(localPath); err == nil {
@@ -108,22 +293,188 @@ func (r *Registry) ensureLoaded() error {
 	}
 
 	// Try to load from cache first
-	if idx, err := r.loadFromCache(); err == nil {
-		r.index = idx
-		return nil
+	if !r.NoCache {
+		if idx, err := r.loadFromCache(); err == nil {
+			r.index = idx
+			return nil
+		}
 	}
 
 	// Try to fetch from remote
-	if idx, err := r.fetchRemote(); err == nil {
+	idx, err := r.fetchRemote(ctx)
+	if err == nil {
 		r.index = idx
 		_ = r.saveToCache(idx)
 		return nil
 	}
+	if r.NoEmbeddedFallback {
+		return fmt.Errorf("failed to load the template index (embedded fallback disabled): %w", err)
+	}
+	if errors.Is(err, ErrBadSignature) {
+		r.Log.Error("🚨 Refusing the remote template index: %v. Using the built-in index instead.", err)
+	}
 
 	// Fall back to embedded index
 	return r.loadEmbedded()
 }
 
+// loadIndex loads an extra index, using its cached copy within the TTL and
+// a stale copy if fetching fails
+func (r *Registry) loadIndex(ctx context.Context, src IndexSource) (*Index, error) {
+	ttl := src.TTL
+	if ttl == 0 {
+		ttl = CacheExpiry
+	}
+	cachePath := r.indexCachePath(src.URL)
+
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) <= ttl && !r.NoCache {
+		if idx, err := r.loadFromFile(cachePath); err == nil {
+			return idx, nil
+		}
+	}
+
+	data, err := r.readIndex(ctx, src.URL)
+	if err == nil {
+		var idx Index
+		if err = yaml.Unmarshal(data, &idx); err == nil {
+			if os.MkdirAll(r.cacheDir, 0755) == nil {
+				_ = os.WriteFile(cachePath, data, 0644)
+			}
+			return &idx, nil
+		}
+	}
+
+	if idx, staleErr := r.loadFromFile(cachePath); staleErr == nil {
+		r.Log.Warn("using cached copy of template index %s: %v", src.URL, err)
+		return idx, nil
+	}
+	return nil, err
+}
+
+// indexCachePath returns where an extra index is cached
+func (r *Registry) indexCachePath(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(r.cacheDir, "index-"+hex.EncodeToString(sum[:])[:16]+".yaml")
+}
+
+// readIndex reads an index from an http(s) URL, an OCI artifact or a local
+// path
+func (r *Registry) readIndex(ctx context.Context, url string) ([]byte, error) {
+	if strings.HasPrefix(url, oci.Scheme) {
+		return r.ociGet(ctx, url)
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return os.ReadFile(strings.TrimPrefix(url, "file:"))
+	}
+	return r.httpGet(ctx, url)
+}
+
+// httpGet downloads url, retrying network errors and server errors
+func (r *Registry) httpGet(ctx context.Context, url string) ([]byte, error) {
+	client := &http.Client{Timeout: r.timeout()}
+
+	policy := r.Retry
+	policy.OnRetry = func(err error, wait time.Duration) {
+		r.Log.Debug("fetching %s failed, retrying in %s: %v", url, wait.Round(time.Millisecond), err)
+	}
+
+	var data []byte
+	err := policy.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("HTTP %d", resp.StatusCode)
+			if resp.StatusCode >= 500 {
+				return retry.Transient(err)
+			}
+			return err
+		}
+		data, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return data, err
+}
+
+// timeout returns the per-request timeout for index downloads
+func (r *Registry) timeout() time.Duration {
+	if r.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return r.Timeout
+}
+
+// merge adds the entries of idx to the registry's index, recording src as
+// their origin. Names and aliases that already exist are only replaced when
+// the source allows overriding, and are otherwise ignored with a warning.
+func (r *Registry) merge(idx *Index, src IndexSource) {
+	if r.index.Official == nil {
+		r.index.Official = make(map[string]TemplateEntry)
+	}
+	if r.index.Community == nil {
+		r.index.Community = make(map[string]TemplateEntry)
+	}
+	if r.index.Aliases == nil {
+		r.index.Aliases = make(map[string]string)
+	}
+
+	exists := func(name string) bool {
+		_, official := r.index.Official[name]
+		_, community := r.index.Community[name]
+		return official || community
+	}
+
+	for _, bucket := range []struct {
+		from, to map[string]TemplateEntry
+	}{
+		{idx.Official, r.index.Official},
+		{idx.Community, r.index.Community},
+	} {
+		for _, name := range sortedKeys(bucket.from) {
+			if exists(name) {
+				if !src.Override {
+					r.Log.Warn("template %s from index %s is already defined; ignoring it (set override to replace)", name, src.URL)
+					continue
+				}
+				delete(r.index.Official, name)
+				delete(r.index.Community, name)
+			}
+			bucket.to[name] = bucket.from[name]
+			r.origins[name] = src.URL
+		}
+	}
+
+	aliases := make([]string, 0, len(idx.Aliases))
+	for alias := range idx.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		target := idx.Aliases[alias]
+		if existing, ok := r.index.Aliases[alias]; ok && existing != target && !src.Override {
+			r.Log.Warn("alias %s from index %s is already defined for %s; ignoring it (set override to replace)", alias, src.URL, existing)
+			continue
+		}
+		r.index.Aliases[alias] = target
+	}
+}
+
+func sortedKeys(m map[string]TemplateEntry) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (r *Registry) loadFromFile(path string) (*Index, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -161,21 +512,22 @@ func (r *Registry) loadFromCache() (*Index, error) {
 	return &idx, nil
 }
 
-func (r *Registry) fetchRemote() (*Index, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(RemoteIndexURL)
+// fetchRemote downloads the official index, verifying its signature when a
+// public key is set
+func (r *Registry) fetchRemote(ctx context.Context) (*Index, error) {
+	data, err := r.httpGet(ctx, r.remoteURL)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
-	}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if r.PublicKey != nil {
+		signature, err := r.httpGet(ctx, r.remoteURL+SignatureSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to fetch signature: %v", ErrBadSignature, err)
+		}
+		if err := verifyIndex(data, signature, r.PublicKey); err != nil {
+			return nil, err
+		}
 	}
 
 	var idx Index
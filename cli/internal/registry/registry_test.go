@@ -1,11 +1,32 @@
 package registry
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/makemore/scaffold/internal/logger"
 )
 
+// newRegistry creates a Registry caching in cacheDir
+func newRegistry(t *testing.T, cacheDir string) *Registry {
+	t.Helper()
+	reg, err := New(cacheDir)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return reg
+}
+
 func TestRegistry_Resolve(t *testing.T) {
 	// Create a temporary index file
 	tmpDir, err := os.MkdirTemp("", "scaffold-registry-test")
@@ -36,7 +57,7 @@ aliases:
 	os.Setenv("SCAFFOLD_INDEX", indexPath)
 	defer os.Unsetenv("SCAFFOLD_INDEX")
 
-	reg := New(tmpDir)
+	reg := newRegistry(t, tmpDir)
 
 	tests := []struct {
 		name    string
@@ -78,7 +99,7 @@ aliases:
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := reg.Resolve(tt.input)
+			got, err := reg.Resolve(context.Background(), tt.input)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -115,8 +136,8 @@ official:
 	os.Setenv("SCAFFOLD_INDEX", indexPath)
 	defer os.Unsetenv("SCAFFOLD_INDEX")
 
-	reg := New(tmpDir)
-	templates, err := reg.List()
+	reg := newRegistry(t, tmpDir)
+	templates, err := reg.List(context.Background())
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
@@ -134,3 +155,378 @@ official:
 	}
 }
 
+func TestRegistry_ListTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	indexPath := filepath.Join(tmpDir, "templates.yaml")
+	index := `
+version: "1"
+official:
+  django:
+    source: "github:org/django"
+    tags: [backend, python]
+  fastapi:
+    source: "github:org/fastapi"
+    tags: [backend, python, async]
+  nextjs:
+    source: "github:org/nextjs"
+    tags: [frontend, typescript]
+community:
+  express:
+    source: "github:someone/express"
+    tags: [Backend, node]
+  plain:
+    source: "github:someone/plain"
+`
+	if err := os.WriteFile(indexPath, []byte(index), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	t.Setenv("SCAFFOLD_INDEX", indexPath)
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "no filter", want: []string{"django", "express", "fastapi", "nextjs", "plain"}},
+		{name: "single tag", tags: []string{"backend"}, want: []string{"django", "express", "fastapi"}},
+		{name: "all tags required", tags: []string{"backend", "python"}, want: []string{"django", "fastapi"}},
+		{name: "three tags", tags: []string{"python", "async", "backend"}, want: []string{"fastapi"}},
+		{name: "no match", tags: []string{"frontend", "python"}, want: []string{}},
+	}
+
+	reg := newRegistry(t, tmpDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := reg.ListDetailed(context.Background(), tt.tags...)
+			if err != nil {
+				t.Fatalf("ListDetailed() error = %v", err)
+			}
+			got := []string{}
+			for _, tmpl := range templates {
+				got = append(got, tmpl.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListDetailed(%v) = %v, want %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+
+	listed, _ := reg.List(context.Background(), "node")
+	if len(listed) != 1 || listed["express"].Category() != "Backend" {
+		t.Errorf("List(node) = %v, want express with category Backend", listed)
+	}
+}
+
+func TestRegistry_ListDetailed(t *testing.T) {
+	tmpDir := t.TempDir()
+	indexContent := `
+version: "1"
+official:
+  django:
+    source: "github:makemore/scaffold//templates/django-base"
+    description: "Django REST API"
+community:
+  django:
+    source: "github:someone/django-fork"
+    description: "Unofficial Django"
+  astro:
+    source: "github:someone/astro-starter"
+    description: "Astro site"
+`
+	indexPath := filepath.Join(tmpDir, "templates.yaml")
+	if err := os.WriteFile(indexPath, []byte(indexContent), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	t.Setenv("SCAFFOLD_INDEX", indexPath)
+
+	var warnings bytes.Buffer
+	reg := newRegistry(t, tmpDir)
+	reg.Log = logger.New(io.Discard, &warnings, logger.Normal)
+
+	templates, err := reg.ListDetailed(context.Background())
+	if err != nil {
+		t.Fatalf("ListDetailed() error = %v", err)
+	}
+
+	want := []ListedTemplate{
+		{Name: "astro", TemplateEntry: TemplateEntry{Source: "github:someone/astro-starter", Description: "Astro site"}, Origin: OriginCommunity},
+		{Name: "django", TemplateEntry: TemplateEntry{Source: "github:makemore/scaffold//templates/django-base", Description: "Django REST API"}, Origin: OriginOfficial},
+	}
+	if !reflect.DeepEqual(templates, want) {
+		t.Errorf("ListDetailed() = %+v, want %+v", templates, want)
+	}
+	if !strings.Contains(warnings.String(), "django") {
+		t.Errorf("warnings = %q, want a warning about django", warnings.String())
+	}
+
+	// List agrees that the official entry wins
+	list, err := reg.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if list["django"].Description != "Django REST API" {
+		t.Errorf("List() django = %+v, want the official entry", list["django"])
+	}
+}
+
+func TestRegistry_ExtraIndexes(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write index: %v", err)
+		}
+		return path
+	}
+
+	t.Setenv("SCAFFOLD_INDEX", write("official.yaml", `
+version: "1"
+official:
+  django:
+    source: "github:makemore/scaffold//templates/django-base"
+    description: "Django REST API"
+aliases:
+  dj: django
+`))
+	internal := write("internal.yaml", `
+version: "1"
+official:
+  acme-rails:
+    source: "git:https://git.acme.internal/rails"
+    description: "Acme Rails"
+community:
+  django:
+    source: "git:https://git.acme.internal/django"
+    description: "Acme Django"
+  acme-go:
+    source: "git:https://git.acme.internal/go-service"
+    description: "Acme Go service"
+aliases:
+  dj: acme-go
+  go: acme-go
+`)
+	t.Setenv(ExtraIndexesEnv, internal)
+
+	var warnings bytes.Buffer
+	reg := newRegistry(t, filepath.Join(tmpDir, "cache"))
+	reg.Log = logger.New(io.Discard, &warnings, logger.Normal)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"django", "github:makemore/scaffold//templates/django-base"}, // collision keeps official
+		{"acme-go", "git:https://git.acme.internal/go-service"},       // added by the extra index
+		{"go", "git:https://git.acme.internal/go-service"},            // new alias
+		{"dj", "github:makemore/scaffold//templates/django-base"},     // existing alias kept
+	}
+	for _, tt := range tests {
+		if got, _ := reg.Resolve(context.Background(), tt.input); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if !strings.Contains(warnings.String(), "template django") {
+		t.Errorf("warnings = %q, want a collision warning for django", warnings.String())
+	}
+	if !strings.Contains(warnings.String(), "alias dj") {
+		t.Errorf("warnings = %q, want a collision warning for alias dj", warnings.String())
+	}
+
+	// Templates from an extra index have it as their origin, even in its
+	// official section
+	listed, err := reg.ListDetailed(context.Background())
+	if err != nil {
+		t.Fatalf("ListDetailed() error = %v", err)
+	}
+	origins := make(map[string]string)
+	for _, l := range listed {
+		origins[l.Name] = l.Origin
+	}
+	if want := map[string]string{"django": OriginOfficial, "acme-go": internal, "acme-rails": internal}; !reflect.DeepEqual(origins, want) {
+		t.Errorf("origins = %v, want %v", origins, want)
+	}
+
+	// With override the extra index replaces the official entry
+	t.Setenv(ExtraIndexesEnv, "")
+	reg = newRegistry(t, filepath.Join(tmpDir, "cache"))
+	reg.AddIndex(IndexSource{URL: internal, Override: true})
+	if got, _ := reg.Resolve(context.Background(), "django"); got != "git:https://git.acme.internal/django" {
+		t.Errorf("Resolve(django) with override = %q, want the internal entry", got)
+	}
+}
+
+func TestRegistry_ExtraIndexCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	officialPath := filepath.Join(tmpDir, "official.yaml")
+	if err := os.WriteFile(officialPath, []byte("version: \"1\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	t.Setenv("SCAFFOLD_INDEX", officialPath)
+
+	indexPath := filepath.Join(tmpDir, "extra.yaml")
+	if err := os.WriteFile(indexPath, []byte("community:\n  a:\n    source: file:./a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	cacheDir := filepath.Join(tmpDir, "cache")
+	reg := newRegistry(t, cacheDir)
+	reg.AddIndex(IndexSource{URL: indexPath, TTL: time.Hour})
+	if got, _ := reg.Resolve(context.Background(), "a"); got != "file:./a" {
+		t.Fatalf("Resolve(a) = %q, want file:./a", got)
+	}
+
+	// Within the TTL the cached copy is used even if the index changes
+	if err := os.WriteFile(indexPath, []byte("community:\n  a:\n    source: file:./b\n"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	reg = newRegistry(t, cacheDir)
+	reg.AddIndex(IndexSource{URL: indexPath, TTL: time.Hour})
+	if got, _ := reg.Resolve(context.Background(), "a"); got != "file:./a" {
+		t.Errorf("Resolve(a) = %q, want cached file:./a", got)
+	}
+
+	// A source that can't be read falls back to its stale copy
+	os.Remove(indexPath)
+	reg = newRegistry(t, cacheDir)
+	reg.AddIndex(IndexSource{URL: indexPath, TTL: time.Nanosecond})
+	if got, _ := reg.Resolve(context.Background(), "a"); got != "file:./a" {
+		t.Errorf("Resolve(a) = %q, want stale cached file:./a", got)
+	}
+}
+
+func TestRegistry_HTTPGetRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		status    int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "server error then success", failures: 2, status: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "server error persists", failures: 5, status: http.StatusBadGateway, wantCalls: 3, wantErr: true},
+		{name: "not found is not retried", failures: 5, status: http.StatusNotFound, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				if calls <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				io.WriteString(w, "version: \"1\"\n")
+			}))
+			defer server.Close()
+
+			reg := newRegistry(t, t.TempDir())
+			reg.Retry.Delay = time.Millisecond
+
+			_, err := reg.httpGet(context.Background(), server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("httpGet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRegistry_NoEmbeddedFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	for _, disabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("disabled=%v", disabled), func(t *testing.T) {
+			t.Setenv("SCAFFOLD_INDEX", "")
+			t.Setenv(ExtraIndexesEnv, "")
+			reg := newRegistry(t, t.TempDir()) // Nothing cached
+			reg.remoteURL = server.URL + "/templates.yaml"
+			reg.Retry.Attempts = 1
+			reg.NoEmbeddedFallback = disabled
+
+			templates, err := reg.List(context.Background())
+			if disabled {
+				if err == nil || !strings.Contains(err.Error(), "embedded fallback disabled") {
+					t.Errorf("List() error = %v, want the remote failure", err)
+				}
+				return
+			}
+			if err != nil || len(templates) == 0 {
+				t.Errorf("List() = %d templates, %v, want the embedded index", len(templates), err)
+			}
+		})
+	}
+}
+
+func TestRegistry_NoCache(t *testing.T) {
+	t.Setenv("SCAFFOLD_INDEX", "")
+	t.Setenv(ExtraIndexesEnv, "")
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		fmt.Fprintf(w, "official:\n  app:\n    source: file:./v%d\n", requests)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	cacheDir := filepath.Join(tmpDir, "cache")
+	extraPath := filepath.Join(tmpDir, "extra.yaml")
+	writeExtra := func(source string) {
+		t.Helper()
+		if err := os.WriteFile(extraPath, []byte("community:\n  extra:\n    source: "+source+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write index: %v", err)
+		}
+	}
+	load := func(noCache bool) *Registry {
+		reg := newRegistry(t, cacheDir)
+		reg.remoteURL = server.URL + "/templates.yaml"
+		reg.AddIndex(IndexSource{URL: extraPath, TTL: time.Hour})
+		reg.NoCache = noCache
+		return reg
+	}
+	resolve := func(reg *Registry, name string) string {
+		t.Helper()
+		got, err := reg.Resolve(context.Background(), name)
+		if err != nil {
+			t.Fatalf("Resolve(%s) error = %v", name, err)
+		}
+		return got
+	}
+
+	writeExtra("file:./a")
+	reg := load(false)
+	if got := resolve(reg, "app"); got != "file:./v1" {
+		t.Fatalf("Resolve(app) = %q, want file:./v1", got)
+	}
+	resolve(reg, "extra")
+
+	// Both indexes are cached and within their TTL
+	writeExtra("file:./b")
+	reg = load(false)
+	if got := resolve(reg, "app"); got != "file:./v1" || requests != 1 {
+		t.Errorf("Resolve(app) = %q after %d requests, want the cached file:./v1", got, requests)
+	}
+	if got := resolve(reg, "extra"); got != "file:./a" {
+		t.Errorf("Resolve(extra) = %q, want the cached file:./a", got)
+	}
+
+	// NoCache fetches both again and caches what it got
+	reg = load(true)
+	if got := resolve(reg, "app"); got != "file:./v2" || requests != 2 {
+		t.Errorf("Resolve(app) = %q after %d requests, want a fetched file:./v2", got, requests)
+	}
+	if got := resolve(reg, "extra"); got != "file:./b" {
+		t.Errorf("Resolve(extra) = %q, want a fetched file:./b", got)
+	}
+	reg = load(false)
+	if got := resolve(reg, "app"); got != "file:./v2" || requests != 2 {
+		t.Errorf("Resolve(app) = %q after %d requests, want the cached file:./v2", got, requests)
+	}
+}
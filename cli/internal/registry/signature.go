@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignatureSuffix is appended to the index URL to fetch its detached
+// signature, a base64-encoded ed25519 signature of the index bytes
+const SignatureSuffix = ".sig"
+
+// PinnedPublicKey is the base64 ed25519 key that signs the official index,
+// set at build time. Verification is skipped when no key is pinned or
+// configured.
+var PinnedPublicKey = ""
+
+// ErrBadSignature is returned when the index signature is missing or does not
+// match the pinned key
+var ErrBadSignature = errors.New("index signature verification failed")
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid index public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid index public key: want %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// verifyIndex checks a detached base64 signature of data against key
+func verifyIndex(data, signature []byte, key ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature: %v", ErrBadSignature, err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return fmt.Errorf("%w: signature does not match", ErrBadSignature)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/logger"
+)
+
+const signedIndex = `version: "1"
+official:
+  signed:
+    source: "github:example/signed"
+`
+
+func TestVerifyIndex(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	data := []byte(signedIndex)
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)) + "\n")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		sig     []byte
+		key     ed25519.PublicKey
+		wantErr bool
+	}{
+		{name: "valid", data: data, sig: sig, key: pub},
+		{name: "tampered index", data: append(data, '#'), sig: sig, key: pub, wantErr: true},
+		{name: "wrong key", data: data, sig: sig, key: otherPub, wantErr: true},
+		{name: "malformed signature", data: data, sig: []byte("not base64!"), key: pub, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyIndex(tt.data, tt.sig, tt.key)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadSignature) {
+					t.Errorf("verifyIndex() error = %v, want ErrBadSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("verifyIndex() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	key, err := ParsePublicKey(base64.StdEncoding.EncodeToString(pub))
+	if err != nil || !bytes.Equal(key, pub) {
+		t.Errorf("ParsePublicKey() = %v, %v, want the key", key, err)
+	}
+	if _, err := ParsePublicKey("c2hvcnQ="); err == nil {
+		t.Error("ParsePublicKey() should reject a key of the wrong size")
+	}
+}
+
+func TestNew_PinnedPublicKey(t *testing.T) {
+	defer func(pinned string) { PinnedPublicKey = pinned }(PinnedPublicKey)
+
+	// The key built into this binary, if any, must parse
+	if PinnedPublicKey != "" {
+		if _, err := ParsePublicKey(PinnedPublicKey); err != nil {
+			t.Fatalf("PinnedPublicKey is invalid: %v", err)
+		}
+	}
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	PinnedPublicKey = base64.StdEncoding.EncodeToString(pub)
+	reg, err := New(t.TempDir())
+	if err != nil || !bytes.Equal(reg.PublicKey, pub) {
+		t.Errorf("New() = %v, want the pinned key", err)
+	}
+
+	PinnedPublicKey = "c2hvcnQ="
+	if _, err := New(t.TempDir()); err == nil || !strings.Contains(err.Error(), "pinned key") {
+		t.Errorf("New() error = %v, want the invalid pinned key", err)
+	}
+}
+
+func TestRegistry_RemoteSignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	validSig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(signedIndex)))
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	invalidSig := base64.StdEncoding.EncodeToString(ed25519.Sign(otherPriv, []byte(signedIndex)))
+
+	tests := []struct {
+		name       string
+		sig        string // Empty serves a 404
+		wantRemote bool
+	}{
+		{name: "valid signature", sig: validSig, wantRemote: true},
+		{name: "invalid signature", sig: invalidSig},
+		{name: "missing signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case strings.HasSuffix(req.URL.Path, SignatureSuffix):
+					if tt.sig == "" {
+						http.NotFound(w, req)
+						return
+					}
+					io.WriteString(w, tt.sig)
+				default:
+					io.WriteString(w, signedIndex)
+				}
+			}))
+			defer server.Close()
+
+			t.Setenv("SCAFFOLD_INDEX", "")
+			t.Setenv(ExtraIndexesEnv, "")
+			var stderr bytes.Buffer
+			reg := newRegistry(t, t.TempDir())
+			reg.remoteURL = server.URL + "/templates.yaml"
+			reg.PublicKey = pub
+			reg.Log = logger.New(io.Discard, &stderr, logger.Quiet)
+
+			templates, err := reg.List(context.Background())
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			_, fromRemote := templates["signed"]
+
+			if tt.wantRemote {
+				if !fromRemote {
+					t.Error("List() should use the verified remote index")
+				}
+				if stderr.Len() != 0 {
+					t.Errorf("stderr = %q, want nothing", stderr.String())
+				}
+				return
+			}
+
+			if fromRemote {
+				t.Error("List() should fall back to the embedded index")
+			}
+			if !strings.Contains(stderr.String(), "Refusing the remote template index") {
+				t.Errorf("stderr = %q, want a loud rejection even when quiet", stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package retry retries transient failures with exponential backoff
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// DefaultAttempts is the number of tries made by the default policy
+const DefaultAttempts = 3
+
+// Policy controls how an operation is retried
+type Policy struct {
+	Attempts int           // Total tries, at least one is always made
+	Delay    time.Duration // Wait before the first retry, doubled after each
+	MaxDelay time.Duration // Upper bound on the wait, unbounded when 0
+
+	// OnRetry is called before waiting to retry after err
+	OnRetry func(err error, wait time.Duration)
+}
+
+// DefaultPolicy returns the policy used for network fetches
+func DefaultPolicy() Policy {
+	return Policy{
+		Attempts: DefaultAttempts,
+		Delay:    500 * time.Millisecond,
+		MaxDelay: 5 * time.Second,
+	}
+}
+
+// sleep waits between attempts unless ctx is cancelled first, replaceable
+// in tests
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error that is not transient,
+// the attempts run out or ctx is cancelled. The last error is returned.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	delay := p.Delay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || !IsTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := jitter(delay)
+		if p.OnRetry != nil {
+			p.OnRetry(err, wait)
+		}
+		if sleep(ctx, wait) != nil {
+			return err
+		}
+
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+// jitter returns a random duration between half of d and d, so that clients
+// failing together don't retry in lockstep
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// transientError marks an error as worth retrying
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// Transient marks err as a temporary failure that may succeed on retry
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// IsTransient reports whether err was marked with Transient or is a network
+// error such as a timeout, refused connection or failed DNS lookup
+func IsTransient(err error) bool {
+	var t *transientError
+	if errors.As(err, &t) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
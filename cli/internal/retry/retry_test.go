@@ -0,0 +1,121 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// stubSleep records waits instead of sleeping
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+
+	var waits []time.Duration
+	orig := sleep
+	sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = orig })
+	return &waits
+}
+
+// failing returns a stub that fails with err the given number of times and
+// then succeeds, and a pointer to its call count
+func failing(times int, err error) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= times {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+func TestPolicy_Do(t *testing.T) {
+	flaky := Transient(errors.New("HTTP 503"))
+	permanent := errors.New("HTTP 404")
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "first try", failures: 0, err: flaky, wantCalls: 1},
+		{name: "fails then succeeds", failures: 2, err: flaky, wantCalls: 3},
+		{name: "attempts run out", failures: 5, err: flaky, wantCalls: 3, wantErr: flaky},
+		{name: "permanent error", failures: 5, err: permanent, wantCalls: 1, wantErr: permanent},
+		{name: "network error", failures: 1, err: &net.OpError{Op: "dial", Err: errors.New("refused")}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := stubSleep(t)
+			fn, calls := failing(tt.failures, tt.err)
+
+			retries := 0
+			policy := Policy{Attempts: 3, Delay: 100 * time.Millisecond}
+			policy.OnRetry = func(error, time.Duration) { retries++ }
+
+			err := policy.Do(context.Background(), fn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if len(*waits) != tt.wantCalls-1 || retries != len(*waits) {
+				t.Errorf("waited %d times, notified %d, want %d", len(*waits), retries, tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestPolicy_DoBackoff(t *testing.T) {
+	waits := stubSleep(t)
+	fn, _ := failing(5, Transient(errors.New("timeout")))
+
+	policy := Policy{Attempts: 6, Delay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	policy.Do(context.Background(), fn)
+
+	maxWaits := []time.Duration{100, 200, 300, 300, 300}
+	if len(*waits) != len(maxWaits) {
+		t.Fatalf("waits = %v, want %d", *waits, len(maxWaits))
+	}
+	for i, max := range maxWaits {
+		max *= time.Millisecond
+		if w := (*waits)[i]; w < max/2 || w > max {
+			t.Errorf("wait %d = %v, want between %v and %v", i, w, max/2, max)
+		}
+	}
+}
+
+func TestPolicy_DoZeroValue(t *testing.T) {
+	stubSleep(t)
+	fn, calls := failing(1, Transient(errors.New("flaky")))
+
+	if err := (Policy{}).Do(context.Background(), fn); err == nil {
+		t.Error("Do() with no retries should return the first error")
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestPolicy_DoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Policy{Attempts: 5, Delay: time.Hour}.Do(ctx, func() error {
+		calls++
+		cancel()
+		return Transient(errors.New("flaky"))
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want the error after 1 call", err, calls)
+	}
+}
@@ -0,0 +1,59 @@
+// Package runner runs external programs behind an interface, so that code
+// running git or template commands can be tested with a fake
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+)
+
+// Options control how a program runs
+type Options struct {
+	Dir    string    // Working directory, the current one when empty
+	Env    []string  // Environment, the current process's when nil
+	Output io.Writer // Receives the output as the program runs, if set
+	// Stderr receives the program's stderr apart from its stdout when set,
+	// for programs whose stdout is parsed
+	Stderr io.Writer
+}
+
+// Runner runs a program and returns its output: stdout and stderr combined
+// in the order they were written, or stdout alone when Options.Stderr is set
+type Runner interface {
+	Run(ctx context.Context, name string, args []string, opts Options) ([]byte, error)
+}
+
+// Exec runs programs with os/exec. Cancelling ctx kills the program.
+type Exec struct{}
+
+// Run runs name with args
+func (Exec) Run(ctx context.Context, name string, args []string, opts Options) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+
+	var output bytes.Buffer
+	w := io.Writer(&output)
+	if opts.Output != nil {
+		w = io.MultiWriter(opts.Output, &output)
+	}
+	// One writer for both streams, so that the output keeps its order
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if opts.Stderr != nil {
+		cmd.Stderr = opts.Stderr
+	}
+
+	err := cmd.Run()
+	return output.Bytes(), err
+}
+
+// Or returns r, or Exec when r is nil
+func Or(r Runner) Runner {
+	if r == nil {
+		return Exec{}
+	}
+	return r
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExec_Run(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir := t.TempDir()
+	var streamed bytes.Buffer
+	out, err := Exec{}.Run(context.Background(), "sh", []string{"-c", `echo "out $PWD $GREETING"; echo err >&2`}, Options{
+		Dir:    dir,
+		Env:    append(os.Environ(), "GREETING=hello"),
+		Output: &streamed,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := "out " + dir + " hello\nerr\n"
+	if string(out) != want {
+		t.Errorf("Run() output = %q, want %q", out, want)
+	}
+	if streamed.String() != want {
+		t.Errorf("streamed output = %q, want %q", streamed.String(), want)
+	}
+
+	var stderr bytes.Buffer
+	out, err = Exec{}.Run(context.Background(), "sh", []string{"-c", "echo out; echo warning >&2"}, Options{Stderr: &stderr})
+	if err != nil || string(out) != "out\n" || stderr.String() != "warning\n" {
+		t.Errorf("Run() = %q, stderr %q, %v, want the streams apart", out, stderr.String(), err)
+	}
+
+	out, err = Exec{}.Run(context.Background(), "sh", []string{"-c", "echo failed; exit 3"}, Options{})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || string(out) != "failed\n" {
+		t.Errorf("Run() = %q, %v, want the output and exit status 3", out, err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package semver parses semantic versions and matches them against ranges
+// such as ^1.2.0, ~1.4 or ">=1.0.0 <2.0.0"
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+	Original            string // As written, e.g. a tag name
+}
+
+// Parse parses a version such as v1.2.3, 1.2 or 1.2.3-rc.1. Missing minor and
+// patch numbers default to zero and build metadata is ignored.
+func Parse(s string) (Version, error) {
+	v := Version{Original: s}
+	rest := strings.TrimPrefix(s, "v")
+	rest, _, _ = strings.Cut(rest, "+")
+	rest, v.Prerelease, _ = strings.Cut(rest, "-")
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than o.
+// A prerelease sorts before its release.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1 // Numeric identifiers sort first
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// comparator is a single bound such as >=1.2.0
+type comparator struct {
+	op string
+	v  Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// Range is a set of bounds a version must all satisfy
+type Range struct {
+	raw         string
+	comparators []comparator
+}
+
+// IsRange reports whether a ref is written as a version range rather than a
+// plain tag, branch or commit. Only refs starting with a range operator count,
+// so a tag like v1.2.0 is still used verbatim.
+func IsRange(ref string) bool {
+	return ref != "" && strings.ContainsAny(ref[:1], "^~<>=")
+}
+
+// ParseRange parses a space-separated list of bounds. Each bound is one of
+// ^1.2.0 (compatible, same major), ~1.2.0 (same minor), >, >=, <, <= or = a
+// version, or a bare version for an exact match.
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: s}
+	for _, field := range strings.Fields(s) {
+		cs, err := parseBound(field)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid version range %q: %w", s, err)
+		}
+		r.comparators = append(r.comparators, cs...)
+	}
+	if len(r.comparators) == 0 {
+		return Range{}, fmt.Errorf("invalid version range %q: empty", s)
+	}
+	return r, nil
+}
+
+func parseBound(s string) ([]comparator, error) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(s, op); ok {
+			v, err := Parse(rest)
+			if err != nil {
+				return nil, err
+			}
+			return []comparator{{op: op, v: v}}, nil
+		}
+	}
+
+	switch s[0] {
+	case '^':
+		v, err := Parse(s[1:])
+		if err != nil {
+			return nil, err
+		}
+		upper := Version{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && v.Minor == 0:
+			upper = Version{Patch: v.Patch + 1}
+		case v.Major == 0:
+			upper = Version{Minor: v.Minor + 1}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case '~':
+		v, err := Parse(s[1:])
+		if err != nil {
+			return nil, err
+		}
+		return []comparator{{">=", v}, {"<", Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+
+	v, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return []comparator{{"=", v}}, nil
+}
+
+// Matches reports whether v satisfies every bound. Prereleases only match
+// when the range names a prerelease itself.
+func (r Range) Matches(v Version) bool {
+	if v.Prerelease != "" && !r.allowsPrerelease() {
+		return false
+	}
+	for _, c := range r.comparators {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Range) allowsPrerelease() bool {
+	for _, c := range r.comparators {
+		if c.v.Prerelease != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the range as written
+func (r Range) String() string {
+	return r.raw
+}
+
+// Best returns the highest tag satisfying the range. Tags that are not
+// versions are ignored.
+func (r Range) Best(tags []string) (string, bool) {
+	var best *Version
+	for _, tag := range tags {
+		v, err := Parse(tag)
+		if err != nil || !r.Matches(v) {
+			continue
+		}
+		if best == nil || v.Compare(*best) > 0 {
+			best = &v
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.Original, true
+}
+
+// Sort sorts version tags in ascending order, followed by any tags that are
+// not versions in name order
+func Sort(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, aErr := Parse(tags[i])
+		b, bErr := Parse(tags[j])
+		switch {
+		case aErr == nil && bErr == nil:
+			return a.Compare(b) < 0
+		case aErr == nil || bErr == nil:
+			return aErr == nil
+		}
+		return tags[i] < tags[j]
+	})
+}
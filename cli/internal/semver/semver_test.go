@@ -0,0 +1,103 @@
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "v1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3, Original: "v1.2.3"}},
+		{input: "1.2", want: Version{Major: 1, Minor: 2, Original: "1.2"}},
+		{input: "2.0.0-rc.1+build.5", want: Version{Major: 2, Prerelease: "rc.1", Original: "2.0.0-rc.1+build.5"}},
+		{input: "latest", wantErr: true},
+		{input: "1.2.3.4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRange(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"^1.2.0":         true,
+		"~1.4":           true,
+		">=1.0.0 <2.0.0": true,
+		"v1.2.0":         false,
+		"main":           false,
+		"a1b2c3d":        false,
+		"":               false,
+	} {
+		if got := IsRange(ref); got != want {
+			t.Errorf("IsRange(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestRange_Best(t *testing.T) {
+	tags := []string{
+		"v0.1.0", "v0.1.4", "v0.2.0",
+		"v1.0.0", "v1.2.0", "v1.2.7", "v1.4.1", "v1.5.0-beta.1",
+		"v2.0.0", "v2.1.0", "latest", "release-2019",
+	}
+
+	tests := []struct {
+		rng    string
+		want   string
+		wantOK bool
+	}{
+		{rng: "^1.2.0", want: "v1.4.1", wantOK: true},
+		{rng: "~1.2.0", want: "v1.2.7", wantOK: true},
+		{rng: "^0.1.0", want: "v0.1.4", wantOK: true},
+		{rng: ">=1.0.0 <2.0.0", want: "v1.4.1", wantOK: true},
+		{rng: ">=1.5.0-beta.0 <2.0.0", want: "v1.5.0-beta.1", wantOK: true},
+		{rng: ">2.0.0", want: "v2.1.0", wantOK: true},
+		{rng: "=1.0.0", want: "v1.0.0", wantOK: true},
+		{rng: "^3.0.0", wantOK: false},
+		{rng: "~1.3", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			r, err := ParseRange(tt.rng)
+			if err != nil {
+				t.Fatalf("ParseRange() error = %v", err)
+			}
+			got, ok := r.Best(tags)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Best() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseRange_Invalid(t *testing.T) {
+	for _, rng := range []string{"", "^", "^one", ">=1.x"} {
+		if _, err := ParseRange(rng); err == nil {
+			t.Errorf("ParseRange(%q) should return error", rng)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	tags := []string{"v1.10.0", "latest", "v1.2.0", "v1.2.0-rc.1", "v0.9.0"}
+	Sort(tags)
+
+	want := []string{"v0.9.0", "v1.2.0-rc.1", "v1.2.0", "v1.10.0", "latest"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("Sort() = %v, want %v", tags, want)
+	}
+}
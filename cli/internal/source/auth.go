@@ -0,0 +1,101 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Environment variables holding credentials for URL sources. They are sent
+// to every URL source that has no credentials of its own.
+const (
+	URLTokenEnv    = "SCAFFOLD_URL_TOKEN" // Sent as a bearer token
+	URLUserEnv     = "SCAFFOLD_URL_USER"  // Sent with URLPasswordEnv as basic auth
+	URLPasswordEnv = "SCAFFOLD_URL_PASSWORD"
+)
+
+// maxRedirects matches the limit of the default HTTP client
+const maxRedirects = 10
+
+// newDownloadRequest builds the GET request for an archive URL. Userinfo in
+// the URL is sent as basic auth; otherwise SCAFFOLD_URL_TOKEN is sent as a
+// bearer token, or SCAFFOLD_URL_USER and SCAFFOLD_URL_PASSWORD as basic
+// auth. The userinfo is removed from the request URL itself.
+func newDownloadRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	userinfo := u.User
+	u.User = nil
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case userinfo != nil:
+		password, _ := userinfo.Password()
+		req.SetBasicAuth(userinfo.Username(), password)
+	case os.Getenv(URLTokenEnv) != "":
+		req.Header.Set("Authorization", "Bearer "+os.Getenv(URLTokenEnv))
+	case os.Getenv(URLUserEnv) != "":
+		req.SetBasicAuth(os.Getenv(URLUserEnv), os.Getenv(URLPasswordEnv))
+	}
+	return req, nil
+}
+
+// dropCrossOriginAuth is an http.Client CheckRedirect policy that removes
+// the Authorization header once a redirect leaves the scheme, host and port
+// of the original request, so credentials never reach another server. The
+// default client only strips it for hosts outside the original domain.
+func dropCrossOriginAuth(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	origin := via[0].URL
+	for _, r := range append(via[1:], req) {
+		if r.URL.Scheme != origin.Scheme || !strings.EqualFold(r.URL.Host, origin.Host) {
+			req.Header.Del("Authorization")
+			break
+		}
+	}
+	return nil
+}
+
+// StripCredentials removes the userinfo of the http(s) URL in a source,
+// which may hold credentials, so that the source can be stored. The
+// userinfo of other schemes, such as the git user of ssh URLs, is kept.
+func StripCredentials(uri string) string {
+	for _, scheme := range []string{"https://", "http://"} {
+		i := strings.Index(uri, scheme)
+		if i < 0 {
+			continue
+		}
+		start := i + len(scheme)
+		host := uri[start:]
+		if end := strings.IndexAny(host, "/?#"); end >= 0 {
+			host = host[:end]
+		}
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			return uri[:start] + uri[start+at+1:]
+		}
+		return uri
+	}
+	return uri
+}
+
+// Redact hides the userinfo of a URL, which may hold credentials, for
+// display. Anything that does not parse as a URL is returned unchanged.
+func Redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = url.User("xxxxx")
+	return u.String()
+}
@@ -0,0 +1,63 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// CacheSourceFile is the sidecar in each cache entry naming its source
+	CacheSourceFile = "source"
+	// cacheRepoDir holds the fetched content within a cache entry
+	cacheRepoDir = "repo"
+)
+
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CacheKey returns the cache entry name for a URL and ref: a readable prefix
+// followed by a hash of the canonicalized URL and ref, so that distinct
+// sources never share an entry
+func CacheKey(url, ref string) string {
+	canonical := canonicalURL(url)
+	sum := sha256.Sum256([]byte(canonical + "#" + ref))
+
+	prefix := path.Base(canonical)
+	if ref != "" {
+		prefix += "-" + ref
+	}
+	prefix = strings.Trim(unsafeKeyChars.ReplaceAllString(prefix, "_"), "_.")
+	if len(prefix) > 40 {
+		prefix = prefix[:40]
+	}
+
+	return prefix + "-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// canonicalURL normalizes spellings of the same repository URL
+func canonicalURL(url string) string {
+	url = strings.TrimRight(url, "/")
+	return strings.TrimSuffix(url, ".git")
+}
+
+// ReadCacheSource returns the source recorded for a cache entry, or "" if
+// the entry has no sidecar
+func ReadCacheSource(entryDir string) string {
+	data, err := os.ReadFile(filepath.Join(entryDir, CacheSourceFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func writeCacheSource(entryDir string, src *Source) error {
+	uri := Redact(src.URL)
+	if src.Ref != "" {
+		uri += "#" + src.Ref
+	}
+	return os.WriteFile(filepath.Join(entryDir, CacheSourceFile), []byte(uri+"\n"), 0644)
+}
@@ -0,0 +1,41 @@
+package source
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCacheKey(t *testing.T) {
+	// Distinct sources that the old character replacement mapped to the
+	// same directory name
+	pairs := [][2]string{
+		{"https://github.com/org/my_repo", "https://github.com/org_my/repo"},
+		{"git@github.com:org/repo", "git_github.com_org/repo"},
+	}
+	for _, p := range pairs {
+		if CacheKey(p[0], "") == CacheKey(p[1], "") {
+			t.Errorf("CacheKey(%q) == CacheKey(%q), want distinct keys", p[0], p[1])
+		}
+	}
+
+	url := "https://github.com/org/repo"
+	if CacheKey(url, "v1.0") != CacheKey(url, "v1.0") {
+		t.Error("CacheKey() should be stable for the same URL and ref")
+	}
+	if CacheKey(url, "v1.0") == CacheKey(url, "v2.0") || CacheKey(url, "") == CacheKey(url, "main") {
+		t.Error("CacheKey() should differ between refs")
+	}
+	if CacheKey(url+".git", "") != CacheKey(url, "") || CacheKey(url+"/", "") != CacheKey(url, "") {
+		t.Error("CacheKey() should ignore a trailing .git or slash")
+	}
+
+	key := CacheKey(url, "v1.0")
+	if !strings.HasPrefix(key, "repo-v1.0-") {
+		t.Errorf("CacheKey() = %q, want readable prefix repo-v1.0-", key)
+	}
+
+	long := CacheKey("https://example.com/"+strings.Repeat("x", 200), "")
+	if len(long) > 60 {
+		t.Errorf("len(CacheKey()) = %d, want a short key", len(long))
+	}
+}
@@ -0,0 +1,327 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/retry"
+	"github.com/makemore/scaffold/internal/runner"
+)
+
+// Cloner clones a git repository into a directory and checks out a ref
+type Cloner interface {
+	// Clone clones url into dest. ref may be a branch, tag or commit and
+	// defaults to the remote HEAD when empty. A depth of 0 clones the full
+	// history. Cancelling ctx aborts the clone.
+	Clone(ctx context.Context, url, ref, dest string, depth int) error
+}
+
+// TagLister lists the tags of a remote repository without cloning it. Cloners
+// implement it so that version ranges can be resolved before cloning.
+type TagLister interface {
+	ListTags(ctx context.Context, url string) ([]string, error)
+}
+
+// SubmoduleUpdater initializes and checks out the submodules of a clone,
+// recursively. Cloners implement it so that templates can pull in shared
+// files as submodules.
+type SubmoduleUpdater interface {
+	UpdateSubmodules(ctx context.Context, dir string) error
+}
+
+// HeadResolver reports the commit checked out in a clone. Cloners implement it
+// so that fetches can be pinned to the exact commit they got.
+type HeadResolver interface {
+	Head(ctx context.Context, dir string) (string, error)
+}
+
+// RefResolver reports the commit a ref of a remote repository points at
+// without cloning it, the remote HEAD when ref is empty. Cloners implement it
+// so that a locked commit can be checked against its branch or tag.
+type RefResolver interface {
+	ResolveRef(ctx context.Context, url, ref string) (string, error)
+}
+
+// DefaultCloner returns the git binary cloner when git is installed, and the
+// in-process cloner otherwise
+func DefaultCloner() Cloner {
+	if _, err := exec.LookPath("git"); err == nil {
+		return &ExecCloner{}
+	}
+	return &GoGitCloner{}
+}
+
+// ErrGitNotFound is returned for git sources when the cloner needs the git
+// binary and none is on PATH
+var ErrGitNotFound = errors.New("git is required for git sources; install git or use a file:/URL source")
+
+// lookGit returns ErrGitNotFound unless a git binary is on PATH
+func lookGit() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return ErrGitNotFound
+	}
+	return nil
+}
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// isCommit reports whether a ref looks like a commit SHA rather than a
+// branch or tag name
+func isCommit(ref string) bool {
+	return commitPattern.MatchString(ref)
+}
+
+// ExecCloner clones by running the git binary. Git's output is shown when
+// verbose and included in the errors of failed commands.
+type ExecCloner struct {
+	Log     *logger.Logger
+	Timeout time.Duration // Limit for each git command, none when 0
+	Runner  runner.Runner // Runs git, with os/exec when nil
+}
+
+// Clone runs git clone for branches and tags. Commits are fetched with
+// cloneCommit.
+func (c *ExecCloner) Clone(ctx context.Context, url, ref, dest string, depth int) error {
+	if isCommit(ref) {
+		return c.cloneCommit(ctx, url, ref, dest, depth)
+	}
+	if err := c.run(ctx, "", cloneArgs(url, ref, dest, depth)...); err != nil {
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+	return nil
+}
+
+// cloneArgs returns the git clone arguments for a ref. Commits can't be
+// passed to --branch, so they are cloned in full and checked out afterwards.
+func cloneArgs(url, ref, dest string, depth int) []string {
+	args := []string{"clone"}
+	if depth > 0 && !isCommit(ref) {
+		args = append(args, "--depth", fmt.Sprint(depth))
+	}
+	if ref != "" && !isCommit(ref) {
+		args = append(args, "--branch", ref)
+	}
+	return append(args, url, dest)
+}
+
+// fetchCommitArgs returns the git fetch arguments for a shallow fetch of
+// one commit from origin
+func fetchCommitArgs(commit string, depth int) []string {
+	return []string{"fetch", "--quiet", "--depth", fmt.Sprint(depth), "origin", commit}
+}
+
+// cloneCommit checks out a commit. With a depth, a commit given in full is
+// fetched on its own first; when the server won't serve it by SHA, or the
+// SHA is abbreviated, the repository is cloned in full instead.
+func (c *ExecCloner) cloneCommit(ctx context.Context, url, commit, dest string, depth int) error {
+	if depth > 0 && len(commit) == 40 {
+		// The full clone follows if this fails
+		err := c.fetchCommit(ctx, url, commit, dest, depth)
+		if err == nil || ctx.Err() != nil || retry.IsTransient(err) {
+			return err
+		}
+		os.RemoveAll(dest)
+	}
+
+	if err := c.run(ctx, "", cloneArgs(url, commit, dest, depth)...); err != nil {
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+	if err := c.run(ctx, dest, "checkout", "--quiet", commit); err != nil {
+		return fmt.Errorf("git checkout %s failed: %w", commit, err)
+	}
+	return nil
+}
+
+// fetchCommit creates a repository at dest with url as origin and checks out
+// a shallow fetch of commit
+func (c *ExecCloner) fetchCommit(ctx context.Context, url, commit, dest string, depth int) error {
+	if err := c.run(ctx, "", "init", "--quiet", dest); err != nil {
+		return fmt.Errorf("git init failed: %w", err)
+	}
+	if err := c.run(ctx, dest, "remote", "add", "origin", url); err != nil {
+		return fmt.Errorf("git remote add failed: %w", err)
+	}
+	if err := c.run(ctx, dest, fetchCommitArgs(commit, depth)...); err != nil {
+		return fmt.Errorf("git fetch %s failed: %w", commit, err)
+	}
+	if err := c.run(ctx, dest, "checkout", "--quiet", "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("git checkout %s failed: %w", commit, err)
+	}
+	return nil
+}
+
+// UpdateSubmodules runs git submodule update --init --recursive
+func (c *ExecCloner) UpdateSubmodules(ctx context.Context, dir string) error {
+	if err := c.run(ctx, dir, "submodule", "update", "--init", "--recursive"); err != nil {
+		return fmt.Errorf("git submodule update failed: %w", err)
+	}
+	return nil
+}
+
+// Head runs git rev-parse HEAD
+func (c *ExecCloner) Head(ctx context.Context, dir string) (string, error) {
+	var stderr strings.Builder
+	out, err := runner.Or(c.Runner).Run(ctx, "git", []string{"rev-parse", "HEAD"}, runner.Options{Dir: dir, Stderr: &stderr})
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD failed: %w", gitError(err, stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ListTags runs git ls-remote --tags
+func (c *ExecCloner) ListTags(ctx context.Context, url string) ([]string, error) {
+	var stderr strings.Builder
+	out, err := runner.Or(c.Runner).Run(ctx, "git", []string{"ls-remote", "--tags", url}, runner.Options{
+		Env:    append(os.Environ(), "GIT_TERMINAL_PROMPT=0"),
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote failed: %w", gitError(err, stderr.String()))
+	}
+	return parseLsRemoteTags(string(out)), nil
+}
+
+// ResolveRef runs git ls-remote
+func (c *ExecCloner) ResolveRef(ctx context.Context, url, ref string) (string, error) {
+	var stderr strings.Builder
+	out, err := runner.Or(c.Runner).Run(ctx, "git", []string{"ls-remote", url}, runner.Options{
+		Env:    append(os.Environ(), "GIT_TERMINAL_PROMPT=0"),
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return "", fmt.Errorf("git ls-remote failed: %w", gitError(err, stderr.String()))
+	}
+	return remoteCommit(parseLsRemote(string(out)), ref)
+}
+
+// parseLsRemote maps the ref names in git ls-remote output to their commits
+func parseLsRemote(out string) map[string]string {
+	refs := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		hash, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if ok {
+			refs[ref] = hash
+		}
+	}
+	return refs
+}
+
+// remoteCommit returns the commit ref points at among a remote's refs, trying
+// it as a branch and then as a tag like Clone does. Annotated tags resolve to
+// the commit they were peeled to.
+func remoteCommit(refs map[string]string, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	for _, name := range []string{ref, "refs/heads/" + ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref} {
+		if hash, ok := refs[name]; ok {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("remote has no branch or tag %s", ref)
+}
+
+// parseLsRemoteTags extracts tag names from git ls-remote output, folding the
+// peeled ^{} entries of annotated tags into their tag
+func parseLsRemoteTags(out string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		_, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		name, ok := strings.CutPrefix(ref, "refs/tags/")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(name, "^{}")
+		if !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// run runs git, killing it when ctx is cancelled or the timeout expires, and
+// marks failures caused by the network as transient
+func (c *ExecCloner) run(ctx context.Context, dir string, args ...string) error {
+	parent := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	out, err := runner.Or(c.Runner).Run(ctx, "git", args, runner.Options{
+		Dir: dir,
+		// Fail instead of hanging on a credential prompt
+		Env:    append(os.Environ(), "GIT_TERMINAL_PROMPT=0"),
+		Output: c.Log.DebugWriter(),
+	})
+	switch {
+	case err == nil:
+		return nil
+	case parent.Err() != nil:
+		return parent.Err()
+	case ctx.Err() != nil || isTransientGitOutput(string(out)):
+		return retry.Transient(gitError(err, string(out)))
+	}
+	return gitError(err, string(out))
+}
+
+// gitError adds what git reported to the error of a failed git command: its
+// fatal: and error: lines, or else its last line of output
+func gitError(err error, output string) error {
+	var reported, last string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		last = line
+		if strings.HasPrefix(line, "fatal:") || strings.HasPrefix(line, "error:") {
+			reported = strings.TrimSpace(reported + "\n" + line)
+		}
+	}
+	if reported == "" {
+		reported = last
+	}
+	if reported == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, reported)
+}
+
+// transientGitMessages are git errors caused by the network or the server
+// rather than by the request, so retrying may help
+var transientGitMessages = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection reset",
+	"connection refused",
+	"failed to connect",
+	"operation timed out",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"returned error: 5",
+}
+
+func isTransientGitOutput(output string) bool {
+	output = strings.ToLower(output)
+	for _, msg := range transientGitMessages {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,719 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/runner"
+)
+
+// bareRepo creates a bare repository with two commits on main, a v1.0 tag on
+// the first and a feature branch. It returns the repo path and the first
+// commit's SHA.
+func bareRepo(t *testing.T) (string, string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	bare := filepath.Join(dir, "repo.git")
+
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = work
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(work, "VERSION"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	write("one")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "one")
+	first := git("rev-parse", "HEAD")
+	git("tag", "v1.0")
+	git("checkout", "--quiet", "-b", "feature")
+	write("feature")
+	git("commit", "--quiet", "-am", "feature")
+	git("checkout", "--quiet", "main")
+	write("two")
+	git("commit", "--quiet", "-am", "two")
+
+	cmd := exec.Command("git", "clone", "--quiet", "--bare", work, bare)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git clone --bare: %v\n%s", err, out)
+	}
+	return bare, first
+}
+
+func TestCloners(t *testing.T) {
+	repo, first := bareRepo(t)
+
+	cloners := map[string]Cloner{
+		"exec":  &ExecCloner{},
+		"gogit": &GoGitCloner{},
+	}
+	tests := []struct {
+		name  string
+		ref   string
+		depth int
+		want  string
+	}{
+		{name: "default branch", ref: "", depth: 1, want: "two"},
+		{name: "branch", ref: "feature", depth: 1, want: "feature"},
+		{name: "tag", ref: "v1.0", depth: 1, want: "one"},
+		{name: "commit", ref: first, depth: 1, want: "one"},
+		{name: "full history", ref: "main", depth: 0, want: "two"},
+	}
+
+	for clonerName, cloner := range cloners {
+		for _, tt := range tests {
+			t.Run(clonerName+"/"+tt.name, func(t *testing.T) {
+				dest := filepath.Join(t.TempDir(), "clone")
+				if err := cloner.Clone(context.Background(), "file://"+repo, tt.ref, dest, tt.depth); err != nil {
+					t.Fatalf("Clone() error = %v", err)
+				}
+
+				got, err := os.ReadFile(filepath.Join(dest, "VERSION"))
+				if err != nil {
+					t.Fatalf("Failed to read cloned file: %v", err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("VERSION = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCloneArgs(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name  string
+		ref   string
+		depth int
+		want  []string
+	}{
+		{"default branch shallow", "", 1, []string{"clone", "--depth", "1", "URL", "DEST"}},
+		{"default branch full", "", 0, []string{"clone", "URL", "DEST"}},
+		{"branch", "main", 1, []string{"clone", "--depth", "1", "--branch", "main", "URL", "DEST"}},
+		{"tag deeper", "v1.0", 50, []string{"clone", "--depth", "50", "--branch", "v1.0", "URL", "DEST"}},
+		{"tag full", "v1.0", 0, []string{"clone", "--branch", "v1.0", "URL", "DEST"}},
+		{"commit", sha, 1, []string{"clone", "URL", "DEST"}},
+		{"short commit", sha[:7], 0, []string{"clone", "URL", "DEST"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cloneArgs("URL", tt.ref, "DEST", tt.depth); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cloneArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	want := []string{"fetch", "--quiet", "--depth", "3", "origin", sha}
+	if got := fetchCommitArgs(sha, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchCommitArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestExecCloner_CommitDepth(t *testing.T) {
+	repo, first := bareRepo(t)
+
+	tests := []struct {
+		name        string
+		ref         string
+		depth       int
+		wantShallow bool
+	}{
+		{name: "full sha is fetched shallow", ref: first, depth: 1, wantShallow: true},
+		{name: "short sha falls back to a full clone", ref: first[:7], depth: 1},
+		{name: "depth 0 clones in full", ref: first, depth: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "clone")
+			cloner := &ExecCloner{}
+			if err := cloner.Clone(context.Background(), "file://"+repo, tt.ref, dest, tt.depth); err != nil {
+				t.Fatalf("Clone() error = %v", err)
+			}
+			if got, _ := os.ReadFile(filepath.Join(dest, "VERSION")); string(got) != "one" {
+				t.Errorf("VERSION = %q, want one", got)
+			}
+			_, err := os.Stat(filepath.Join(dest, ".git", "shallow"))
+			if shallow := err == nil; shallow != tt.wantShallow {
+				t.Errorf("shallow = %v, want %v", shallow, tt.wantShallow)
+			}
+		})
+	}
+}
+
+// submoduleRepo creates a repository whose shared directory is a submodule
+// holding partial.txt, and returns its path
+func submoduleRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Git refuses local submodules by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	dir := t.TempDir()
+	git := func(work string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = work
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commitFile := func(work, name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(work, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		git(work, "init", "--quiet", "--initial-branch=main")
+		git(work, "add", ".")
+		git(work, "commit", "--quiet", "-m", "init")
+	}
+
+	shared := filepath.Join(dir, "shared")
+	repo := filepath.Join(dir, "repo")
+	commitFile(shared, "partial.txt", "shared")
+	commitFile(repo, "scaffold.yaml", "name: test")
+	git(repo, "submodule", "--quiet", "add", "file://"+shared, "shared")
+	git(repo, "commit", "--quiet", "-m", "add submodule")
+	return repo
+}
+
+func TestFetcher_Submodules(t *testing.T) {
+	repo := submoduleRepo(t)
+	src, err := Parse("git:file://" + repo)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	for name, cloner := range map[string]Cloner{"exec": &ExecCloner{}, "gogit": &GoGitCloner{}} {
+		t.Run(name, func(t *testing.T) {
+			fetcher := NewFetcher(t.TempDir())
+			fetcher.Cloner = cloner
+
+			// Without Submodules the submodule stays empty
+			path, err := fetcher.Fetch(context.Background(), src)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(path, "shared", "partial.txt")); !os.IsNotExist(err) {
+				t.Fatal("submodule should not be checked out by default")
+			}
+
+			// A cached clone gets its submodules once they are asked for
+			fetcher.Submodules = true
+			path, err = fetcher.Fetch(context.Background(), src)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(path, "shared", "partial.txt"))
+			if err != nil || string(got) != "shared" {
+				t.Errorf("shared/partial.txt = %q, %v, want submodule contents", got, err)
+			}
+		})
+	}
+}
+
+func TestCloners_MissingRef(t *testing.T) {
+	repo, _ := bareRepo(t)
+
+	for name, cloner := range map[string]Cloner{"exec": &ExecCloner{}, "gogit": &GoGitCloner{}} {
+		dest := filepath.Join(t.TempDir(), "clone")
+		if err := cloner.Clone(context.Background(), "file://"+repo, "no-such-ref", dest, 1); err == nil {
+			t.Errorf("%s: Clone() should return error for a missing ref", name)
+		}
+	}
+}
+
+func TestFetcher_Cancelled(t *testing.T) {
+	repo, _ := bareRepo(t)
+	src, err := Parse("git:file://" + repo + "#v1.0")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for name, cloner := range map[string]Cloner{"exec": &ExecCloner{}, "gogit": &GoGitCloner{}} {
+		fetcher := NewFetcher(t.TempDir())
+		fetcher.Cloner = cloner
+		if _, err := fetcher.Fetch(ctx, src); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: Fetch() error = %v, want context.Canceled", name, err)
+		}
+		if _, err := os.Stat(fetcher.cachePathFor(src)); !os.IsNotExist(err) {
+			t.Errorf("%s: Fetch() should not leave a partial cache entry", name)
+		}
+	}
+}
+
+func TestFetcher_UsesCloner(t *testing.T) {
+	repo, _ := bareRepo(t)
+
+	fetcher := NewFetcher(t.TempDir())
+	fetcher.Cloner = &GoGitCloner{}
+
+	src, err := Parse("git:file://" + repo + "#v1.0")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	path, err := fetcher.Fetch(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(path, "VERSION")); string(got) != "one" {
+		t.Errorf("VERSION = %q, want one", got)
+	}
+
+	// The cache entry records where it came from
+	entry := fetcher.cachePathFor(src)
+	if got := ReadCacheSource(entry); got != "file://"+repo+"#v1.0" {
+		t.Errorf("ReadCacheSource() = %q, want file://%s#v1.0", got, repo)
+	}
+}
+
+func TestFetchResolved_Commit(t *testing.T) {
+	repo, first := bareRepo(t)
+	out, err := exec.Command("git", "--git-dir", repo, "rev-parse", "main").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v", err)
+	}
+	head := strings.TrimSpace(string(out))
+
+	cloners := map[string]Cloner{
+		"exec":  &ExecCloner{},
+		"gogit": &GoGitCloner{},
+	}
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "", want: head},
+		{ref: "main", want: head},
+		{ref: "v1.0", want: first},
+		{ref: first, want: first},
+	}
+
+	for clonerName, cloner := range cloners {
+		fetcher := NewFetcher(t.TempDir())
+		fetcher.Cloner = cloner
+		for _, tt := range tests {
+			t.Run(clonerName+"/"+tt.ref, func(t *testing.T) {
+				src, err := Parse("git:file://" + repo + "#" + tt.ref)
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				// The second fetch is a cache hit and reports the same commit
+				for range 2 {
+					_, res, err := fetcher.FetchResolved(context.Background(), src)
+					if err != nil {
+						t.Fatalf("FetchResolved() error = %v", err)
+					}
+					if res.Commit != tt.want || res.Ref != tt.ref {
+						t.Errorf("FetchResolved() = %+v, want commit %s", res, tt.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParseLsRemoteTags(t *testing.T) {
+	out := "1111111111111111111111111111111111111111\trefs/tags/v1.0.0\n" +
+		"2222222222222222222222222222222222222222\trefs/tags/v1.1.0\n" +
+		"3333333333333333333333333333333333333333\trefs/tags/v1.1.0^{}\n" +
+		"4444444444444444444444444444444444444444\trefs/heads/main\n"
+
+	got := parseLsRemoteTags(out)
+	want := []string{"v1.0.0", "v1.1.0"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("parseLsRemoteTags() = %v, want %v", got, want)
+	}
+}
+
+func TestCloners_ListTags(t *testing.T) {
+	repo, _ := bareRepo(t)
+
+	for name, lister := range map[string]TagLister{"exec": &ExecCloner{}, "gogit": &GoGitCloner{}} {
+		tags, err := lister.ListTags(context.Background(), "file://"+repo)
+		if err != nil {
+			t.Fatalf("%s: ListTags() error = %v", name, err)
+		}
+		if len(tags) != 1 || tags[0] != "v1.0" {
+			t.Errorf("%s: ListTags() = %v, want [v1.0]", name, tags)
+		}
+	}
+}
+
+func TestRemoteCommit(t *testing.T) {
+	refs := parseLsRemote("4444444444444444444444444444444444444444\tHEAD\n" +
+		"4444444444444444444444444444444444444444\trefs/heads/main\n" +
+		"1111111111111111111111111111111111111111\trefs/tags/v1.0.0\n" +
+		"2222222222222222222222222222222222222222\trefs/tags/v1.1.0\n" +
+		"3333333333333333333333333333333333333333\trefs/tags/v1.1.0^{}\n")
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "", want: "4444444444444444444444444444444444444444"},
+		{ref: "main", want: "4444444444444444444444444444444444444444"},
+		{ref: "v1.0.0", want: "1111111111111111111111111111111111111111"},
+		{ref: "v1.1.0", want: "3333333333333333333333333333333333333333"},
+	}
+	for _, tt := range tests {
+		got, err := remoteCommit(refs, tt.ref)
+		if err != nil {
+			t.Errorf("remoteCommit(%q) error = %v", tt.ref, err)
+		} else if got != tt.want {
+			t.Errorf("remoteCommit(%q) = %s, want %s", tt.ref, got, tt.want)
+		}
+	}
+	if _, err := remoteCommit(refs, "missing"); err == nil {
+		t.Error("remoteCommit(missing) should fail")
+	}
+}
+
+func TestCloners_ResolveRef(t *testing.T) {
+	repo, first := bareRepo(t)
+	out, err := exec.Command("git", "-C", repo, "rev-parse", "main", "feature").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v", err)
+	}
+	heads := strings.Fields(string(out))
+
+	tests := map[string]string{"": heads[0], "main": heads[0], "feature": heads[1], "v1.0": first}
+	for name, resolver := range map[string]RefResolver{"exec": &ExecCloner{}, "gogit": &GoGitCloner{}} {
+		for ref, want := range tests {
+			got, err := resolver.ResolveRef(context.Background(), "file://"+repo, ref)
+			if err != nil {
+				t.Errorf("%s: ResolveRef(%q) error = %v", name, ref, err)
+			} else if got != want {
+				t.Errorf("%s: ResolveRef(%q) = %s, want %s", name, ref, got, want)
+			}
+		}
+		if _, err := resolver.ResolveRef(context.Background(), "file://"+repo, "missing"); err == nil {
+			t.Errorf("%s: ResolveRef(missing) should fail", name)
+		}
+	}
+}
+
+// tagCloner is a Cloner that serves a fixed tag list and records the ref it
+// was asked to clone
+type tagCloner struct {
+	tags   []string
+	cloned string
+}
+
+func (c *tagCloner) Clone(_ context.Context, url, ref, dest string, depth int) error {
+	c.cloned = ref
+	return os.MkdirAll(dest, 0755)
+}
+
+func (c *tagCloner) ListTags(_ context.Context, url string) ([]string, error) {
+	return c.tags, nil
+}
+
+func TestFetcher_VersionRange(t *testing.T) {
+	cloner := &tagCloner{tags: []string{"v1.0.0", "v1.2.0", "v1.3.1", "v2.0.0"}}
+	fetcher := NewFetcher(t.TempDir())
+	fetcher.Cloner = cloner
+
+	src, err := Parse("github:org/repo#^1.2.0")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	_, res, err := fetcher.FetchResolved(context.Background(), src)
+	if err != nil {
+		t.Fatalf("FetchResolved() error = %v", err)
+	}
+	if cloner.cloned != "v1.3.1" || res.Ref != "v1.3.1" {
+		t.Errorf("cloned %q with Ref %q, want v1.3.1", cloner.cloned, res.Ref)
+	}
+	if src.Ref != "^1.2.0" {
+		t.Errorf("src.Ref = %q, want the range left as given", src.Ref)
+	}
+
+	src, _ = Parse("github:org/repo#^3.0.0")
+	_, err = fetcher.Fetch(context.Background(), src)
+	if err == nil {
+		t.Fatal("Fetch() should return error for an unsatisfiable range")
+	}
+	if !strings.Contains(err.Error(), "v1.0.0, v1.2.0, v1.3.1, v2.0.0") {
+		t.Errorf("error = %v, want the available tags", err)
+	}
+}
+
+func TestIsTransientGitOutput(t *testing.T) {
+	tests := map[string]bool{
+		"fatal: unable to access 'https://github.com/org/repo/': Could not resolve host: github.com": true,
+		"error: RPC failed; curl 56 GnuTLS recv error (-9)":                                          true,
+		"fatal: unable to access 'https://x/': The requested URL returned error: 502":                true,
+		"remote: Repository not found.\nfatal: repository 'https://x/' not found":                    false,
+		"fatal: Authentication failed for 'https://x/'":                                              false,
+		"warning: Could not find remote branch v9 to clone.":                                         false,
+	}
+	for output, want := range tests {
+		if got := isTransientGitOutput(output); got != want {
+			t.Errorf("isTransientGitOutput(%q) = %v, want %v", output, got, want)
+		}
+	}
+}
+
+// progressRunner runs git clone like git does, writing progress and then
+// failing for URLs containing "missing" and creating the clone otherwise
+type progressRunner struct{}
+
+func (progressRunner) Run(ctx context.Context, name string, args []string, opts runner.Options) ([]byte, error) {
+	url, dest := args[len(args)-2], args[len(args)-1]
+	var output bytes.Buffer
+	w := io.Writer(&output)
+	if opts.Output != nil {
+		w = io.MultiWriter(opts.Output, &output)
+	}
+	fmt.Fprintf(w, "Cloning into '%s'...\n", dest)
+	fmt.Fprintln(w, "Receiving objects: 100% (3/3), done.")
+	if strings.Contains(url, "missing") {
+		fmt.Fprintf(w, "fatal: repository '%s' not found\n", url)
+		return output.Bytes(), errors.New("exit status 128")
+	}
+	return output.Bytes(), os.MkdirAll(dest, 0755)
+}
+
+func TestExecCloner_Output(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      logger.Level
+		url        string
+		wantShown  bool
+		wantErrMsg string
+	}{
+		{name: "quiet", level: logger.Quiet, url: "https://example.com/repo"},
+		{name: "normal", level: logger.Normal, url: "https://example.com/repo"},
+		{name: "verbose", level: logger.Verbose, url: "https://example.com/repo", wantShown: true},
+		{name: "quiet failure", level: logger.Quiet, url: "https://example.com/missing",
+			wantErrMsg: "fatal: repository 'https://example.com/missing' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			cloner := &ExecCloner{Log: logger.New(&stdout, &stderr, tt.level), Runner: progressRunner{}}
+			err := cloner.Clone(context.Background(), tt.url, "", filepath.Join(t.TempDir(), "clone"), 1)
+			if tt.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("Clone() error = %v, want git's message %q", err, tt.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatalf("Clone() error = %v", err)
+			}
+
+			if stdout.Len() > 0 {
+				t.Errorf("git output reached the results: %q", stdout.String())
+			}
+			shown := strings.Contains(stderr.String(), "Cloning into") && strings.Contains(stderr.String(), "Receiving objects")
+			if shown != tt.wantShown || (!tt.wantShown && stderr.Len() > 0) {
+				t.Errorf("logged output = %q, want shown %v", stderr.String(), tt.wantShown)
+			}
+		})
+	}
+}
+
+// fakeRunner records the git commands it is asked to run. Clones and inits
+// create their destination with a manifest, and commands whose arguments
+// start with a key of fail return an error.
+type fakeRunner struct {
+	calls []string
+	fail  map[string]bool
+}
+
+func (r *fakeRunner) Run(ctx context.Context, name string, args []string, opts runner.Options) ([]byte, error) {
+	line := strings.Join(args, " ")
+	r.calls = append(r.calls, line)
+	for prefix := range r.fail {
+		if strings.HasPrefix(line, prefix) {
+			return []byte("fatal: " + prefix + " refused\n"), errors.New("exit status 128")
+		}
+	}
+	switch {
+	case args[0] == "rev-parse":
+		warning := "warning: refname 'HEAD' is ambiguous.\n"
+		if opts.Stderr == nil {
+			return []byte(warning + strings.Repeat("a", 40) + "\n"), nil
+		}
+		io.WriteString(opts.Stderr, warning)
+		return []byte(strings.Repeat("a", 40) + "\n"), nil
+	case args[0] == "clone" || args[0] == "init":
+		dest := args[len(args)-1]
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return nil, err
+		}
+		return nil, os.WriteFile(filepath.Join(dest, "scaffold.yaml"), []byte("name: fake\n"), 0644)
+	}
+	return nil, nil
+}
+
+func TestExecCloner_Runner(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		name string
+		ref  string
+		fail map[string]bool
+		want []string
+	}{
+		{
+			name: "branch",
+			ref:  "main",
+			want: []string{"clone --depth 1 --branch main https://example.com/repo DEST"},
+		},
+		{
+			name: "full commit",
+			ref:  sha,
+			want: []string{
+				"init --quiet DEST",
+				"remote add origin https://example.com/repo",
+				"fetch --quiet --depth 1 origin " + sha,
+				"checkout --quiet FETCH_HEAD",
+			},
+		},
+		{
+			name: "commit not served by SHA",
+			ref:  sha,
+			fail: map[string]bool{"fetch": true},
+			want: []string{
+				"init --quiet DEST",
+				"remote add origin https://example.com/repo",
+				"fetch --quiet --depth 1 origin " + sha,
+				"clone https://example.com/repo DEST",
+				"checkout --quiet " + sha,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "clone")
+			fake := &fakeRunner{fail: tt.fail}
+			cloner := &ExecCloner{Runner: fake}
+			if err := cloner.Clone(context.Background(), "https://example.com/repo", tt.ref, dest, 1); err != nil {
+				t.Fatalf("Clone() error = %v", err)
+			}
+			got := strings.Split(strings.ReplaceAll(strings.Join(fake.calls, "\n"), dest, "DEST"), "\n")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("git calls =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestExecCloner_HeadIgnoresWarnings(t *testing.T) {
+	cloner := &ExecCloner{Runner: &fakeRunner{}}
+	got, err := cloner.Head(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if want := strings.Repeat("a", 40); got != want {
+		t.Errorf("Head() = %q, want only the commit %q", got, want)
+	}
+}
+
+func TestFetcher_Runner(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // The runner stands in for git
+
+	fake := &fakeRunner{}
+	fetcher := NewFetcher(t.TempDir())
+	fetcher.Cloner = &ExecCloner{}
+	fetcher.Runner = fake
+
+	src, err := Parse("git:https://example.com/org/repo#main")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	path, err := fetcher.Fetch(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(path, "scaffold.yaml")); string(got) != "name: fake\n" {
+		t.Errorf("scaffold.yaml = %q, want the fake clone", got)
+	}
+	if len(fake.calls) == 0 || !strings.HasPrefix(fake.calls[0], "clone ") {
+		t.Errorf("git calls = %v, want a clone through the runner", fake.calls)
+	}
+
+	fake.fail = map[string]bool{"clone": true}
+	other, _ := Parse("git:https://example.com/org/other#main")
+	if _, err := fetcher.Fetch(context.Background(), other); err == nil || !strings.Contains(err.Error(), "fatal: clone refused") {
+		t.Errorf("Fetch() error = %v, want git's message from the runner", err)
+	}
+}
+
+func TestFetcher_GitNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, ok := DefaultCloner().(*GoGitCloner); !ok {
+		t.Errorf("DefaultCloner() = %T without git, want *GoGitCloner", DefaultCloner())
+	}
+
+	fetcher := NewFetcher(t.TempDir())
+	fetcher.Cloner = &ExecCloner{}
+	for _, uri := range []string{"git:https://example.com/org/repo", "git:https://example.com/org/repo#^1.0.0"} {
+		src, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		_, err = fetcher.Fetch(context.Background(), src)
+		if !errors.Is(err, ErrGitNotFound) || !strings.Contains(err.Error(), "install git or use a file:/URL source") ||
+			!strings.Contains(err.Error(), "built-in git client") {
+			t.Errorf("Fetch(%s) error = %v, want ErrGitNotFound", uri, err)
+		}
+		if _, err := os.Stat(fetcher.cachePathFor(src)); !os.IsNotExist(err) {
+			t.Errorf("Fetch(%s) should not create a cache entry", uri)
+		}
+	}
+}
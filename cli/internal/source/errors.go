@@ -0,0 +1,17 @@
+package source
+
+import "fmt"
+
+// FetchError reports a failure to fetch a source
+type FetchError struct {
+	Source string // The parsed source, e.g. git:https://github.com/org/repo#v1
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("fetch %s: %v", e.Source, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
@@ -1,71 +1,322 @@
 package source
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/makemore/scaffold/internal/archive"
+	"github.com/makemore/scaffold/internal/logger"
+	"github.com/makemore/scaffold/internal/retry"
+	"github.com/makemore/scaffold/internal/runner"
+	"github.com/makemore/scaffold/internal/semver"
 )
 
 // Fetcher handles fetching templates from various sources
 type Fetcher struct {
 	CacheDir string
+	Cloner   Cloner        // Git clone strategy
+	Runner   runner.Runner // Runs git for an ExecCloner without a Runner of its own
+	Depth    int           // Git clone depth, 0 for full history
+	Log      *logger.Logger
+	Stdin    io.Reader     // Read for the stdin source, os.Stdin when nil
+	Retry    retry.Policy  // Retries for clones and downloads
+	Timeout  time.Duration // Limit for each download, none when 0
+	// Submodules checks out the submodules of git sources, which needs a
+	// Cloner that is also a SubmoduleUpdater
+	Submodules bool
+	// NoCache fetches every source again into a temporary directory, which
+	// Close removes, leaving the cache as it is
+	NoCache bool
+
+	noCacheDir string   // Stands in for CacheDir with NoCache
+	tempDirs   []string // Removed by Close, such as extracted stdin tarballs
 }
 
+// CacheSubdir is where templates are cached within the scaffold cache, apart
+// from template indexes
+const CacheSubdir = "templates"
+
 // NewFetcher creates a new Fetcher with the given cache directory
 func NewFetcher(cacheDir string) *Fetcher {
 	if cacheDir == "" {
 		home, _ := os.UserHomeDir()
-		cacheDir = filepath.Join(home, ".scaffold", "cache")
+		cacheDir = filepath.Join(home, ".scaffold", "cache", CacheSubdir)
+	}
+	return &Fetcher{CacheDir: cacheDir, Cloner: DefaultCloner(), Depth: 1, Retry: retry.DefaultPolicy()}
+}
+
+// Fetch retrieves a template from the given source and returns the local path.
+// Failures are reported as a *FetchError. Cancelling ctx aborts clones and
+// downloads, leaving no partial cache entry behind.
+func (f *Fetcher) Fetch(ctx context.Context, src *Source) (string, error) {
+	path, _, err := f.fetchSource(ctx, src)
+	return path, err
+}
+
+// fetchSource fetches like Fetch and also returns the source as fetched,
+// with its version range or OCI tag resolved. src itself is left as it is.
+func (f *Fetcher) fetchSource(ctx context.Context, src *Source) (string, *Source, error) {
+	if f.NoCache && f.noCacheDir == "" {
+		dir, err := os.MkdirTemp("", "scaffold-nocache-")
+		if err != nil {
+			return "", nil, &FetchError{Source: src.String(), Err: fmt.Errorf("failed to create temp directory: %w", err)}
+		}
+		f.noCacheDir = dir
 	}
-	return &Fetcher{CacheDir: cacheDir}
+
+	path, resolved, err := f.fetch(ctx, src)
+	if err != nil {
+		return "", nil, &FetchError{Source: src.String(), Err: err}
+	}
+	return path, resolved, nil
+}
+
+// Resolution records what a fetched source resolved to, so that it can be
+// fetched again exactly
+type Resolution struct {
+	Ref    string // The ref after resolving version ranges and OCI tags
+	Commit string // Commit checked out for git sources, empty otherwise
+	Root   string // Top of what was fetched, above the source's subdirectory
 }
 
-// Fetch retrieves a template from the given source and returns the local path
-func (f *Fetcher) Fetch(src *Source) (string, error) {
+// FetchResolved fetches like Fetch and also reports what the source resolved
+// to. The commit is left empty when the Cloner cannot report it.
+func (f *Fetcher) FetchResolved(ctx context.Context, src *Source) (string, *Resolution, error) {
+	path, resolved, err := f.fetchSource(ctx, src)
+	if err != nil {
+		return "", nil, err
+	}
+	res := &Resolution{Ref: resolved.Ref, Root: path}
+	if src.Subdir != "" {
+		res.Root = strings.TrimSuffix(path, string(filepath.Separator)+filepath.Clean(filepath.FromSlash(src.Subdir)))
+	}
+	if src.Type == TypeGit {
+		res.Commit = f.head(ctx, resolved)
+	}
+	return path, res, nil
+}
+
+// head returns the commit of the cached clone of a git source, or "" when the
+// cloner cannot tell
+func (f *Fetcher) head(ctx context.Context, src *Source) string {
+	resolver, ok := f.cloner().(HeadResolver)
+	if !ok {
+		return ""
+	}
+	commit, err := resolver.Head(ctx, filepath.Join(f.cachePathFor(src), cacheRepoDir))
+	if err != nil {
+		f.Log.Debug("failed to resolve the commit of %s: %v", src, err)
+		return ""
+	}
+	return commit
+}
+
+// fetch fetches src and returns its path and the source as fetched, which is
+// a copy of src with another ref when a git version range or an OCI tag was
+// resolved, and src otherwise
+func (f *Fetcher) fetch(ctx context.Context, src *Source) (string, *Source, error) {
+	var path string
+	var err error
 	switch src.Type {
 	case TypeGit:
-		return f.fetchGit(src)
+		return f.fetchGit(ctx, src)
+	case TypeOCI:
+		return f.fetchOCI(ctx, src)
 	case TypeFile:
-		return f.fetchFile(src)
+		path, err = f.fetchFile(src)
 	case TypeURL:
-		return f.fetchURL(src)
+		path, err = f.fetchURL(ctx, src)
+	case TypeStdin:
+		path, err = f.fetchStdin()
 	default:
-		return "", fmt.Errorf("unsupported source type: %s", src.Type)
+		h, ok := schemeHandler(string(src.Type))
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported source type: %s", src.Type)
+		}
+		path, err = f.fetchCustom(ctx, h, src)
+	}
+	return path, src, err
+}
+
+func (f *Fetcher) fetchGit(ctx context.Context, src *Source) (string, *Source, error) {
+	if semver.IsRange(src.Ref) {
+		tag, err := f.resolveRange(ctx, src)
+		if err != nil {
+			return "", nil, err
+		}
+		resolved := *src
+		resolved.Ref = tag
+		src = &resolved
 	}
+	path, err := f.cloneGit(ctx, src)
+	return path, src, err
 }
 
-func (f *Fetcher) fetchGit(src *Source) (string, error) {
-	// Create a unique cache path based on the URL
-	cachePath := f.cachePathFor(src)
+// cloneGit clones a git source into the cache, unless it is there already
+func (f *Fetcher) cloneGit(ctx context.Context, src *Source) (string, error) {
+	// Each source gets its own cache entry, with the clone in a subdirectory
+	entryDir := f.cachePathFor(src)
+	repoPath := filepath.Join(entryDir, cacheRepoDir)
 
 	// Check if already cached
-	if _, err := os.Stat(cachePath); err == nil {
+	if _, err := os.Stat(repoPath); err == nil {
 		// TODO: Check if we need to update (fetch latest)
-		return f.resolveSubdir(cachePath, src.Subdir), nil
+		f.Log.Debug("cache hit for %s: %s", src, repoPath)
+		touch(entryDir)
+		// The cached clone may have been made without its submodules
+		if err := f.updateSubmodules(ctx, src, repoPath); err != nil {
+			return "", err
+		}
+		return f.resolveSubdir(repoPath, src.Subdir)
 	}
 
 	// Clone the repository
-	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+	if err := f.requireGit(); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	args := []string{"clone", "--depth", "1"}
-	if src.Ref != "" {
-		args = append(args, "--branch", src.Ref)
+	cloner := f.cloner()
+	f.Log.Debug("cloning %s into %s", src, repoPath)
+	err := f.retryPolicy(src).Do(ctx, func() error {
+		// Start each attempt from a clean directory
+		os.RemoveAll(repoPath)
+		return cloner.Clone(ctx, src.URL, src.Ref, repoPath, f.Depth)
+	})
+	if err == nil {
+		err = f.updateSubmodules(ctx, src, repoPath)
+	}
+	if err != nil {
+		os.RemoveAll(entryDir)
+		return "", err
+	}
+	if err := writeCacheSource(entryDir, src); err != nil {
+		return "", fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+
+	return f.resolveSubdir(repoPath, src.Subdir)
+}
+
+// updateSubmodules checks out the submodules of a clone when Submodules is
+// set and the repository has any
+func (f *Fetcher) updateSubmodules(ctx context.Context, src *Source, repoPath string) error {
+	if !f.Submodules {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".gitmodules")); err != nil {
+		return nil
+	}
+
+	if err := f.requireGit(); err != nil {
+		return err
+	}
+	updater, ok := f.cloner().(SubmoduleUpdater)
+	if !ok {
+		return fmt.Errorf("%s has submodules, but the git cloner can't check them out", src)
+	}
+	f.Log.Debug("updating submodules of %s", src)
+	return f.retryPolicy(src).Do(ctx, func() error {
+		return updater.UpdateSubmodules(ctx, repoPath)
+	})
+}
+
+// retryPolicy returns the fetcher's retry policy, logging each retry of src
+func (f *Fetcher) retryPolicy(src *Source) retry.Policy {
+	policy := f.Retry
+	policy.OnRetry = func(err error, wait time.Duration) {
+		f.Log.Debug("fetching %s failed, retrying in %s: %v", src, wait.Round(time.Millisecond), err)
+	}
+	return policy
+}
+
+func (f *Fetcher) cloner() Cloner {
+	cloner := f.Cloner
+	if cloner == nil {
+		cloner = DefaultCloner()
+	}
+	if c, ok := cloner.(*ExecCloner); ok && c.Runner == nil && f.Runner != nil {
+		withRunner := *c
+		withRunner.Runner = f.Runner
+		return &withRunner
+	}
+	return cloner
+}
+
+// requireGit returns ErrGitNotFound when the cloner runs the git binary and
+// there is none, before it fails with a less helpful error from exec. A
+// cloner given a Runner may not need the binary. Only a fetcher set to the
+// git binary gets here without it, so the error points to the built-in
+// client it could use instead.
+func (f *Fetcher) requireGit() error {
+	if c, ok := f.cloner().(*ExecCloner); !ok || c.Runner != nil {
+		return nil
+	}
+	if err := lookGit(); err != nil {
+		return fmt.Errorf("%w, or leave the fetcher's Cloner unset to use the built-in git client", err)
+	}
+	return nil
+}
+
+// RemoteCommit returns the commit the ref of a git source points at on its
+// remote, without cloning it. A commit ref is returned as it is.
+func (f *Fetcher) RemoteCommit(ctx context.Context, src *Source) (string, error) {
+	if src.Type != TypeGit {
+		return "", fmt.Errorf("%s is not a git source", src)
+	}
+	if isCommit(src.Ref) {
+		return src.Ref, nil
+	}
+
+	if err := f.requireGit(); err != nil {
+		return "", err
+	}
+	resolver, ok := f.cloner().(RefResolver)
+	if !ok {
+		return "", fmt.Errorf("cannot resolve %s: the git cloner can't list remote refs", src)
+	}
+	return resolver.ResolveRef(ctx, src.URL, src.Ref)
+}
+
+// resolveRange returns the highest remote tag that satisfies a version range
+// ref, such as ^1.2.0
+func (f *Fetcher) resolveRange(ctx context.Context, src *Source) (string, error) {
+	r, err := semver.ParseRange(src.Ref)
+	if err != nil {
+		return "", err
 	}
-	args = append(args, src.URL, cachePath)
 
-	cmd := exec.Command("git", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if err := f.requireGit(); err != nil {
+		return "", err
+	}
+	lister, ok := f.cloner().(TagLister)
+	if !ok {
+		return "", fmt.Errorf("cannot resolve version range %s: the git cloner can't list tags", r)
+	}
+	tags, err := lister.ListTags(ctx, src.URL)
+	if err != nil {
+		return "", err
+	}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("git clone failed: %w", err)
+	tag, ok := r.Best(tags)
+	if !ok {
+		semver.Sort(tags)
+		available := "none"
+		if len(tags) > 0 {
+			available = strings.Join(tags, ", ")
+		}
+		return "", fmt.Errorf("no tag matches %s (available tags: %s)", r, available)
 	}
 
-	return f.resolveSubdir(cachePath, src.Subdir), nil
+	f.Log.Debug("resolved %s to tag %s", r, tag)
+	return tag, nil
 }
 
 func (f *Fetcher) fetchFile(src *Source) (string, error) {
@@ -94,31 +345,156 @@ func (f *Fetcher) fetchFile(src *Source) (string, error) {
 		return "", fmt.Errorf("template path does not exist: %s", path)
 	}
 
+	f.Log.Debug("using local template %s", path)
 	return path, nil
 }
 
-func (f *Fetcher) fetchURL(src *Source) (string, error) {
-	// TODO: Implement URL fetching (download and extract archives)
-	return "", fmt.Errorf("URL fetching not yet implemented")
+// fetchURL downloads an archive and extracts it into the cache. The archive
+// is streamed from the response straight into the extractor. An archive
+// holding a single top-level directory is unwrapped.
+func (f *Fetcher) fetchURL(ctx context.Context, src *Source) (string, error) {
+	entryDir := f.cachePathFor(src)
+	repoPath := filepath.Join(entryDir, cacheRepoDir)
+
+	if _, err := os.Stat(repoPath); err == nil {
+		f.Log.Debug("cache hit for %s: %s", src, repoPath)
+		touch(entryDir)
+		return f.resolveSubdir(singleRoot(repoPath), src.Subdir)
+	}
+
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	f.Log.Debug("downloading %s into %s", Redact(src.URL), repoPath)
+	err := f.retryPolicy(src).Do(ctx, func() error {
+		// Start each attempt from a clean directory
+		if err := os.RemoveAll(repoPath); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(repoPath, 0755); err != nil {
+			return err
+		}
+		return f.downloadArchive(ctx, src.URL, repoPath)
+	})
+	if err != nil {
+		os.RemoveAll(entryDir)
+		return "", err
+	}
+	if err := writeCacheSource(entryDir, src); err != nil {
+		return "", fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+
+	return f.resolveSubdir(singleRoot(repoPath), src.Subdir)
+}
+
+// downloadArchive fetches an archive over HTTP and extracts it into dest.
+// Network and server errors are marked as transient.
+func (f *Fetcher) downloadArchive(ctx context.Context, rawURL, dest string) error {
+	req, err := newDownloadRequest(ctx, rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	client := &http.Client{Timeout: f.Timeout, CheckRedirect: dropCrossOriginAuth}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("failed to download: HTTP %d", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			return retry.Transient(err)
+		}
+		return err
+	}
+
+	if err := archive.Extract(resp.Body, req.URL.Path, dest); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", req.URL, err)
+	}
+	return nil
+}
+
+// fetchStdin extracts a gzip tarball read from stdin into a temp directory.
+// A tarball holding a single top-level directory is unwrapped.
+func (f *Fetcher) fetchStdin() (string, error) {
+	in := f.Stdin
+	if in == nil {
+		in = os.Stdin
+	}
+
+	dir, err := os.MkdirTemp("", "scaffold-stdin-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	if err := archive.ExtractTarGz(in, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to read template tarball from stdin: %w", err)
+	}
+	f.tempDirs = append(f.tempDirs, dir)
+	f.Log.Debug("extracted stdin tarball into %s", dir)
+
+	return singleRoot(dir), nil
+}
+
+// singleRoot returns the only entry of dir when it is a directory, so that
+// archives wrapping the template in a folder work, and dir otherwise
+func singleRoot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
 }
 
 func (f *Fetcher) cachePathFor(src *Source) string {
-	// Create a safe directory name from the URL
-	safeName := strings.ReplaceAll(src.URL, "/", "_")
-	safeName = strings.ReplaceAll(safeName, ":", "_")
-	safeName = strings.ReplaceAll(safeName, "@", "_")
+	if f.noCacheDir != "" {
+		return filepath.Join(f.noCacheDir, CacheKey(src.URL, src.Ref))
+	}
+	return filepath.Join(f.CacheDir, CacheKey(src.URL, src.Ref))
+}
 
-	if src.Ref != "" {
-		safeName += "_" + src.Ref
+// Close removes what was fetched with NoCache or read from stdin. Paths
+// returned by Fetch for them are then gone; cached sources are not affected.
+func (f *Fetcher) Close() error {
+	dirs := f.tempDirs
+	if f.noCacheDir != "" {
+		dirs = append(dirs, f.noCacheDir)
 	}
+	f.noCacheDir, f.tempDirs = "", nil
+
+	var firstErr error
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
 
-	return filepath.Join(f.CacheDir, safeName)
+// touch marks a cache entry as used so that pruning keeps it
+func touch(path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
 }
 
-func (f *Fetcher) resolveSubdir(basePath, subdir string) string {
+// resolveSubdir returns the directory of subdir within a fetched source. The
+// subdirectory must exist and stay inside the source, so that the rest of a
+// checkout, its .git directory included, is never processed.
+func (f *Fetcher) resolveSubdir(basePath, subdir string) (string, error) {
 	if subdir == "" {
-		return basePath
+		return basePath, nil
+	}
+
+	rel := filepath.Clean(filepath.FromSlash(subdir))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("subdirectory %s is outside the source", subdir)
 	}
-	return filepath.Join(basePath, subdir)
-}
 
+	path := filepath.Join(basePath, rel)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("subdirectory %s not found in source", subdir)
+	}
+	return path, nil
+}
@@ -0,0 +1,187 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/makemore/scaffold/internal/retry"
+)
+
+// GoGitCloner clones in-process using go-git, so no git binary is needed
+type GoGitCloner struct {
+	Timeout time.Duration // Limit for each clone, none when 0
+}
+
+// Clone clones url into dest, trying ref as a branch and then as a tag. Commit
+// refs are checked out from a full clone.
+func (c *GoGitCloner) Clone(ctx context.Context, url, ref, dest string, depth int) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	if ref == "" || isCommit(ref) {
+		opts := &git.CloneOptions{URL: url}
+		if ref == "" {
+			opts.Depth = depth
+			opts.SingleBranch = true
+		}
+		repo, err := git.PlainCloneContext(ctx, dest, false, opts)
+		if err != nil {
+			return fmt.Errorf("git clone failed: %w", markTimeout(err))
+		}
+		if ref == "" {
+			return nil
+		}
+		return checkoutCommit(repo, ref)
+	}
+
+	var err error
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(ref),
+		plumbing.NewTagReferenceName(ref),
+	} {
+		_, err = git.PlainCloneContext(ctx, dest, false, &git.CloneOptions{
+			URL:           url,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         depth,
+		})
+		if err == nil {
+			return nil
+		}
+		// Clear the partial clone before the next attempt
+		os.RemoveAll(dest)
+		if err = markTimeout(err); retry.IsTransient(err) {
+			break
+		}
+	}
+	return fmt.Errorf("git clone of %s failed: %w", ref, err)
+}
+
+// withTimeout bounds ctx by the timeout if set
+func (c *GoGitCloner) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout > 0 {
+		return context.WithTimeout(ctx, c.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// markTimeout marks a clone that ran out of time as transient
+func markTimeout(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return retry.Transient(err)
+	}
+	return err
+}
+
+// UpdateSubmodules initializes and checks out the submodules of the clone in
+// dir using go-git
+func (c *GoGitCloner) UpdateSubmodules(ctx context.Context, dir string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open clone: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return fmt.Errorf("failed to read submodules: %w", err)
+	}
+	err = submodules.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	})
+	if err != nil {
+		return fmt.Errorf("submodule update failed: %w", markTimeout(err))
+	}
+	return nil
+}
+
+// Head returns the commit checked out in the clone in dir using go-git
+func (c *GoGitCloner) Head(ctx context.Context, dir string) (string, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open clone: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+// ListTags lists the remote's tags using go-git
+func (c *GoGitCloner) ListTags(ctx context.Context, url string) ([]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	var tags []string
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+	return tags, nil
+}
+
+// ResolveRef resolves ref against the remote's refs using go-git
+func (c *GoGitCloner) ResolveRef(ctx context.Context, url, ref string) (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	list, err := remote.ListContext(ctx, &git.ListOptions{PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return "", fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	refs := make(map[string]string)
+	targets := make(map[string]string)
+	for _, r := range list {
+		if r.Type() == plumbing.SymbolicReference {
+			targets[r.Name().String()] = r.Target().String()
+			continue
+		}
+		refs[r.Name().String()] = r.Hash().String()
+	}
+	// HEAD is advertised as the branch it points to
+	for name, target := range targets {
+		if hash, ok := refs[target]; ok {
+			refs[name] = hash
+		}
+	}
+	return remoteCommit(refs, ref)
+}
+
+func checkoutCommit(repo *git.Repository, ref string) error {
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return fmt.Errorf("failed to resolve commit %s: %w", ref, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: *hash}); err != nil {
+		return fmt.Errorf("git checkout %s failed: %w", ref, err)
+	}
+	return nil
+}
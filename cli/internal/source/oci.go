@@ -0,0 +1,122 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/archive"
+	"github.com/makemore/scaffold/internal/oci"
+)
+
+// fetchOCI pulls an OCI artifact and extracts its layers into the cache. A
+// tag is first resolved to the digest it points to, and the source is
+// returned with the digest as its ref so that the lockfile pins it; cache
+// entries are keyed by digest.
+func (f *Fetcher) fetchOCI(ctx context.Context, src *Source) (string, *Source, error) {
+	registry, repository, _ := strings.Cut(src.URL, "/")
+	ref := oci.Reference{Registry: registry, Repository: repository, Reference: src.Ref}
+	client := oci.NewClient(f.Timeout, f.Log)
+	policy := f.retryPolicy(src)
+
+	var manifest *oci.Manifest
+	if !oci.IsDigest(ref.Reference) {
+		err := policy.Do(ctx, func() error {
+			var err error
+			manifest, ref.Reference, err = client.Manifest(ctx, ref)
+			return err
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		f.Log.Debug("resolved %s to %s", src, ref.Reference)
+		resolved := *src
+		resolved.Ref = ref.Reference
+		src = &resolved
+	}
+
+	entryDir := f.cachePathFor(src)
+	repoPath := filepath.Join(entryDir, cacheRepoDir)
+
+	if _, err := os.Stat(repoPath); err == nil {
+		f.Log.Debug("cache hit for %s: %s", src, repoPath)
+		touch(entryDir)
+		path, err := f.resolveSubdir(singleRoot(repoPath), src.Subdir)
+		return path, src, err
+	}
+
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	f.Log.Debug("pulling %s into %s", src, repoPath)
+	err := policy.Do(ctx, func() error {
+		// Start each attempt from a clean directory
+		if err := os.RemoveAll(repoPath); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(repoPath, 0755); err != nil {
+			return err
+		}
+		if manifest == nil {
+			var err error
+			if manifest, _, err = client.Manifest(ctx, ref); err != nil {
+				return err
+			}
+		}
+		return pullLayers(ctx, client, ref, manifest, repoPath)
+	})
+	if err != nil {
+		os.RemoveAll(entryDir)
+		return "", nil, err
+	}
+	if err := writeCacheSource(entryDir, src); err != nil {
+		return "", nil, fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+
+	path, err := f.resolveSubdir(singleRoot(repoPath), src.Subdir)
+	return path, src, err
+}
+
+// pullLayers downloads the layers of an artifact into dest, in order, so
+// later layers overwrite files of earlier ones
+func pullLayers(ctx context.Context, client *oci.Client, ref oci.Reference, manifest *oci.Manifest, dest string) error {
+	if len(manifest.Layers) == 0 {
+		return fmt.Errorf("artifact %s has no layers", ref)
+	}
+	for _, layer := range manifest.Layers {
+		data, err := client.Blob(ctx, ref, layer.Digest)
+		if err != nil {
+			return err
+		}
+		if err := extractLayer(layer, data, dest); err != nil {
+			return fmt.Errorf("failed to extract layer %s: %w", layer.Digest, err)
+		}
+	}
+	return nil
+}
+
+// extractLayer unpacks an archive layer into dest. Any other layer is
+// written as the file named by its title annotation, which is how oras push
+// stores single files.
+func extractLayer(layer oci.Descriptor, data []byte, dest string) error {
+	title := layer.Title()
+	if archive.DetectFormat(data) != "" {
+		return archive.Extract(bytes.NewReader(data), title, dest)
+	}
+
+	if title == "" {
+		return fmt.Errorf("layer of type %s is not an archive and has no %s annotation", layer.MediaType, oci.TitleAnnotation)
+	}
+	if !filepath.IsLocal(filepath.FromSlash(title)) {
+		return fmt.Errorf("layer file name %s is outside the template", title)
+	}
+	path := filepath.Join(dest, filepath.FromSlash(title))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,142 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/archive"
+	"github.com/makemore/scaffold/internal/oci"
+)
+
+// ociArtifact serves a template as an OCI artifact under acme/django:v1: a
+// tar.gz layer holding the template and a README.md pushed as a plain file.
+// It returns the server, the manifest digest and a count of blob requests.
+func ociArtifact(t *testing.T) (*httptest.Server, string, *int) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	templateDir := filepath.Join(tmpDir, "template")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "scaffold.yaml"), []byte("name: pulled\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	tarball := filepath.Join(tmpDir, "t.tar.gz")
+	if err := archive.Create(archive.FormatTarGz, templateDir, tarball, "template"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	layer, err := os.ReadFile(tarball)
+	if err != nil {
+		t.Fatalf("Failed to read tarball: %v", err)
+	}
+	readme := []byte("# Pulled\n")
+
+	blobs := map[string][]byte{oci.Digest(layer): layer, oci.Digest(readme): readme}
+	manifest, _ := json.Marshal(oci.Manifest{
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Layers: []oci.Descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: oci.Digest(layer)},
+			{MediaType: "text/markdown", Digest: oci.Digest(readme), Annotations: map[string]string{oci.TitleAnnotation: "template/README.md"}},
+		},
+	})
+	digest := oci.Digest(manifest)
+
+	blobRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v2/acme/django")
+		switch {
+		case path == "/manifests/v1" || path == "/manifests/"+digest:
+			w.Write(manifest)
+		case strings.HasPrefix(path, "/blobs/") && blobs[strings.TrimPrefix(path, "/blobs/")] != nil:
+			blobRequests++
+			w.Write(blobs[strings.TrimPrefix(path, "/blobs/")])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, digest, &blobRequests
+}
+
+func TestFetch_OCI(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	server, digest, blobRequests := ociArtifact(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	fetcher := NewFetcher(t.TempDir())
+
+	src, err := Parse("oci://" + host + "/acme/django:v1")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	path, res, err := fetcher.FetchResolved(context.Background(), src)
+	if err != nil {
+		t.Fatalf("FetchResolved() error = %v", err)
+	}
+	for name, want := range map[string]string{"scaffold.yaml": "name: pulled\n", "README.md": "# Pulled\n"} {
+		if got, _ := os.ReadFile(filepath.Join(path, name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if res.Ref != digest {
+		t.Errorf("Ref = %q, want the tag resolved to %s", res.Ref, digest)
+	}
+	if src.Ref != "v1" {
+		t.Errorf("src.Ref = %q, want the tag left as given", src.Ref)
+	}
+	if *blobRequests != 2 {
+		t.Errorf("blob requests = %d, want 2", *blobRequests)
+	}
+
+	// Pinned by digest, the artifact comes from the cache
+	pinned, err := Parse("oci://" + host + "/acme/django@" + digest)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got, err := fetcher.Fetch(context.Background(), pinned); err != nil || got != path {
+		t.Errorf("Fetch() = %q, %v, want cached %q", got, err, path)
+	}
+	if *blobRequests != 2 {
+		t.Errorf("blob requests = %d, want 2 (second fetch from cache)", *blobRequests)
+	}
+
+	for _, uri := range []string{"oci://" + host + "/acme/django:v2", "oci://" + host + "/acme/django@sha256:0000"} {
+		src, _ := Parse(uri)
+		if _, err := fetcher.Fetch(context.Background(), src); err == nil {
+			t.Errorf("Fetch(%s) should fail", uri)
+		}
+	}
+}
+
+func TestExtractLayer(t *testing.T) {
+	tests := []struct {
+		name    string
+		layer   oci.Descriptor
+		wantErr bool
+	}{
+		{"titled file", oci.Descriptor{Annotations: map[string]string{oci.TitleAnnotation: "docs/notes.txt"}}, false},
+		{"untitled file", oci.Descriptor{MediaType: "text/plain"}, true},
+		{"escaping title", oci.Descriptor{Annotations: map[string]string{oci.TitleAnnotation: "../notes.txt"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := extractLayer(tt.layer, []byte("notes\n"), dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractLayer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				if got, _ := os.ReadFile(filepath.Join(dest, "docs", "notes.txt")); string(got) != "notes\n" {
+					t.Errorf("docs/notes.txt = %q, want the layer content", got)
+				}
+			}
+		})
+	}
+}
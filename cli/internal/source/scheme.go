@@ -0,0 +1,108 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SchemeHandler parses and fetches the sources of a custom URI scheme, such
+// as an in-house artifact store. Programs embedding scaffold register them
+// through the public scheme package.
+type SchemeHandler interface {
+	// Parse turns a URI of the scheme into a Source. Its Type is set to the
+	// scheme, and its URI to the one parsed when left empty.
+	Parse(uri string) (*Source, error)
+	// Fetch makes the source available locally and returns its path. dir is
+	// the source's cache entry, which the handler may fetch into and find
+	// again on later fetches; a handler returning a path elsewhere leaves it
+	// empty.
+	Fetch(ctx context.Context, src *Source, dir string) (string, error)
+}
+
+// builtinSchemes are the URI prefixes Parse handles itself and the names of
+// the built-in source types
+var builtinSchemes = []string{"git", "file", "github", "gitlab", "bitbucket", "oci", "http", "https", "url", "stdin"}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = make(map[string]SchemeHandler)
+)
+
+// RegisterScheme makes sources starting with scheme: parse and fetch with
+// handler. Like database/sql.Register, it panics when handler is nil, the
+// scheme is built in or it is registered twice.
+func RegisterScheme(scheme string, handler SchemeHandler) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+
+	if handler == nil {
+		panic("source: RegisterScheme handler is nil")
+	}
+	if scheme == "" || strings.ContainsAny(scheme, ":/") {
+		panic(fmt.Sprintf("source: invalid scheme %q", scheme))
+	}
+	for _, builtin := range builtinSchemes {
+		if scheme == builtin {
+			panic("source: RegisterScheme of built-in scheme " + scheme)
+		}
+	}
+	if _, dup := schemes[scheme]; dup {
+		panic("source: RegisterScheme called twice for scheme " + scheme)
+	}
+	schemes[scheme] = handler
+}
+
+// schemeHandler returns the handler registered for scheme
+func schemeHandler(scheme string) (SchemeHandler, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+
+	h, ok := schemes[scheme]
+	return h, ok
+}
+
+// parseCustomScheme parses uri with the handler of its scheme. It reports
+// false when no handler is registered for it.
+func parseCustomScheme(uri string) (*Source, bool, error) {
+	scheme, _, ok := strings.Cut(uri, ":")
+	if !ok {
+		return nil, false, nil
+	}
+	h, ok := schemeHandler(scheme)
+	if !ok {
+		return nil, false, nil
+	}
+
+	src, err := h.Parse(uri)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to parse %s source: %w", scheme, err)
+	}
+	if src == nil {
+		return nil, true, fmt.Errorf("failed to parse %s source: no source returned", scheme)
+	}
+	src.Type = Type(scheme)
+	if src.URI == "" {
+		src.URI = uri
+	}
+	return src, true, nil
+}
+
+// fetchCustom fetches a source with the handler of its scheme into its own
+// cache entry
+func (f *Fetcher) fetchCustom(ctx context.Context, h SchemeHandler, src *Source) (string, error) {
+	entryDir := f.cachePathFor(src)
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	path, err := h.Fetch(ctx, src, entryDir)
+	if err != nil {
+		os.Remove(entryDir) // Only when the handler left it empty
+		return "", err
+	}
+	touch(entryDir)
+	return f.resolveSubdir(path, src.Subdir)
+}
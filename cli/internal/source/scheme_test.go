@@ -0,0 +1,117 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubStore is a scheme handler for stubstore://<name>#<ref> sources that
+// writes a template into its cache entry, counting the fetches that do
+type stubStore struct {
+	fetches int
+}
+
+func (s *stubStore) Parse(uri string) (*Source, error) {
+	rest := strings.TrimPrefix(uri, "stubstore://")
+	rest, ref, _ := strings.Cut(rest, "#")
+	name, subdir, _ := strings.Cut(rest, "//")
+	if name == "" {
+		return nil, errors.New("missing artifact name")
+	}
+	return &Source{URL: name, Ref: ref, Subdir: subdir}, nil
+}
+
+func (s *stubStore) Fetch(ctx context.Context, src *Source, dir string) (string, error) {
+	if src.URL == "missing" {
+		return "", errors.New("artifact not found")
+	}
+	manifest := filepath.Join(dir, "api", "scaffold.yaml")
+	if _, err := os.Stat(manifest); err == nil {
+		return dir, nil
+	}
+	s.fetches++
+	if err := os.MkdirAll(filepath.Dir(manifest), 0755); err != nil {
+		return "", err
+	}
+	return dir, os.WriteFile(manifest, []byte("name: "+src.URL+"@"+src.Ref+"\n"), 0644)
+}
+
+var stub = &stubStore{}
+
+func init() {
+	RegisterScheme("stubstore", stub)
+}
+
+func TestCustomScheme(t *testing.T) {
+	src, err := Parse("stubstore://django//api#v2")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if src.Type != "stubstore" || src.URI != "stubstore://django//api#v2" || src.URL != "django" || src.Ref != "v2" {
+		t.Errorf("Parse() = %+v", src)
+	}
+	if got := src.String(); got != "stubstore:django//api#v2" {
+		t.Errorf("String() = %q", got)
+	}
+
+	fetcher := NewFetcher(t.TempDir())
+	for range 2 {
+		path, err := fetcher.Fetch(context.Background(), src)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(path, "scaffold.yaml")); string(got) != "name: django@v2\n" {
+			t.Errorf("scaffold.yaml = %q, want the stub template", got)
+		}
+	}
+	if stub.fetches != 1 {
+		t.Errorf("fetches = %d, want 1 (second fetch from the cache entry)", stub.fetches)
+	}
+
+	if _, err := Parse("stubstore://"); err == nil || !strings.Contains(err.Error(), "missing artifact name") {
+		t.Errorf("Parse() error = %v, want the handler's error", err)
+	}
+	missing, _ := Parse("stubstore://missing")
+	var fetchErr *FetchError
+	if _, err := fetcher.Fetch(context.Background(), missing); !errors.As(err, &fetchErr) {
+		t.Errorf("Fetch() error = %v, want a *FetchError", err)
+	}
+	if entries, _ := os.ReadDir(fetcher.CacheDir); len(entries) != 1 {
+		t.Errorf("cache has %d entries, want only the fetched one", len(entries))
+	}
+
+	// Unregistered schemes are still unknown
+	if _, err := Parse("otherstore://django"); err == nil || !strings.Contains(err.Error(), "unknown source format") {
+		t.Errorf("Parse() error = %v, want unknown source format", err)
+	}
+}
+
+func TestRegisterScheme_Panics(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  string
+		handler SchemeHandler
+	}{
+		{"nil handler", "other", nil},
+		{"built-in scheme", "github", &stubStore{}},
+		{"url source type", "url", &stubStore{}},
+		{"stdin source type", "stdin", &stubStore{}},
+		{"duplicate", "stubstore", &stubStore{}},
+		{"invalid scheme", "a/b", &stubStore{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterScheme(%q) should panic", tt.scheme)
+				}
+			}()
+			RegisterScheme(tt.scheme, tt.handler)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+
+	"github.com/makemore/scaffold/internal/oci"
 )
 
 // Type represents the type of template source
@@ -14,8 +16,13 @@ const (
 	TypeGit   Type = "git"
 	TypeFile  Type = "file"
 	TypeURL   Type = "url"
+	TypeStdin Type = "stdin"
+	TypeOCI   Type = "oci"
 )
 
+// StdinSource is the source name for a gzip tarball read from stdin
+const StdinSource = "-"
+
 // Source represents a parsed template source
 type Source struct {
 	Type     Type
@@ -32,6 +39,7 @@ type Source struct {
 //   - git:git@github.com:org/repo.git
 //   - git:https://github.com/org/repo#v1.0
 //   - git:https://github.com/org/repo//subdir#main
+//   - github:org/repo#^1.2.0 (highest tag matching a version range)
 //   - file:./relative/path
 //   - file:~/templates/my-template
 //   - file:/absolute/path
@@ -39,11 +47,19 @@ type Source struct {
 //   - github:org/repo
 //   - gitlab:org/repo
 //   - bitbucket:org/repo
+//   - oci://ghcr.io/org/template:v1
+//   - oci://ghcr.io/org/template@sha256:<hex>
+//   - - or file:- (gzip tarball on stdin)
+//   - <scheme>:... for a scheme added with RegisterScheme
 func Parse(uri string) (*Source, error) {
 	if uri == "" {
 		return nil, fmt.Errorf("empty source URI")
 	}
 
+	if uri == StdinSource || uri == "file:"+StdinSource {
+		return &Source{Type: TypeStdin, URI: uri, URL: StdinSource}, nil
+	}
+
 	// Handle shorthand aliases
 	if strings.HasPrefix(uri, "github:") {
 		path := strings.TrimPrefix(uri, "github:")
@@ -66,11 +82,20 @@ func Parse(uri string) (*Source, error) {
 		return parseFileSource(strings.TrimPrefix(uri, "file:"))
 	}
 
+	if strings.HasPrefix(uri, oci.Scheme) {
+		return parseOCISource(uri)
+	}
+
 	// Handle plain URLs
 	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
 		return parseURLSource(uri)
 	}
 
+	// Handle schemes registered by programs embedding scaffold
+	if src, ok, err := parseCustomScheme(uri); ok {
+		return src, err
+	}
+
 	return nil, fmt.Errorf("unknown source format: %s", uri)
 }
 
@@ -89,10 +114,8 @@ func parseGitSource(uri string) (*Source, error) {
 	// Extract subdir (after // but not the :// in https://)
 	// Look for // that comes after the host part
 	searchStart := 0
-	if strings.HasPrefix(uri, "https://") {
-		searchStart = 8 // len("https://")
-	} else if strings.HasPrefix(uri, "http://") {
-		searchStart = 7 // len("http://")
+	if idx := strings.Index(uri, "://"); idx != -1 {
+		searchStart = idx + 3 // https://, ssh://, file://, ...
 	} else if strings.HasPrefix(uri, "git@") {
 		// git@github.com:org/repo - find the first /
 		if colonIdx := strings.Index(uri, ":"); colonIdx != -1 {
@@ -130,6 +153,21 @@ func parseFileSource(path string) (*Source, error) {
 	}, nil
 }
 
+// parseOCISource parses an OCI artifact reference. URL holds the registry
+// and repository, Ref the tag or digest.
+func parseOCISource(uri string) (*Source, error) {
+	ref, err := oci.ParseReference(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{
+		Type: TypeOCI,
+		URI:  uri,
+		URL:  ref.Registry + "/" + ref.Repository,
+		Ref:  ref.Reference,
+	}, nil
+}
+
 func parseURLSource(uri string) (*Source, error) {
 	parsed, err := url.Parse(uri)
 	if err != nil {
@@ -145,7 +183,7 @@ func parseURLSource(uri string) (*Source, error) {
 
 // String returns a human-readable representation of the source
 func (s *Source) String() string {
-	result := fmt.Sprintf("%s:%s", s.Type, s.URL)
+	result := fmt.Sprintf("%s:%s", s.Type, Redact(s.URL))
 	if s.Subdir != "" {
 		result += "//" + s.Subdir
 	}
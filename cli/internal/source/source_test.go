@@ -1,7 +1,17 @@
 package source
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/makemore/scaffold/internal/archive"
 )
 
 func TestParse(t *testing.T) {
@@ -14,6 +24,18 @@ func TestParse(t *testing.T) {
 		wantSubdir string
 		wantErr    bool
 	}{
+		{
+			name:     "stdin",
+			uri:      "-",
+			wantType: TypeStdin,
+			wantURL:  "-",
+		},
+		{
+			name:     "file stdin",
+			uri:      "file:-",
+			wantType: TypeStdin,
+			wantURL:  "-",
+		},
 		{
 			name:     "github shorthand",
 			uri:      "github:org/repo",
@@ -60,6 +82,21 @@ func TestParse(t *testing.T) {
 			wantSubdir: "templates/base",
 			wantRef:    "main",
 		},
+		{
+			name:       "git ssh URL with subdir",
+			uri:        "git:ssh://git@example.com/org/repo.git//templates/base#v1",
+			wantType:   TypeGit,
+			wantURL:    "ssh://git@example.com/org/repo.git",
+			wantSubdir: "templates/base",
+			wantRef:    "v1",
+		},
+		{
+			name:       "git file URL with subdir",
+			uri:        "git:file:///srv/repos/templates//base",
+			wantType:   TypeGit,
+			wantURL:    "file:///srv/repos/templates",
+			wantSubdir: "base",
+		},
 		{
 			name:     "file prefix relative",
 			uri:      "file:./templates/base",
@@ -84,6 +121,32 @@ func TestParse(t *testing.T) {
 			wantType: TypeURL,
 			wantURL:  "https://example.com/template.tar.gz",
 		},
+		{
+			name:     "oci tag",
+			uri:      "oci://ghcr.io/acme/templates/django:v1.2",
+			wantType: TypeOCI,
+			wantURL:  "ghcr.io/acme/templates/django",
+			wantRef:  "v1.2",
+		},
+		{
+			name:     "oci registry port without tag",
+			uri:      "oci://localhost:5000/django",
+			wantType: TypeOCI,
+			wantURL:  "localhost:5000/django",
+			wantRef:  "latest",
+		},
+		{
+			name:     "oci digest",
+			uri:      "oci://ghcr.io/acme/django@sha256:0123abcd",
+			wantType: TypeOCI,
+			wantURL:  "ghcr.io/acme/django",
+			wantRef:  "sha256:0123abcd",
+		},
+		{
+			name:    "oci without repository",
+			uri:     "oci://ghcr.io",
+			wantErr: true,
+		},
 		{
 			name:    "empty uri",
 			uri:     "",
@@ -122,3 +185,179 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestFetch_FetchError(t *testing.T) {
+	src, err := Parse("file:" + filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	_, err = NewFetcher(t.TempDir()).Fetch(context.Background(), src)
+	var fe *FetchError
+	if !errors.As(err, &fe) {
+		t.Fatalf("Fetch() error = %v, want *FetchError", err)
+	}
+	if fe.Source != src.String() {
+		t.Errorf("FetchError.Source = %q, want %q", fe.Source, src.String())
+	}
+}
+
+func TestFetch_Stdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	templateDir := filepath.Join(tmpDir, "template")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "scaffold.yaml"), []byte("name: piped\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	tarball := filepath.Join(tmpDir, "t.tar.gz")
+	if err := archive.Create(archive.FormatTarGz, templateDir, tarball, "template"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	data, err := os.ReadFile(tarball)
+	if err != nil {
+		t.Fatalf("Failed to read tarball: %v", err)
+	}
+
+	src, _ := Parse("-")
+	fetcher := NewFetcher(t.TempDir())
+	fetcher.Stdin = bytes.NewReader(data)
+	path, err := fetcher.Fetch(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	defer fetcher.Close()
+	if got, _ := os.ReadFile(filepath.Join(path, "scaffold.yaml")); string(got) != "name: piped\n" {
+		t.Errorf("scaffold.yaml = %q, want the piped template unwrapped", got)
+	}
+
+	for name, input := range map[string]string{"empty": "", "not an archive": "hello"} {
+		fetcher.Stdin = strings.NewReader(input)
+		if _, err := fetcher.Fetch(context.Background(), src); err == nil || !strings.Contains(err.Error(), "stdin") {
+			t.Errorf("%s: Fetch() error = %v, want a stdin error", name, err)
+		}
+	}
+
+	if err := fetcher.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("Close() should remove the extracted tarball, stat error = %v", err)
+	}
+}
+
+func TestFetch_URL(t *testing.T) {
+	tmpDir := t.TempDir()
+	templateDir := filepath.Join(tmpDir, "template")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "scaffold.yaml"), []byte("name: remote\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	tarball := filepath.Join(tmpDir, "t.tar.gz")
+	if err := archive.Create(archive.FormatTarGz, templateDir, tarball, "template-1.0"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download": // No extension, so the format comes from the magic bytes
+			downloads++
+			http.ServeFile(w, r, tarball)
+		case "/garbage.tar.gz":
+			w.Write([]byte("not an archive"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(t.TempDir())
+	src, _ := Parse(server.URL + "/download")
+	for i := 0; i < 2; i++ {
+		path, err := fetcher.Fetch(context.Background(), src)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(path, "scaffold.yaml")); string(got) != "name: remote\n" {
+			t.Errorf("scaffold.yaml = %q, want the downloaded template unwrapped", got)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("downloads = %d, want 1 (second fetch from cache)", downloads)
+	}
+
+	for _, p := range []string{"/missing.tar.gz", "/garbage.tar.gz"} {
+		src, _ := Parse(server.URL + p)
+		if _, err := fetcher.Fetch(context.Background(), src); err == nil {
+			t.Errorf("Fetch(%s) should return error", p)
+		}
+		if _, err := os.Stat(fetcher.cachePathFor(src)); !os.IsNotExist(err) {
+			t.Errorf("Fetch(%s) should not leave a cache entry", p)
+		}
+	}
+}
+
+func TestFetch_NoCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	templateDir := filepath.Join(tmpDir, "template")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	tarball := filepath.Join(tmpDir, "t.tar.gz")
+	writeVersion := func(version string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(templateDir, "scaffold.yaml"), []byte("name: remote\nversion: "+version+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+		if err := archive.Create(archive.FormatTarGz, templateDir, tarball, "template"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		http.ServeFile(w, r, tarball)
+	}))
+	defer server.Close()
+	src, _ := Parse(server.URL + "/template.tar.gz")
+	fetch := func(f *Fetcher) string {
+		t.Helper()
+		path, err := f.Fetch(context.Background(), src)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		got, _ := os.ReadFile(filepath.Join(path, "scaffold.yaml"))
+		return string(got)
+	}
+
+	cacheDir := t.TempDir()
+	writeVersion("1")
+	fetch(NewFetcher(cacheDir))
+
+	// The cache entry is valid, but NoCache downloads the template again
+	writeVersion("2")
+	fetcher := NewFetcher(cacheDir)
+	fetcher.NoCache = true
+	if got := fetch(fetcher); got != "name: remote\nversion: 2\n" || downloads != 2 {
+		t.Errorf("scaffold.yaml = %q after %d downloads, want version 2 downloaded again", got, downloads)
+	}
+	path, _ := fetcher.Fetch(context.Background(), src)
+	if strings.HasPrefix(path, cacheDir) {
+		t.Errorf("Fetch() = %s, want a path outside the cache", path)
+	}
+	if err := fetcher.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Close() should remove %s", path)
+	}
+
+	// The cache is left as it was
+	if got := fetch(NewFetcher(cacheDir)); got != "name: remote\nversion: 1\n" || downloads != 2 {
+		t.Errorf("scaffold.yaml = %q after %d downloads, want the cached version 1", got, downloads)
+	}
+}
@@ -0,0 +1,44 @@
+// Package suggest finds likely intended names for typos
+package suggest
+
+import "strings"
+
+// Closest returns the candidate nearest to input by edit distance, or "" if
+// none is within maxDistance. Comparison is case-insensitive.
+func Closest(input string, candidates []string, maxDistance int) string {
+	best := ""
+	bestDistance := maxDistance + 1
+
+	for _, c := range candidates {
+		d := Distance(strings.ToLower(input), strings.ToLower(c))
+		if d < bestDistance {
+			best = c
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+// Distance returns the Levenshtein edit distance between a and b
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,36 @@
+package suggest
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"require", "requires", 1},
+		{"varibles", "variables", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"name", "variables", "requires", "conflicts"}
+
+	if got := Closest("require", candidates, 2); got != "requires" {
+		t.Errorf("Closest(require) = %q, want requires", got)
+	}
+	if got := Closest("Varibles", candidates, 2); got != "variables" {
+		t.Errorf("Closest(Varibles) = %q, want variables", got)
+	}
+	if got := Closest("completely_different", candidates, 2); got != "" {
+		t.Errorf("Closest(completely_different) = %q, want empty", got)
+	}
+}
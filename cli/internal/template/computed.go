@@ -0,0 +1,85 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ApplyComputed evaluates computed variable templates and adds the results to
+// vars. Computed variables may reference each other and are evaluated in
+// dependency order; a cycle is an error. Values already present in vars
+// (e.g. supplied via --var) are kept as-is.
+func ApplyComputed(computed map[string]string, vars map[string]string) error {
+	order, err := computedOrder(computed)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		vars[name] = Substitute(computed[name], vars)
+	}
+	return nil
+}
+
+// computedOrder returns computed variable names in dependency order.
+// Independent variables are ordered alphabetically for determinism.
+func computedOrder(computed map[string]string) ([]string, error) {
+	names := make([]string, 0, len(computed))
+	for name := range computed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var order []string
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			// Trim the path to the start of the cycle
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("computed variable cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range References(computed[name]) {
+			if _, ok := computed[ref]; ok {
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,79 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyComputed_DependencyOrder(t *testing.T) {
+	computed := map[string]string{
+		"import_path": "{{ module_path }}/internal",
+		"module_path": "github.com/{{ org }}/{{ project_slug }}",
+		"image":       "{{ registry }}/{{ project_slug }}",
+	}
+	vars := map[string]string{
+		"org":          "acme",
+		"project_slug": "my_app",
+		"registry":     "gcr.io",
+	}
+
+	if err := ApplyComputed(computed, vars); err != nil {
+		t.Fatalf("ApplyComputed() error = %v", err)
+	}
+
+	want := map[string]string{
+		"module_path": "github.com/acme/my_app",
+		"import_path": "github.com/acme/my_app/internal",
+		"image":       "gcr.io/my_app",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("vars[%s] = %v, want %v", k, vars[k], v)
+		}
+	}
+}
+
+func TestApplyComputed_ExplicitValueWins(t *testing.T) {
+	computed := map[string]string{
+		"module_path": "github.com/{{ org }}/app",
+		"import_path": "{{ module_path }}/internal",
+	}
+	vars := map[string]string{
+		"org":         "acme",
+		"module_path": "example.com/custom",
+	}
+
+	if err := ApplyComputed(computed, vars); err != nil {
+		t.Fatalf("ApplyComputed() error = %v", err)
+	}
+	if vars["module_path"] != "example.com/custom" {
+		t.Errorf("module_path = %v, want explicit value", vars["module_path"])
+	}
+	if vars["import_path"] != "example.com/custom/internal" {
+		t.Errorf("import_path = %v, want %v", vars["import_path"], "example.com/custom/internal")
+	}
+}
+
+func TestApplyComputed_Cycle(t *testing.T) {
+	computed := map[string]string{
+		"a": "{{ b }}",
+		"b": "{{ c }}",
+		"c": "{{ a }}",
+		"d": "{{ org }}",
+	}
+
+	err := ApplyComputed(computed, map[string]string{"org": "acme"})
+	if err == nil {
+		t.Fatal("ApplyComputed() should return error for a cycle")
+	}
+	if !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("error = %v, want cycle path a -> b -> c -> a", err)
+	}
+}
+
+func TestApplyComputed_SelfReference(t *testing.T) {
+	err := ApplyComputed(map[string]string{"a": "x{{ a }}"}, map[string]string{})
+	if err == nil {
+		t.Error("ApplyComputed() should return error for a self reference")
+	}
+}
@@ -0,0 +1,114 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+// FileCount summarizes the files of a template
+type FileCount struct {
+	Files       int // Files that may be generated
+	Conditional int // Of those, files generated only when a condition holds
+	Excluded    int // Files left out by files.exclude or .scaffoldignore
+}
+
+// Count tallies the files of the template without generating anything. The
+// manifest, ignored names, partials and keep markers are not counted.
+func (p *Processor) Count() (FileCount, error) {
+	var count FileCount
+	ignored, err := readIgnoreFile(p.srcDir)
+	if err != nil {
+		return count, err
+	}
+	exclude, err := newExcludeMatcher(append(append([]string{}, p.manifest.Files.Exclude...), ignored...))
+	if err != nil {
+		return count, err
+	}
+
+	patterns, err := conditionPatterns(p.manifest.Files.Conditions, "file")
+	if err != nil {
+		return count, err
+	}
+	dirPatterns, err := conditionPatterns(p.manifest.Files.DirConditions, "directory")
+	if err != nil {
+		return count, err
+	}
+
+	err = filepath.Walk(p.srcDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(p.srcDir, filePath)
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(relPath)
+
+		switch {
+		case relPath == ".":
+			return nil
+		case info.IsDir() && (slashPath == PartialsDir || p.ignored(info.Name())):
+			return filepath.SkipDir
+		case info.IsDir(), p.ignored(info.Name()), info.Name() == KeepFile,
+			slashPath == config.ManifestFile, slashPath == IgnoreFile:
+			return nil
+		}
+
+		if exclude.excluded(slashPath, false) {
+			count.Excluded++
+			return nil
+		}
+		count.Files++
+		if conditional(slashPath, patterns) || p.guarded(slashPath, dirPatterns) {
+			count.Conditional++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// conditionPatterns returns the sorted glob patterns of a conditions map,
+// checking that they are valid
+func conditionPatterns(conditions map[string]string, kind string) ([]string, error) {
+	patterns := make([]string, 0, len(conditions))
+	for pattern := range conditions {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s condition pattern %q: %w", kind, pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return patterns, nil
+}
+
+// guarded reports whether one of a file's directories has a guard in its
+// name or matches a directory condition pattern
+func (p *Processor) guarded(slashPath string, dirPatterns []string) bool {
+	for dir := path.Dir(slashPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if _, _, ok := p.dirGuard(path.Base(dir)); ok {
+			return true
+		}
+		if conditional(dir, dirPatterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditional reports whether a file or one of its directories matches a
+// condition pattern
+func conditional(slashPath string, patterns []string) bool {
+	for p := slashPath; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, p); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestProcessor_Count(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"scaffold.yaml":          "name: test",
+		IgnoreFile:               "*.log\n",
+		"README.md":              "# app",
+		"docker/Dockerfile":      "FROM scratch",
+		"docker/nginx/nginx":     "server {}",
+		"ci/github/build.yml":    "on: push",
+		"debug.log":              "noise",
+		"notes/todo.txt":         "later",
+		"k8s__if_deploy/app.yml": "kind: Deployment",
+		"empty/" + KeepFile:      "",
+		PartialsDir + "/head.md": "{{ project_name }}",
+		".git/HEAD":              "ref: refs/heads/main",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{
+		Name: "test",
+		Files: config.FileConfig{
+			Exclude:    []string{"notes/"},
+			Conditions: map[string]string{"docker": "use_docker", "ci/*": "ci != none"},
+		},
+	}
+	got, err := NewProcessor(manifest, srcDir, t.TempDir()).Count()
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	want := FileCount{Files: 5, Conditional: 4, Excluded: 2}
+	if got != want {
+		t.Errorf("Count() = %+v, want %+v", got, want)
+	}
+}
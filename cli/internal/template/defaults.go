@@ -0,0 +1,23 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveDefault renders a variable default against the values known so far,
+// so a default such as "{{ project_slug }}_db" can build on earlier answers.
+// A default can only reference variables declared before it or already
+// supplied; any other reference is an error.
+func ResolveDefault(def string, vars map[string]string) (string, error) {
+	var missing []string
+	for _, ref := range References(def) {
+		if _, ok := vars[ref]; !ok {
+			missing = append(missing, ref)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("default %q references unknown variables: %s", def, strings.Join(missing, ", "))
+	}
+	return Substitute(def, vars), nil
+}
@@ -0,0 +1,39 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveDefault(t *testing.T) {
+	vars := map[string]string{"project_slug": "my_app", "org": "acme"}
+
+	tests := []struct {
+		def     string
+		want    string
+		wantErr string
+	}{
+		{def: "{{ project_slug }}_db", want: "my_app_db"},
+		{def: "github.com/{{org}}/{{ project_slug }}", want: "github.com/acme/my_app"},
+		{def: "literal", want: "literal"},
+		{def: "{{ region }}-{{ zone }}", wantErr: "region, zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.def, func(t *testing.T) {
+			got, err := ResolveDefault(tt.def, vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveDefault() error = %v, want unknown %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveDefault() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveDefault() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/makemore/scaffold/internal/condition"
+	"github.com/makemore/scaffold/internal/config"
+)
+
+// Template engines selectable with the manifest's engine field
+const (
+	// EngineSimple substitutes {{ variable }} placeholders only
+	EngineSimple = "simple"
+	// EngineGoTemplate renders files with Go's text/template, so templates
+	// can use conditionals, loops and {{- -}} trim markers
+	EngineGoTemplate = "gotemplate"
+)
+
+// funcs are available to gotemplate files
+var funcs = template.FuncMap{
+	// truthy treats "false", "no", "0" and friends as false, unlike {{ if }}
+	// which is true for any non-empty string
+	"truthy":      condition.IsTruthy,
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"title":       titleCase,
+	"module_path": ModulePath,
+	"slug":        Slug,
+}
+
+// invalidModuleChars matches runs of characters not allowed in a Go module
+// path element
+var invalidModuleChars = regexp.MustCompile(`[^a-z0-9._~-]+`)
+
+// dashRun matches repeated dashes left behind by replaced characters
+var dashRun = regexp.MustCompile(`-{2,}`)
+
+// ModulePath sanitizes s into a valid Go module path: it is lowercased,
+// characters a module path can't contain become dashes, and each
+// slash-separated element is trimmed of leading and trailing dots and dashes.
+// Empty elements are dropped.
+func ModulePath(s string) string {
+	var elems []string
+	for _, elem := range strings.Split(strings.ToLower(s), "/") {
+		elem = invalidModuleChars.ReplaceAllString(elem, "-")
+		elem = dashRun.ReplaceAllString(elem, "-")
+		elem = strings.Trim(elem, "-.")
+		if elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	return strings.Join(elems, "/")
+}
+
+// nonSlugChars matches runs of characters not allowed in a slug
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// latinFold spells accented Latin letters without their accents
+var latinFold = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// Slug turns s into an identifier that is valid as a package, module or
+// variable name in most languages: it is lowercased, accented Latin letters
+// lose their accents, and every run of other characters, spaces included,
+// becomes a single underscore, trimmed from the ends. A slug that would
+// start with a digit is prefixed with "project_", and one with nothing left
+// is "project".
+func Slug(s string) string {
+	slug := nonSlugChars.ReplaceAllString(latinFold.Replace(strings.ToLower(s)), "_")
+	slug = strings.Trim(slug, "_")
+	switch {
+	case slug == "":
+		return "project"
+	case slug[0] >= '0' && slug[0] <= '9':
+		return "project_" + slug
+	}
+	return slug
+}
+
+// PartialsDir is the template directory that include reads from. It is
+// never copied to the output.
+const PartialsDir = "_partials"
+
+// MaxIncludeDepth bounds nested includes so that a partial including itself
+// fails instead of recursing forever
+const MaxIncludeDepth = 10
+
+// Render renders content with the named engine. An empty engine is simple.
+func Render(engine, name, content string, vars map[string]string) (string, error) {
+	return (&Renderer{Engine: engine}).Render(name, content, vars)
+}
+
+// Renderer renders file contents with a template engine
+type Renderer struct {
+	Engine string
+	// Partials is the directory include reads from; include is unavailable
+	// when empty
+	Partials string
+	// Lists names the variables holding comma-separated lists, which
+	// gotemplate files see as []string so that they can range over them
+	Lists []string
+
+	values    interface{} // What gotemplate files render with, built once
+	valuesErr error
+	built     bool
+}
+
+// Render renders content, which came from the file called name. A Renderer
+// serves one set of variables: what gotemplate files see of them is worked
+// out on the first render and reused after that.
+func (r *Renderer) Render(name, content string, vars map[string]string) (string, error) {
+	switch r.Engine {
+	case "", EngineSimple:
+		return Substitute(content, vars), nil
+	case EngineGoTemplate:
+		return r.renderGoTemplate(name, content, vars, 0)
+	default:
+		return "", fmt.Errorf("unknown template engine %q", r.Engine)
+	}
+}
+
+func (r *Renderer) renderGoTemplate(name, content string, vars map[string]string, depth int) (string, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=zero").
+		Funcs(funcs).
+		Funcs(template.FuncMap{
+			"include": func(partial string) (string, error) {
+				return r.include(partial, vars, depth+1)
+			},
+		}).
+		Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	if !r.built {
+		r.values, r.valuesErr = r.data(vars)
+		r.built = true
+	}
+	data, err := r.values, r.valuesErr
+	if err != nil {
+		return "", err
+	}
+	if values, ok := data.(map[string]interface{}); ok {
+		for _, t := range tmpl.Templates() {
+			seedFields(t.Root, values, values)
+		}
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// data returns the values a gotemplate file renders with. Without list or
+// dotted variables that is vars itself; with them, list variables become
+// []string, unset ones empty, and dotted ones are nested as by Nest.
+func (r *Renderer) data(vars map[string]string) (interface{}, error) {
+	dotted := hasDotted(vars)
+	if len(r.Lists) == 0 && !dotted {
+		return vars, nil
+	}
+
+	data := make(map[string]interface{}, len(vars))
+	if dotted {
+		nested, err := Nest(vars)
+		if err != nil {
+			return nil, err
+		}
+		data = nested
+	} else {
+		for name, value := range vars {
+			data[name] = value
+		}
+	}
+	for _, name := range r.Lists {
+		data[name] = config.SplitList(vars[name])
+	}
+	return data, nil
+}
+
+// seedFields sets the variables a template refers to but that are missing
+// from values to the empty string, so that they render empty as they do over
+// a map of strings, rather than as <no value>. A missing variable nested
+// under a set one, as in .database.port or .port within {{ with .database }},
+// is seeded too. dot is the map . refers to, nil where that isn't known.
+func seedFields(node parse.Node, values, dot map[string]interface{}) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			seedFields(child, values, dot)
+		}
+	case *parse.ActionNode:
+		seedFields(n.Pipe, values, dot)
+	case *parse.IfNode:
+		seedFields(n.Pipe, values, dot)
+		seedFields(n.List, values, dot)
+		seedFields(n.ElseList, values, dot)
+	case *parse.RangeNode:
+		seedFields(n.Pipe, values, dot)
+		seedFields(n.List, values, nil)
+		seedFields(n.ElseList, values, dot)
+	case *parse.WithNode:
+		seedFields(n.Pipe, values, dot)
+		seedFields(n.List, values, pipeMap(n.Pipe, dot))
+		seedFields(n.ElseList, values, dot)
+	case *parse.TemplateNode:
+		seedFields(n.Pipe, values, dot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				seedFields(arg, values, dot)
+			}
+		}
+	case *parse.ChainNode:
+		seedFields(n.Node, values, dot)
+	case *parse.FieldNode:
+		if dot != nil {
+			seedPath(n.Ident, dot)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			seedPath(n.Ident[1:], values)
+		}
+	}
+}
+
+// pipeMap returns the map a pipeline made of a single field refers to, nil
+// for any other pipeline
+func pipeMap(pipe *parse.PipeNode, dot map[string]interface{}) map[string]interface{} {
+	if dot == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok {
+		return nil
+	}
+	node := dot
+	for _, name := range field.Ident {
+		if node, ok = node[name].(map[string]interface{}); !ok {
+			return nil
+		}
+	}
+	return node
+}
+
+// seedPath seeds the last name of a field path when the maps leading to it
+// exist
+func seedPath(path []string, values map[string]interface{}) {
+	node := values
+	for _, name := range path[:len(path)-1] {
+		child, ok := node[name].(map[string]interface{})
+		if !ok {
+			return
+		}
+		node = child
+	}
+	if _, ok := node[path[len(path)-1]]; !ok {
+		node[path[len(path)-1]] = ""
+	}
+}
+
+// include renders a partial, named relative to the partials directory
+func (r *Renderer) include(partial string, vars map[string]string, depth int) (string, error) {
+	if r.Partials == "" {
+		return "", fmt.Errorf("include %q: no partials directory", partial)
+	}
+	if depth > MaxIncludeDepth {
+		return "", fmt.Errorf("include %q: includes nested more than %d deep", partial, MaxIncludeDepth)
+	}
+
+	rel := path.Clean(strings.TrimPrefix(partial, PartialsDir+"/"))
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("include %q: path outside %s", partial, PartialsDir)
+	}
+
+	content, err := os.ReadFile(filepath.Join(r.Partials, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", fmt.Errorf("include %q: %w", partial, err)
+	}
+	return r.renderGoTemplate(rel, string(content), vars, depth)
+}
+
+// blankRun matches two or more consecutive blank lines
+var blankRun = regexp.MustCompile(`\n([ \t]*\n){2,}`)
+
+// Line ending styles selectable with the manifest's line_endings field
+const (
+	// LineEndingsPreserve keeps line endings as they are in the template
+	LineEndingsPreserve = "preserve"
+	LineEndingsLF       = "lf"
+	LineEndingsCRLF     = "crlf"
+)
+
+// NormalizeLineEndings converts every line ending in content to the given
+// style, leaving content as it is for preserve or no style
+func NormalizeLineEndings(content, style string) string {
+	switch style {
+	case LineEndingsLF:
+		return strings.ReplaceAll(content, "\r\n", "\n")
+	case LineEndingsCRLF:
+		return strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	}
+	return content
+}
+
+// TrimBlankLines collapses runs of blank lines, such as those left behind by
+// removed conditional blocks, into a single blank line
+func TrimBlankLines(content string) string {
+	return blankRun.ReplaceAllString(content, "\n\n")
+}
@@ -0,0 +1,307 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// composeTemplate has a conditional section that leaves blank lines behind
+// when disabled
+const composeTemplate = `services:
+  app:
+    image: {{ .project_slug }}
+
+{{ if truthy .use_redis }}
+  redis:
+    image: redis:7
+{{ end }}
+
+volumes: {}
+`
+
+func TestRender_GoTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		vars map[string]string
+		trim bool
+		want string
+	}{
+		{
+			name: "enabled section",
+			tmpl: composeTemplate,
+			vars: map[string]string{"project_slug": "app", "use_redis": "yes"},
+			want: "services:\n  app:\n    image: app\n\n\n  redis:\n    image: redis:7\n\n\nvolumes: {}\n",
+		},
+		{
+			name: "disabled section without trimming",
+			tmpl: composeTemplate,
+			vars: map[string]string{"project_slug": "app", "use_redis": "false"},
+			want: "services:\n  app:\n    image: app\n\n\n\nvolumes: {}\n",
+		},
+		{
+			name: "disabled section with trim_blank_lines",
+			tmpl: composeTemplate,
+			vars: map[string]string{"project_slug": "app", "use_redis": "false"},
+			trim: true,
+			want: "services:\n  app:\n    image: app\n\nvolumes: {}\n",
+		},
+		{
+			name: "trim markers",
+			tmpl: "a:\n{{- if truthy .flag }}\n  b: 1\n{{- end }}\nc: 2\n",
+			vars: map[string]string{"flag": "true"},
+			want: "a:\n  b: 1\nc: 2\n",
+		},
+		{
+			name: "trim markers with section removed",
+			tmpl: "a:\n{{- if truthy .flag }}\n  b: 1\n{{- end }}\nc: 2\n",
+			vars: map[string]string{"flag": "false"},
+			want: "a:\nc: 2\n",
+		},
+		{
+			name: "missing variable renders empty",
+			tmpl: "name={{ .missing }}",
+			vars: map[string]string{},
+			want: "name=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(EngineGoTemplate, "test", tt.tmpl, tt.vars)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if tt.trim {
+				got = TrimBlankLines(got)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_Engines(t *testing.T) {
+	vars := map[string]string{"name": "app"}
+
+	if got, _ := Render("", "f", "{{ name }}", vars); got != "app" {
+		t.Errorf("default engine = %q, want app", got)
+	}
+	if _, err := Render(EngineGoTemplate, "f", "{{ if }}", vars); err == nil {
+		t.Error("Render() should return error for an invalid gotemplate")
+	}
+	if _, err := Render("jinja", "f", "", vars); err == nil {
+		t.Error("Render() should return error for an unknown engine")
+	}
+}
+
+func TestRender_ListVariable(t *testing.T) {
+	vars := map[string]string{"name": "app", "services": "api, web,,worker"}
+	content := "{{ .name }}:{{ range $i, $s := .services }} {{ $i }}={{ $s }}{{ end }} ({{ len .services }})"
+
+	r := &Renderer{Engine: EngineGoTemplate, Lists: []string{"services", "unset"}}
+	got, err := r.Render("f", content, vars)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "app: 0=api 1=web 2=worker (3)"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	// Simple substitution sees the list as it was given
+	if got, _ := Render(EngineSimple, "f", "{{ services }}", vars); got != "api, web,,worker" {
+		t.Errorf("simple Render() = %q, want the raw value", got)
+	}
+}
+
+func TestRender_MissingVariable(t *testing.T) {
+	content := `{{ .name }}[{{ .missing }}]{{ if eq .missing "" }} empty{{ end }}`
+	want := "app[] empty"
+
+	tests := []struct {
+		name  string
+		lists []string
+		vars  map[string]string
+	}{
+		{"plain", nil, map[string]string{"name": "app"}},
+		{"list variable", []string{"services"}, map[string]string{"name": "app", "services": "api"}},
+		{"dotted variable", nil, map[string]string{"name": "app", "database.host": "db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Renderer{Engine: EngineGoTemplate, Lists: tt.lists}
+			got, err := r.Render("f", content, tt.vars)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("Render() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// A missing variable under a dotted one renders empty as well
+	r := &Renderer{Engine: EngineGoTemplate}
+	vars := map[string]string{"database.host": "db"}
+	got, err := r.Render("f", "{{ .database.host }}:{{ .database.port }}{{ with .database }}/{{ .port }}{{ end }}", vars)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "db:/"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"github.com/acme/my_app", "github.com/acme/my_app"},
+		{"github.com/Acme Corp/My App", "github.com/acme-corp/my-app"},
+		{"GitHub.com/ACME/API", "github.com/acme/api"},
+		{"example.com/a b - c", "example.com/a-b-c"},
+		{"example.com/café!/app@v2", "example.com/caf/app-v2"},
+		{"example.com//-app-/.hidden.", "example.com/app/hidden"},
+		{"  spaced  ", "spaced"},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ModulePath(tt.in); got != tt.want {
+			t.Errorf("ModulePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"my-app", "my_app"},
+		{"My App 2", "my_app_2"},
+		{"  lots   of\tspace ", "lots_of_space"},
+		{"2048 Game", "project_2048_game"},
+		{"42", "project_42"},
+		{"Café Über-Straße", "cafe_uber_strasse"},
+		{"naïve.io", "naive_io"},
+		{"日本 app", "app"},
+		{"日本", "project"},
+		{"__init__", "init"},
+		{"a--b__c", "a_b_c"},
+	}
+
+	for _, tt := range tests {
+		if got := Slug(tt.in); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRender_SlugFilter(t *testing.T) {
+	got, err := Render(EngineGoTemplate, "setup.py", `name="{{ .name | slug }}"`, map[string]string{"name": "My App 2"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got != `name="my_app_2"` {
+		t.Errorf("Render() = %q, want name=\"my_app_2\"", got)
+	}
+}
+
+func TestRender_ModulePathFilter(t *testing.T) {
+	vars := map[string]string{"org": "Acme Corp", "name": "My App"}
+	got, err := Render(EngineGoTemplate, "go.mod", `module {{ printf "github.com/%s/%s" .org .name | module_path }}`, vars)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got != "module github.com/acme-corp/my-app" {
+		t.Errorf("Render() = %q, want module github.com/acme-corp/my-app", got)
+	}
+}
+
+func TestRender_Include(t *testing.T) {
+	partials := t.TempDir()
+	files := map[string]string{
+		"header.txt":      "# {{ .project_name }} - {{ .license }}",
+		"ci/steps.yml":    "steps:\n{{ include \"ci/checkout.yml\" }}",
+		"ci/checkout.yml": "  - uses: actions/checkout@v4",
+		"loop.txt":        "{{ include \"loop.txt\" }}",
+	}
+	for name, content := range files {
+		path := filepath.Join(partials, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write partial: %v", err)
+		}
+	}
+
+	r := &Renderer{Engine: EngineGoTemplate, Partials: partials}
+	vars := map[string]string{"project_name": "myapp", "license": "MIT"}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "simple include",
+			tmpl: "{{ include \"header.txt\" }}\nprint('hi')\n",
+			want: "# myapp - MIT\nprint('hi')\n",
+		},
+		{
+			name: "nested include",
+			tmpl: "name: ci\n{{ include \"_partials/ci/steps.yml\" }}\n",
+			want: "name: ci\nsteps:\n  - uses: actions/checkout@v4\n",
+		},
+		{
+			name:    "depth limit",
+			tmpl:    "{{ include \"loop.txt\" }}",
+			wantErr: "nested more than",
+		},
+		{
+			name:    "outside partials",
+			tmpl:    "{{ include \"../secret\" }}",
+			wantErr: "outside",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Render("main", tt.tmpl, vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	mixed := "a\r\nb\nc\r\n\r\nd"
+	tests := map[string]string{
+		"":                  mixed,
+		LineEndingsPreserve: mixed,
+		LineEndingsLF:       "a\nb\nc\n\nd",
+		LineEndingsCRLF:     "a\r\nb\r\nc\r\n\r\nd",
+	}
+	for style, want := range tests {
+		if got := NormalizeLineEndings(mixed, style); got != want {
+			t.Errorf("NormalizeLineEndings(%q) = %q, want %q", style, got, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ProcessError reports a failure to process a template file
+type ProcessError struct {
+	File string // Slash-separated, relative to the template root
+	Line int    // 1-based, or 0 when unknown
+	Err  error
+}
+
+func (e *ProcessError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
+
+// templateLine extracts the line from text/template errors, which look like
+// "template: name:3: ..." or "template: name:3:14: ..."
+var templateLine = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+
+func newProcessError(file string, err error) *ProcessError {
+	pe := &ProcessError{File: file, Err: err}
+	if m := templateLine.FindStringSubmatch(err.Error()); m != nil {
+		pe.Line, _ = strconv.Atoi(m[1])
+	}
+	return pe
+}
@@ -0,0 +1,33 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestProcess_ProcessError(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "config"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	content := "name: {{ .project_name }}\n\nport: {{ .port | nosuchfunc }}\n"
+	if err := os.WriteFile(filepath.Join(srcDir, "config", "app.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	manifest := &config.Manifest{Engine: EngineGoTemplate}
+	_, err := NewProcessor(manifest, srcDir, t.TempDir()).Process(context.Background())
+
+	var pe *ProcessError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Process() error = %v, want *ProcessError", err)
+	}
+	if pe.File != "config/app.yaml" || pe.Line != 3 {
+		t.Errorf("ProcessError = %s:%d, want config/app.yaml:3", pe.File, pe.Line)
+	}
+}
@@ -0,0 +1,156 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile holds more exclude patterns in a template's root, one per line,
+// applied after the manifest's. Blank lines and lines starting with # are
+// skipped. The file itself is never generated.
+const IgnoreFile = ".scaffoldignore"
+
+// readIgnoreFile returns the patterns in dir's IgnoreFile, if there is one
+func readIgnoreFile(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFile, err)
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, nil
+}
+
+// excludeRule is a single gitignore-style exclude pattern
+type excludeRule struct {
+	segments []string // Slash-separated pattern segments, ** matching any depth
+	negate   bool     // Pattern started with !, re-including matches
+	dirOnly  bool     // Pattern ended with /, matching directories only
+}
+
+// excludeMatcher applies exclude patterns in order, like gitignore: the last
+// pattern matching a path decides, and a pattern matching a directory also
+// matches everything below it. A pattern without a slash matches at any
+// depth; one with a slash is relative to the template root.
+type excludeMatcher []excludeRule
+
+func newExcludeMatcher(patterns []string) (excludeMatcher, error) {
+	var m excludeMatcher
+	for _, pattern := range patterns {
+		rule := excludeRule{}
+		p := pattern
+		if rest, ok := strings.CutPrefix(p, "!"); ok {
+			rule.negate = true
+			p = rest
+		}
+		if rest, ok := strings.CutSuffix(p, "/"); ok {
+			rule.dirOnly = true
+			p = rest
+		}
+		if !strings.Contains(p, "/") {
+			p = "**/" + p
+		}
+		p = strings.TrimPrefix(p, "/")
+		if p == "" {
+			return nil, fmt.Errorf("invalid exclude pattern %q", pattern)
+		}
+
+		rule.segments = strings.Split(p, "/")
+		for _, seg := range rule.segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+		}
+		m = append(m, rule)
+	}
+	return m, nil
+}
+
+// excluded reports whether relPath, a slash-separated path relative to the
+// template root, is excluded
+func (m excludeMatcher) excluded(relPath string, isDir bool) bool {
+	excluded := false
+	for _, rule := range m {
+		if rule.matches(relPath, isDir) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
+
+// reincludable reports whether a negated pattern could match something below
+// the directory dir, so that an excluded directory must still be walked
+func (m excludeMatcher) reincludable(dir string) bool {
+	dirSegments := strings.Split(dir, "/")
+	for _, rule := range m {
+		if rule.negate && matchesBelow(rule.segments, dirSegments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the rule matches relPath or one of its parent
+// directories
+func (r excludeRule) matches(relPath string, isDir bool) bool {
+	segments := strings.Split(relPath, "/")
+	for i := 1; i <= len(segments); i++ {
+		// Every proper prefix of the path is a directory
+		prefixIsDir := i < len(segments) || isDir
+		if r.dirOnly && !prefixIsDir {
+			continue
+		}
+		if matchSegments(r.segments, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where **
+// matches zero or more whole segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], segments[0])
+	return ok && matchSegments(pattern[1:], segments[1:])
+}
+
+// matchesBelow reports whether pattern could match a path strictly below the
+// directory given by dir
+func matchesBelow(pattern, dir []string) bool {
+	switch {
+	case len(dir) == 0:
+		return len(pattern) > 0
+	case len(pattern) == 0:
+		return false
+	case pattern[0] == "**":
+		return true
+	}
+	ok, _ := path.Match(pattern[0], dir[0])
+	return ok && matchesBelow(pattern[1:], dir[1:])
+}
@@ -0,0 +1,60 @@
+package template
+
+import "testing"
+
+func TestExcludeMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{name: "basename at any depth", patterns: []string{"*.pyc"}, path: "app/mod/x.pyc", want: true},
+		{name: "directory contents", patterns: []string{"__pycache__"}, path: "app/__pycache__/x", want: true},
+		{name: "anchored", patterns: []string{"build/*.log"}, path: "src/build/a.log", want: false},
+		{name: "double star", patterns: []string{"docs/**/*.tmp"}, path: "docs/a/b/c.tmp", want: true},
+		{name: "dir only skips files", patterns: []string{"cache/"}, path: "cache", want: false},
+		{name: "dir only matches dirs", patterns: []string{"cache/"}, path: "cache", isDir: true, want: true},
+		{name: "negated", patterns: []string{"node_modules/**", "!node_modules/.keep"}, path: "node_modules/.keep", want: false},
+		{name: "negation keeps siblings excluded", patterns: []string{"node_modules/**", "!node_modules/.keep"}, path: "node_modules/x.js", want: true},
+		{name: "later pattern wins", patterns: []string{"!a.txt", "*.txt"}, path: "a.txt", want: true},
+		{name: "unmatched", patterns: []string{"*.pyc"}, path: "main.py", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newExcludeMatcher(tt.patterns)
+			if err != nil {
+				t.Fatalf("newExcludeMatcher() error = %v", err)
+			}
+			if got := m.excluded(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("excluded(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeMatcher_Reincludable(t *testing.T) {
+	m, err := newExcludeMatcher([]string{"vendor", "!vendor/keep/**", "dist"})
+	if err != nil {
+		t.Fatalf("newExcludeMatcher() error = %v", err)
+	}
+
+	for dir, want := range map[string]bool{
+		"vendor":      true,
+		"vendor/keep": true,
+		"vendor/lib":  false,
+		"dist":        false,
+	} {
+		if got := m.reincludable(dir); got != want {
+			t.Errorf("reincludable(%q) = %v, want %v", dir, got, want)
+		}
+	}
+}
+
+func TestExcludeMatcher_InvalidPattern(t *testing.T) {
+	if _, err := newExcludeMatcher([]string{"[a-"}); err == nil {
+		t.Error("newExcludeMatcher() should return error for a malformed pattern")
+	}
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/makemore/scaffold/internal/condition"
+)
+
+// GuardMarker separates a directory's name from the condition guarding it.
+// k8s__if_deploy_target_kubernetes is generated as k8s, and only when
+// deploy_target == kubernetes; docs__if_with_docs only when with_docs is
+// truthy.
+const GuardMarker = "__if_"
+
+// dirGuard splits a directory name into the name it is generated as and the
+// condition guarding it. The guard is a variable name, optionally followed
+// by _ and the value it must equal; the longest known variable it starts
+// with is taken as the name.
+func (p *Processor) dirGuard(name string) (string, string, bool) {
+	i := strings.LastIndex(name, GuardMarker)
+	if i <= 0 || i+len(GuardMarker) == len(name) {
+		return name, "", false
+	}
+	base, guard := name[:i], name[i+len(GuardMarker):]
+
+	for end := len(guard); end > 0; end = strings.LastIndexByte(guard[:end], '_') {
+		if !p.known(guard[:end]) {
+			continue
+		}
+		if end == len(guard) {
+			return base, guard, true
+		}
+		return base, guard[:end] + " == " + quoteValue(guard[end+1:]), true
+	}
+	// An unknown variable is unset, so the directory is left out
+	return base, guard, true
+}
+
+// known reports whether name is a variable of the manifest or one set on
+// the processor
+func (p *Processor) known(name string) bool {
+	if _, ok := p.variables[name]; ok {
+		return true
+	}
+	for _, v := range p.manifest.Variables {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteValue quotes a value for a condition, which has no escapes
+func quoteValue(value string) string {
+	if strings.Contains(value, "'") {
+		return `"` + value + `"`
+	}
+	return "'" + value + "'"
+}
+
+// stripGuards removes the guards from the directory names in a
+// slash-separated path. The name of a file is left as it is.
+func (p *Processor) stripGuards(slashPath string, isDir bool) string {
+	segments := strings.Split(slashPath, "/")
+	dirs := len(segments)
+	if !isDir {
+		dirs--
+	}
+	for i := 0; i < dirs; i++ {
+		segments[i], _, _ = p.dirGuard(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// dirIncluded evaluates the guard in a directory's name and the manifest's
+// dir_conditions whose glob matches its path. All of them must hold.
+func (p *Processor) dirIncluded(relPath string) (bool, error) {
+	var exprs []string
+	if _, guard, ok := p.dirGuard(path.Base(relPath)); ok {
+		exprs = append(exprs, guard)
+	}
+
+	conditions := p.manifest.Files.DirConditions
+	patterns := make([]string, 0, len(conditions))
+	for pattern := range conditions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, relPath)
+		if err != nil {
+			return false, fmt.Errorf("invalid directory condition pattern %q: %w", pattern, err)
+		}
+		if matched {
+			exprs = append(exprs, conditions[pattern])
+		}
+	}
+
+	for _, expr := range exprs {
+		ok, err := condition.Evaluate(expr, p.variables)
+		if err != nil {
+			return false, fmt.Errorf("condition for %s/: %w", relPath, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,124 @@
+package template
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestDirGuard(t *testing.T) {
+	manifest := &config.Manifest{Variables: []config.Variable{{Name: "deploy_target"}, {Name: "deploy"}, {Name: "with_docs"}}}
+	p := NewProcessor(manifest, "", "")
+
+	tests := []struct {
+		name     string
+		wantName string
+		wantExpr string
+		wantOK   bool
+	}{
+		{"k8s__if_deploy_target_kubernetes", "k8s", "deploy_target == 'kubernetes'", true},
+		{"k8s__if_deploy_target_k8s_on_prem", "k8s", "deploy_target == 'k8s_on_prem'", true},
+		{"deploy__if_deploy_yes", "deploy", "deploy == 'yes'", true},
+		{"docs__if_with_docs", "docs", "with_docs", true},
+		{"extra__if_unknown_var", "extra", "unknown_var", true},
+		{"k8s", "k8s", "", false},
+		{"__if_with_docs", "__if_with_docs", "", false},
+		{"docs__if_", "docs__if_", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, expr, ok := p.dirGuard(tt.name)
+			if name != tt.wantName || expr != tt.wantExpr || ok != tt.wantOK {
+				t.Errorf("dirGuard(%q) = %q, %q, %v, want %q, %q, %v", tt.name, name, expr, ok, tt.wantName, tt.wantExpr, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProcessor_DirConditions(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"README.md": "# app",
+		"k8s__if_deploy_target_kubernetes/deployment.yaml":                   "kind: Deployment",
+		"k8s__if_deploy_target_kubernetes/__project_name__/values.yaml":      "name: {{ project_name }}",
+		"k8s__if_deploy_target_kubernetes/monitoring__if_metrics/grafana.js": "{}",
+		"docs/index.md": "# docs",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{
+		Name:      "test",
+		Variables: []config.Variable{{Name: "deploy_target"}, {Name: "metrics"}, {Name: "with_docs"}},
+		Files: config.FileConfig{
+			DirConditions: map[string]string{"docs": "with_docs"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		vars        map[string]string
+		present     []string
+		absent      []string
+		wantSkipped []string
+	}{
+		{
+			name:    "included",
+			vars:    map[string]string{"project_name": "app", "deploy_target": "kubernetes", "metrics": "yes", "with_docs": "true"},
+			present: []string{"README.md", "k8s/deployment.yaml", "k8s/app/values.yaml", "k8s/monitoring/grafana.js", "docs/index.md"},
+			absent:  []string{"k8s__if_deploy_target_kubernetes", "k8s/monitoring__if_metrics"},
+		},
+		{
+			name:        "nested guard excluded",
+			vars:        map[string]string{"project_name": "app", "deploy_target": "kubernetes", "metrics": "no", "with_docs": "true"},
+			present:     []string{"k8s/deployment.yaml", "docs/index.md"},
+			absent:      []string{"k8s/monitoring"},
+			wantSkipped: []string{"k8s__if_deploy_target_kubernetes/monitoring__if_metrics/"},
+		},
+		{
+			name:        "excluded",
+			vars:        map[string]string{"project_name": "app", "deploy_target": "nomad", "metrics": "yes"},
+			present:     []string{"README.md"},
+			absent:      []string{"k8s", "k8s__if_deploy_target_kubernetes", "docs"},
+			wantSkipped: []string{"docs/", "k8s__if_deploy_target_kubernetes/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			p := NewProcessor(manifest, srcDir, destDir)
+			p.SetVariables(tt.vars)
+			result, err := p.Process(context.Background())
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			for _, path := range tt.present {
+				if _, err := os.Stat(filepath.Join(destDir, path)); err != nil {
+					t.Errorf("%s should be generated", path)
+				}
+			}
+			for _, path := range tt.absent {
+				if _, err := os.Stat(filepath.Join(destDir, path)); !os.IsNotExist(err) {
+					t.Errorf("%s should not be generated", path)
+				}
+			}
+			if !reflect.DeepEqual(result.Skipped, tt.wantSkipped) {
+				t.Errorf("Skipped = %v, want %v", result.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
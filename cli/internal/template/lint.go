@@ -0,0 +1,154 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+var (
+	// wordPattern matches anything that may name a variable
+	wordPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	// actionPattern and fieldPattern find the .variable fields used in Go
+	// template actions
+	actionPattern = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	fieldPattern  = regexp.MustCompile(`\.([a-zA-Z_][a-zA-Z0-9_]*)`)
+)
+
+// Lint reports likely mistakes in the template in dir, on top of the
+// manifest's own: variables nothing refers to, and variables that files use
+// but that have neither a default nor required set, which stay unset when
+// generating with --no-prompt.
+func Lint(manifest *config.Manifest, dir string) ([]string, error) {
+	p := NewProcessor(manifest, dir, "")
+	words := make(map[string]bool)
+	for _, text := range manifestText(manifest) {
+		addWords(words, text)
+	}
+	usedIn := make(map[string]string) // Variable -> first file using it
+	use := func(names []string, file string) {
+		for _, name := range names {
+			words[name] = true // __name__ in paths is a single word
+			if _, ok := usedIn[name]; !ok {
+				usedIn[name] = file
+			}
+		}
+	}
+
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		slashPath := filepath.ToSlash(relPath)
+
+		switch {
+		case relPath == ".", slashPath == config.ManifestFile:
+			return nil
+		case p.ignored(info.Name()):
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		addWords(words, slashPath)
+		use(pathReferences(slashPath), slashPath)
+		if _, guard, ok := p.dirGuard(info.Name()); ok && info.IsDir() {
+			addWords(words, guard)
+		}
+		if info.IsDir() || isBinary(filePath) {
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", slashPath, err)
+		}
+		addWords(words, string(data))
+		use(contentReferences(manifest.Engine, string(data)), slashPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := manifest.Lint()
+	for _, v := range manifest.Variables {
+		if !words[v.Name] {
+			warnings = append(warnings, fmt.Sprintf("variable %s is declared but never used", v.Name))
+			continue
+		}
+		if file, ok := usedIn[v.Name]; ok && v.Default == "" && !v.Required {
+			warnings = append(warnings, fmt.Sprintf("%s uses %s, which has no default and isn't required, so it is left unset with --no-prompt", file, v.Name))
+		}
+	}
+	return warnings, nil
+}
+
+// manifestText returns the manifest settings that may refer to variables
+func manifestText(m *config.Manifest) []string {
+	var text []string
+	for _, v := range m.Variables {
+		text = append(text, v.Default, v.ShowIf)
+	}
+	for _, expr := range m.Computed {
+		text = append(text, expr)
+	}
+	for pattern, cond := range m.Files.Conditions {
+		text = append(text, pattern, cond)
+	}
+	for pattern, cond := range m.Files.DirConditions {
+		text = append(text, pattern, cond)
+	}
+	for from, to := range m.Files.Rename {
+		text = append(text, from, to)
+	}
+	for _, include := range m.Files.ExternalIncludes {
+		text = append(text, include.To)
+	}
+	for _, a := range m.Actions {
+		text = append(text, a.Command, a.Message, a.Condition, a.WorkDir, a.CommitMessage)
+		text = append(text, a.Args...)
+		for _, value := range a.Env {
+			text = append(text, value)
+		}
+	}
+	return text
+}
+
+// addWords adds every identifier in text to words
+func addWords(words map[string]bool, text string) {
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		words[word] = true
+	}
+}
+
+// pathReferences returns the variables substituted into a path
+func pathReferences(slashPath string) []string {
+	var names []string
+	for _, m := range pathVariablePattern.FindAllStringSubmatch(slashPath, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// contentReferences returns the variables a file's content renders with the
+// given engine
+func contentReferences(engine, content string) []string {
+	if engine != EngineGoTemplate {
+		return References(content)
+	}
+	var names []string
+	for _, action := range actionPattern.FindAllString(content, -1) {
+		for _, m := range fieldPattern.FindAllStringSubmatch(action, -1) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
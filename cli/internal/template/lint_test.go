@@ -0,0 +1,95 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/config"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest *config.Manifest
+		files    map[string]string
+		want     []string
+	}{
+		{
+			name: "clean",
+			manifest: &config.Manifest{
+				Variables: []config.Variable{
+					{Name: "org", Default: "acme"},
+					{Name: "port", Required: true},
+					{Name: "use_docker", Type: "bool"},
+					{Name: "ci", Type: "choice", Choices: []string{"github", "none"}, Default: "none"},
+				},
+				Files: config.FileConfig{Conditions: map[string]string{"Dockerfile": "use_docker"}},
+			},
+			files: map[string]string{
+				"README.md":           "# {{ org }} on {{ port }}",
+				"Dockerfile":          "FROM scratch",
+				"__ci__/workflow.yml": "on: push",
+			},
+		},
+		{
+			name:     "unused variable",
+			manifest: &config.Manifest{Variables: []config.Variable{{Name: "org", Default: "acme"}, {Name: "license", Default: "MIT"}}},
+			files:    map[string]string{"README.md": "# {{ org }}"},
+			want:     []string{"variable license is declared but never used"},
+		},
+		{
+			name:     "unset without a prompt",
+			manifest: &config.Manifest{Variables: []config.Variable{{Name: "org"}}},
+			files:    map[string]string{"docs/README.md": "# {{ org }}"},
+			want:     []string{"docs/README.md uses org, which has no default and isn't required, so it is left unset with --no-prompt"},
+		},
+		{
+			name:     "unset in a path",
+			manifest: &config.Manifest{Variables: []config.Variable{{Name: "app"}}},
+			files:    map[string]string{"__app__/main.go": "package main"},
+			want:     []string{"__app__ uses app, which has no default and isn't required, so it is left unset with --no-prompt"},
+		},
+		{
+			name: "gotemplate fields",
+			manifest: &config.Manifest{
+				Engine:    EngineGoTemplate,
+				Variables: []config.Variable{{Name: "services", Type: "list"}, {Name: "org", Default: "acme"}},
+			},
+			files: map[string]string{"README.md": "{{ range .services }}{{ . }}{{ end }} by {{ .org }}"},
+			want:  []string{"README.md uses services, which has no default and isn't required, so it is left unset with --no-prompt"},
+		},
+		{
+			name: "manifest rules",
+			manifest: &config.Manifest{
+				Variables: []config.Variable{{Name: "db", Type: "choice", Choices: []string{"postgres"}, Default: "postgres"}},
+				Actions:   []config.Action{{Name: "migrate", Type: "command", Condition: "db == postgres"}},
+			},
+			want: []string{"variable db has a single choice, postgres", "action migrate has no command"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for path, content := range tt.files {
+				fullPath := filepath.Join(dir, path)
+				if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+					t.Fatalf("Failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write file: %v", err)
+				}
+			}
+
+			got, err := Lint(tt.manifest, dir)
+			if err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Nest builds nested maps from dotted variable names, so that gotemplate
+// files can write {{ .database.host }} for a value set as database.host.
+// Names without dots are kept as they are. A name that holds a value and is
+// also the parent of dotted names, such as database next to database.host,
+// is an error.
+func Nest(vars map[string]string) (map[string]interface{}, error) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names) // A parent sorts before its children
+
+	nested := make(map[string]interface{}, len(vars))
+	for _, name := range names {
+		parts := strings.Split(name, ".")
+		node := nested
+		for i, part := range parts[:len(parts)-1] {
+			switch child := node[part].(type) {
+			case nil:
+				m := make(map[string]interface{})
+				node[part] = m
+				node = m
+			case map[string]interface{}:
+				node = child
+			default:
+				return nil, fmt.Errorf("%s conflicts with %s", name, strings.Join(parts[:i+1], "."))
+			}
+		}
+
+		leaf := parts[len(parts)-1]
+		if _, ok := node[leaf].(map[string]interface{}); ok {
+			return nil, fmt.Errorf("%s conflicts with the values nested under it", name)
+		}
+		node[leaf] = vars[name]
+	}
+	return nested, nil
+}
+
+// hasDotted reports whether any variable name is dotted
+func hasDotted(vars map[string]string) bool {
+	for name := range vars {
+		if strings.Contains(name, ".") {
+			return true
+		}
+	}
+	return false
+}
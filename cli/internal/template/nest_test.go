@@ -0,0 +1,88 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNest(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "flat",
+			vars: map[string]string{"org": "acme"},
+			want: map[string]interface{}{"org": "acme"},
+		},
+		{
+			name: "nested and merged",
+			vars: map[string]string{"org": "acme", "database.host": "localhost", "database.port": "5432", "database.pool.size": "10"},
+			want: map[string]interface{}{
+				"org": "acme",
+				"database": map[string]interface{}{
+					"host": "localhost",
+					"port": "5432",
+					"pool": map[string]interface{}{"size": "10"},
+				},
+			},
+		},
+		{
+			name:    "value and parent",
+			vars:    map[string]string{"database": "postgres", "database.host": "localhost"},
+			wantErr: "database.host conflicts with database",
+		},
+		{
+			name:    "deeper conflict",
+			vars:    map[string]string{"database.pool": "10", "database.pool.size": "10"},
+			wantErr: "database.pool.size conflicts with database.pool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Nest(tt.vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Nest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Nest() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Nest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_NestedVariable(t *testing.T) {
+	vars := map[string]string{"name": "app", "services": "api,web", "database.host": "localhost", "database.port": "5432"}
+	content := "{{ .name }} {{ .database.host }}:{{ .database.port }} {{ len .services }}"
+
+	r := &Renderer{Engine: EngineGoTemplate, Lists: []string{"services"}}
+	got, err := r.Render("f", content, vars)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "app localhost:5432 2"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	// The variables are nested once per Renderer
+	vars["database.host"] = "db"
+	if got, _ := r.Render("f", content, vars); got != "app localhost:5432 2" {
+		t.Errorf("Render() = %q, want the variables of the first render", got)
+	}
+
+	vars["database"] = "postgres"
+	r = &Renderer{Engine: EngineGoTemplate, Lists: []string{"services"}}
+	if _, err := r.Render("f", content, vars); err == nil {
+		t.Error("Render() should return error for conflicting variables")
+	}
+}
@@ -2,13 +2,20 @@
 package template
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/makemore/scaffold/internal/condition"
 	"github.com/makemore/scaffold/internal/config"
 )
 
@@ -16,10 +23,67 @@ import (
 type Processor struct {
 	manifest  *config.Manifest
 	variables map[string]string
+	lists     []string // Variables holding comma-separated lists
 	srcDir    string
+	rootDir   string // Top of the fetched source, which external includes stay within
 	destDir   string
+	written   []string // Relative paths of files written, slash-separated
+	result    Result
+	renderer  *Renderer // Renders the files of the current Process
+	owners    Owners    // Shared with the other sources being layered
+	priority  int
+	keepGoing bool // Collect file errors instead of stopping at the first
 }
 
+// Owners records the priority of the source that wrote each output file.
+// Processors layering into the same destination share one Owners so that a
+// file is only overwritten by a source of equal or higher priority; between
+// equal priorities the later source wins.
+type Owners map[string]int
+
+// claim reports whether a source of the given priority may write path, and
+// if so records it as the owner
+func (o Owners) claim(path string, priority int) bool {
+	if current, ok := o[path]; ok && current > priority {
+		return false
+	}
+	o[path] = priority
+	return true
+}
+
+// Result categorizes what a Process run did. Paths are slash-separated;
+// written paths are relative to the destination and skipped ones to the
+// template, with a trailing slash for a whole skipped directory.
+type Result struct {
+	Created     []string // Written where no file existed
+	Overwritten []string // Written over a file from an earlier source
+	Skipped     []string // Excluded, false condition, or owned by a higher-priority source
+	Unresolved  []Token  // {{ variable }} placeholders left in written files
+}
+
+// Token is a placeholder left unsubstituted in a generated file
+type Token struct {
+	File  string // Slash-separated, relative to the destination
+	Token string // As it appears in the file, e.g. "{{ api_key }}"
+}
+
+// Add appends the categorized files of another result
+func (r *Result) Add(other *Result) {
+	r.Created = append(r.Created, other.Created...)
+	r.Overwritten = append(r.Overwritten, other.Overwritten...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Unresolved = append(r.Unresolved, other.Unresolved...)
+}
+
+// DefaultIgnore lists the VCS and OS metadata names that are never generated,
+// whatever the manifest says
+var DefaultIgnore = []string{".git", ".hg", ".svn", ".DS_Store"}
+
+// KeepFile marks a directory to generate even when it has no other content.
+// Git can't track empty directories, so templates commit this marker; the
+// directory is created and the marker itself is not written.
+const KeepFile = ".scaffold-keep"
+
 // NewProcessor creates a new template processor
 func NewProcessor(manifest *config.Manifest, srcDir, destDir string) *Processor {
 	return &Processor{
@@ -35,10 +99,85 @@ func (p *Processor) SetVariables(vars map[string]string) {
 	p.variables = vars
 }
 
-// Process processes the template and writes to the destination
-func (p *Processor) Process() error {
-	return filepath.Walk(p.srcDir, func(path string, info os.FileInfo, err error) error {
+// SetLists names the variables holding comma-separated lists, which
+// gotemplate files can range over
+func (p *Processor) SetLists(names []string) {
+	p.lists = names
+}
+
+// SetRoot sets the top of the fetched source the template directory is in,
+// such as the repository root of a template in a subdirectory. External
+// includes may reach anywhere inside it. It is the template directory itself
+// when unset.
+func (p *Processor) SetRoot(root string) {
+	p.rootDir = root
+}
+
+// SetKeepGoing makes Process carry on past files it fails to read or render,
+// like make -k
+func (p *Processor) SetKeepGoing(keepGoing bool) {
+	p.keepGoing = keepGoing
+}
+
+// SetOwners shares file ownership with other processors writing to the same
+// destination, using the manifest's priority
+func (p *Processor) SetOwners(owners Owners) {
+	p.owners = owners
+	p.priority = p.manifest.Priority
+}
+
+// Process processes the template and writes to the destination.
+//
+// Exclude patterns are applied in order with gitignore-style negation. An
+// excluded directory is normally skipped whole, but when a later negated
+// pattern could re-include something inside it the directory is still
+// walked, so broad negations such as "!**/keep" make excluded trees cost a
+// full walk.
+//
+// A directory whose name has a guard (see GuardMarker), or that matches a
+// dir_conditions pattern, is skipped whole when its condition is false and
+// generated without the guard otherwise.
+//
+// The walk is depth-first with the entries of each directory in bytewise
+// name order, whatever the platform or filesystem returns. The order of the
+// Result lists, and which file wins when two template paths render to the
+// same destination, are therefore the same on every run.
+//
+// With keep-going set, a file or directory that fails is left out and the
+// walk continues. The errors are then returned joined, each a *ProcessError,
+// together with the result of everything else.
+//
+// Cancelling ctx stops the walk before the next file and returns ctx's error.
+func (p *Processor) Process(ctx context.Context) (*Result, error) {
+	ignored, err := readIgnoreFile(p.srcDir)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := newExcludeMatcher(append(append([]string{}, p.manifest.Files.Exclude...), ignored...))
+	if err != nil {
+		return nil, err
+	}
+
+	p.result = Result{}
+	p.renderer = &Renderer{
+		Engine:   p.manifest.Engine,
+		Partials: filepath.Join(p.srcDir, PartialsDir),
+		Lists:    p.lists,
+	}
+	var failed []error
+	err = filepath.Walk(p.srcDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			if !p.keepGoing || path == p.srcDir {
+				return err
+			}
+			relPath, _ := filepath.Rel(p.srcDir, path)
+			failed = append(failed, newProcessError(filepath.ToSlash(relPath), err))
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
@@ -53,80 +192,423 @@ func (p *Processor) Process() error {
 			return nil
 		}
 
-		// Skip scaffold.yaml
-		if relPath == "scaffold.yaml" {
+		// Skip scaffold.yaml and .scaffoldignore
+		if relPath == "scaffold.yaml" || relPath == IgnoreFile {
+			return nil
+		}
+
+		// Partials are only rendered through include
+		if relPath == PartialsDir && info.IsDir() {
+			return filepath.SkipDir
+		}
+
+		// Skip VCS metadata and ignored names; other dotfiles are generated
+		if p.ignored(filepath.Base(relPath)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip excluded files, and excluded directories unless a negated
+		// pattern may re-include something inside them
+		slashPath := filepath.ToSlash(relPath)
+		if exclude.excluded(slashPath, info.IsDir()) {
+			if info.IsDir() {
+				if exclude.reincludable(slashPath) {
+					return nil
+				}
+				p.result.Skipped = append(p.result.Skipped, slashPath+"/")
+				return filepath.SkipDir
+			}
+			p.result.Skipped = append(p.result.Skipped, slashPath)
 			return nil
 		}
 
-		// Skip hidden files/directories (but not the root)
-		baseName := filepath.Base(relPath)
-		if strings.HasPrefix(baseName, ".") && baseName != "." {
+		// Skip files and whole directories whose condition is false
+		include, err := p.included(slashPath)
+		if err == nil && include && info.IsDir() {
+			include, err = p.dirIncluded(slashPath)
+		}
+		if err != nil {
+			return err
+		}
+		if !include {
 			if info.IsDir() {
+				p.result.Skipped = append(p.result.Skipped, slashPath+"/")
 				return filepath.SkipDir
 			}
+			p.result.Skipped = append(p.result.Skipped, slashPath)
 			return nil
 		}
 
-		// Apply variable substitution to path
-		destRelPath := p.substituteInPath(relPath)
+		// Apply renames, strip directory guards and then substitute
+		// variables in the path
+		destRelPath := p.substituteInPath(filepath.FromSlash(p.stripGuards(p.rename(slashPath, info.IsDir()), info.IsDir())))
 		destPath := filepath.Join(p.destDir, destRelPath)
 
 		if info.IsDir() {
 			return os.MkdirAll(destPath, info.Mode())
 		}
+		if info.Name() == KeepFile {
+			return os.MkdirAll(filepath.Dir(destPath), 0755)
+		}
 
-		return p.processFile(path, destPath, info.Mode())
+		if err := p.writeFile(path, slashPath, destRelPath, info.Mode()); err != nil {
+			if p.keepGoing {
+				failed = append(failed, err)
+				return nil
+			}
+			return err
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.processExternal(ctx, &failed); err != nil {
+		return nil, err
+	}
+	if len(failed) > 0 {
+		return &p.result, errors.Join(failed...)
+	}
+	return &p.result, nil
+}
+
+// writeFile renders the file at srcPath to destRelPath and records the
+// outcome, unless a higher-priority source owns the destination. slashPath
+// names the file in the result and errors, which are *ProcessError.
+func (p *Processor) writeFile(srcPath, slashPath, destRelPath string, mode os.FileMode) error {
+	destPath := filepath.Join(p.destDir, destRelPath)
+	slashDest := filepath.ToSlash(destRelPath)
+	if p.owners != nil && !p.owners.claim(slashDest, p.priority) {
+		p.result.Skipped = append(p.result.Skipped, slashPath)
+		return nil
+	}
+
+	_, statErr := os.Lstat(destPath)
+	unresolved, err := p.processFile(srcPath, destPath, mode)
+	if err != nil {
+		return newProcessError(slashPath, err)
+	}
+	for _, token := range unresolved {
+		p.result.Unresolved = append(p.result.Unresolved, Token{File: slashDest, Token: token})
+	}
+	p.written = append(p.written, slashDest)
+	if statErr == nil {
+		p.result.Overwritten = append(p.result.Overwritten, slashDest)
+	} else {
+		p.result.Created = append(p.result.Created, slashDest)
+	}
+	return nil
 }
 
-func (p *Processor) processFile(srcPath, destPath string, mode os.FileMode) error {
+// processExternal copies the manifest's external includes into the
+// destination, rendering their files like the template's own. A directory is
+// copied whole, except for ignored names: exclude, conditions and rename
+// match paths in the template directory and don't apply. With keep-going,
+// failed files are added to failed.
+func (p *Processor) processExternal(ctx context.Context, failed *[]error) error {
+	for _, include := range p.manifest.Files.ExternalIncludes {
+		srcPath, err := p.externalPath(include.From)
+		if err != nil {
+			return err
+		}
+		to := include.To
+		if to == "" {
+			to = path.Base(include.From)
+		}
+		destRoot := filepath.Clean(filepath.FromSlash(Substitute(to, p.variables)))
+		if !filepath.IsLocal(destRoot) {
+			return fmt.Errorf("external include %s: destination %s is outside the output", include.From, to)
+		}
+
+		err = filepath.Walk(srcPath, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if p.ignored(info.Name()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			relPath, err := filepath.Rel(srcPath, filePath)
+			if err != nil {
+				return err
+			}
+			destRelPath := filepath.Join(destRoot, relPath)
+			if info.IsDir() {
+				return os.MkdirAll(filepath.Join(p.destDir, destRelPath), info.Mode())
+			}
+			slashPath := path.Join(include.From, filepath.ToSlash(relPath))
+			if err := p.writeFile(filePath, slashPath, destRelPath, info.Mode()); err != nil {
+				if p.keepGoing {
+					*failed = append(*failed, err)
+					return nil
+				}
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("external include %s: %w", include.From, err)
+		}
+	}
+	return nil
+}
+
+// externalPath resolves an external include relative to the template
+// directory and checks, following symlinks, that it stays within the root
+func (p *Processor) externalPath(from string) (string, error) {
+	root := p.rootDir
+	if root == "" {
+		root = p.srcDir
+	}
+	within := func(root, target string) bool {
+		rel, err := filepath.Rel(root, target)
+		return err == nil && filepath.IsLocal(rel)
+	}
+
+	target := filepath.Join(p.srcDir, filepath.FromSlash(from))
+	if !within(root, target) {
+		return "", fmt.Errorf("external include %s is outside the source", from)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", fmt.Errorf("external include %s not found: %w", from, err)
+	}
+	if !within(realRoot, realTarget) {
+		return "", fmt.Errorf("external include %s is outside the source", from)
+	}
+	return realTarget, nil
+}
+
+// ignored reports whether a file or directory name is never generated
+func (p *Processor) ignored(name string) bool {
+	for _, ignore := range DefaultIgnore {
+		if name == ignore {
+			return true
+		}
+	}
+	for _, ignore := range p.manifest.Files.Ignore {
+		if name == ignore {
+			return true
+		}
+	}
+	return false
+}
+
+// rename applies the manifest's rename mappings to a slash-separated source
+// path. A mapping for a directory also moves everything inside it, and the
+// longest matching mapping wins. A file without a mapping of its own then has
+// the first matching strip suffix removed from its name.
+func (p *Processor) rename(relPath string, isDir bool) string {
+	if to, ok := p.manifest.Files.Rename[relPath]; ok {
+		return to
+	}
+
+	renamed, longest := relPath, -1
+	for from, to := range p.manifest.Files.Rename {
+		if len(from) <= longest {
+			continue
+		}
+		if relPath == from {
+			renamed, longest = to, len(from)
+		} else if rest, ok := strings.CutPrefix(relPath, from+"/"); ok {
+			renamed, longest = to+"/"+rest, len(from)
+		}
+	}
+	if isDir {
+		return renamed
+	}
+
+	dir, name := path.Split(renamed)
+	for _, suffix := range p.manifest.Files.StripSuffixes {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok && trimmed != "" {
+			return dir + trimmed
+		}
+	}
+	return renamed
+}
+
+// included evaluates the manifest conditions whose glob matches relPath. All
+// matching conditions must hold.
+func (p *Processor) included(relPath string) (bool, error) {
+	conditions := p.manifest.Files.Conditions
+	patterns := make([]string, 0, len(conditions))
+	for pattern := range conditions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, relPath)
+		if err != nil {
+			return false, fmt.Errorf("invalid file condition pattern %q: %w", pattern, err)
+		}
+		if !matched {
+			continue
+		}
+
+		ok, err := condition.Evaluate(conditions[pattern], p.variables)
+		if err != nil {
+			return false, fmt.Errorf("condition for %s: %w", relPath, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// WrittenFiles returns the slash-separated paths, relative to the destination,
+// of the files written by Process
+func (p *Processor) WrittenFiles() []string {
+	return p.written
+}
+
+// processFile writes one file and returns the placeholders left unresolved
+// in it
+func (p *Processor) processFile(srcPath, destPath string, mode os.FileMode) ([]string, error) {
 	// Ensure parent directory exists
 	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check if file is binary
 	if isBinary(srcPath) {
-		return copyFile(srcPath, destPath, mode)
+		return nil, copyFile(srcPath, destPath, mode)
 	}
 
 	// Read and process text file
 	content, err := os.ReadFile(srcPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	processed, err := p.renderer.Render(filepath.Base(srcPath), string(content), p.variables)
+	if err != nil {
+		return nil, err
+	}
+	if p.manifest.TrimBlankLines {
+		processed = TrimBlankLines(processed)
 	}
+	processed = NormalizeLineEndings(processed, p.manifest.LineEndings)
 
-	processed := p.substituteVariables(string(content))
+	if err := os.WriteFile(destPath, []byte(processed), mode); err != nil {
+		return nil, err
+	}
 
-	return os.WriteFile(destPath, []byte(processed), mode)
+	// Braces in gotemplate output were escaped on purpose, e.g. {{"{{ x }}"}}
+	var unresolved []string
+	if p.manifest.Engine == "" || p.manifest.Engine == EngineSimple {
+		unresolved = Unresolved(processed)
+	}
+	return unresolved, nil
 }
 
-// substituteVariables replaces {{ variable }} patterns
-func (p *Processor) substituteVariables(content string) string {
-	// Match {{ variable_name }} with optional whitespace
-	re := regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+// variablePattern matches {{ variable_name }} with optional whitespace, and
+// optionally filters, as in {{ variable_name | upper }}
+var variablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)((?:\s*\|\s*[a-zA-Z_][a-zA-Z0-9_]*)*)\s*\}\}`)
 
-	return re.ReplaceAllStringFunc(content, func(match string) string {
+// filters transform a value in {{ variable | filter }}. They are the case
+// filters and the sanitizers gotemplate files have as functions.
+var filters = map[string]func(string) string{
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"title":       titleCase,
+	"slug":        Slug,
+	"module_path": ModulePath,
+}
+
+// Substitute replaces {{ variable }} patterns in content with values from vars,
+// applying any filters from left to right. Unknown variables, and variables
+// with an unknown filter, are left untouched.
+func Substitute(content string, vars map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(content, func(match string) string {
 		// Extract variable name
-		submatch := re.FindStringSubmatch(match)
-		if len(submatch) < 2 {
+		submatch := variablePattern.FindStringSubmatch(match)
+		if len(submatch) < 3 {
 			return match
 		}
 		varName := submatch[1]
 
-		if val, ok := p.variables[varName]; ok {
-			return val
+		val, ok := vars[varName]
+		if !ok {
+			return match // Keep original if not found
+		}
+		for _, name := range strings.Split(submatch[2], "|")[1:] {
+			filter, ok := filters[strings.TrimSpace(name)]
+			if !ok {
+				return match
+			}
+			val = filter(val)
 		}
-		return match // Keep original if not found
+		return val
 	})
 }
 
+// titleCase upper-cases the first letter of each space-separated word
+func titleCase(s string) string {
+	words := strings.Split(s, " ")
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		if size > 0 {
+			words[i] = string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// References returns the names of variables referenced in content, in order
+// of first appearance
+func References(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range variablePattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// Unresolved returns the {{ variable }} placeholders in content, in order of
+// first appearance. Expressions that aren't plain variable names, such as
+// GitHub Actions' ${{ github.sha }} or Helm's {{ .Values.image }}, are not
+// placeholders and are ignored.
+func Unresolved(content string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, m := range variablePattern.FindAllStringIndex(content, -1) {
+		token := content[m[0]:m[1]]
+		if m[0] > 0 && content[m[0]-1] == '$' {
+			continue
+		}
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// pathVariablePattern matches __variable_name__ in file and directory names
+var pathVariablePattern = regexp.MustCompile(`__([a-zA-Z_][a-zA-Z0-9_]*)__`)
+
 // substituteInPath handles __variable__ patterns in file/directory names
 func (p *Processor) substituteInPath(path string) string {
-	// Match __variable_name__ pattern
-	re := regexp.MustCompile(`__([a-zA-Z_][a-zA-Z0-9_]*)__`)
-
-	return re.ReplaceAllStringFunc(path, func(match string) string {
+	return pathVariablePattern.ReplaceAllStringFunc(path, func(match string) string {
 		varName := strings.Trim(match, "_")
 		if val, ok := p.variables[varName]; ok {
 			return val
@@ -174,4 +656,3 @@ func copyFile(src, dst string, mode os.FileMode) error {
 	_, err = io.Copy(dstFile, srcFile)
 	return err
 }
-
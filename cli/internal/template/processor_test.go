@@ -1,8 +1,13 @@
 package template
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -29,6 +34,9 @@ func TestProcessor_Process(t *testing.T) {
 		"README.md":                      "# {{ project_name }}\n\nBy {{ author }}",
 		"__project_slug__/config.py":     "PROJECT = '{{ project_slug }}'",
 		"__project_slug__/settings.yaml": "name: {{ project_name }}",
+		".gitignore":                     "*.pyc",
+		".github/workflows/ci.yml":       "name: {{ project_name }} CI",
+		".DS_Store":                      "metadata",
 	}
 
 	for path, content := range testFiles {
@@ -68,7 +76,7 @@ variables:
 	})
 
 	// Process the template
-	if err := processor.Process(); err != nil {
+	if _, err := processor.Process(context.Background()); err != nil {
 		t.Fatalf("Process() error = %v", err)
 	}
 
@@ -98,6 +106,21 @@ variables:
 	if !strings.Contains(string(configContent), "PROJECT = 'my_project'") {
 		t.Errorf("config.py should contain \"PROJECT = 'my_project'\", got: %s", configContent)
 	}
+
+	// Dotfiles survive, OS metadata does not
+	if _, err := os.Stat(filepath.Join(destDir, ".gitignore")); err != nil {
+		t.Error(".gitignore should be generated")
+	}
+	ciContent, err := os.ReadFile(filepath.Join(destDir, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatalf("Failed to read .github/workflows/ci.yml: %v", err)
+	}
+	if string(ciContent) != "name: My Project CI" {
+		t.Errorf("ci.yml = %q, want %q", ciContent, "name: My Project CI")
+	}
+	if _, err := os.Stat(filepath.Join(destDir, ".DS_Store")); !os.IsNotExist(err) {
+		t.Error(".DS_Store should not be generated")
+	}
 }
 
 func TestProcessor_SkipsScaffoldYaml(t *testing.T) {
@@ -121,7 +144,7 @@ func TestProcessor_SkipsScaffoldYaml(t *testing.T) {
 	manifest := &config.Manifest{Name: "test"}
 	processor := NewProcessor(manifest, srcDir, destDir)
 
-	if err := processor.Process(); err != nil {
+	if _, err := processor.Process(context.Background()); err != nil {
 		t.Fatalf("Process() error = %v", err)
 	}
 
@@ -131,3 +154,798 @@ func TestProcessor_SkipsScaffoldYaml(t *testing.T) {
 	}
 }
 
+func TestProcessor_Priority(t *testing.T) {
+	destDir := t.TempDir()
+
+	writeSource := func(name, content string) string {
+		t.Helper()
+		dir := filepath.Join(t.TempDir(), name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		return dir
+	}
+
+	tests := []struct {
+		name    string
+		order   []*config.Manifest
+		want    string
+		skipped string // Source that should not have written the file
+	}{
+		{
+			name:    "higher priority added first still wins",
+			order:   []*config.Manifest{{Name: "postgres", Priority: 10}, {Name: "redis"}},
+			want:    "postgres",
+			skipped: "redis",
+		},
+		{
+			name:  "higher priority added last wins",
+			order: []*config.Manifest{{Name: "redis"}, {Name: "postgres", Priority: 10}},
+			want:  "postgres",
+		},
+		{
+			name:  "ties fall back to order",
+			order: []*config.Manifest{{Name: "postgres"}, {Name: "redis"}},
+			want:  "redis",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owners := Owners{}
+			for _, m := range tt.order {
+				p := NewProcessor(m, writeSource(m.Name, m.Name), destDir)
+				p.SetOwners(owners)
+				if _, err := p.Process(context.Background()); err != nil {
+					t.Fatalf("Process() error = %v", err)
+				}
+				if m.Name == tt.skipped && len(p.WrittenFiles()) != 0 {
+					t.Errorf("%s WrittenFiles() = %v, want none", m.Name, p.WrittenFiles())
+				}
+			}
+
+			got, err := os.ReadFile(filepath.Join(destDir, "docker-compose.yml"))
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("docker-compose.yml = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor_Conditions(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"README.md":           "# app",
+		"docker-compose.yml":  "services: {}",
+		"docker/Dockerfile":   "FROM scratch",
+		"docker/nginx/nginx":  "server {}",
+		"ci/github/build.yml": "on: push",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{
+		Name: "test",
+		Files: config.FileConfig{
+			Conditions: map[string]string{
+				"docker-compose.yml": "use_docker == true",
+				"docker":             "use_docker",
+				"ci/*":               "ci != none",
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "enabled",
+			vars:    map[string]string{"use_docker": "true", "ci": "github"},
+			present: []string{"README.md", "docker-compose.yml", "docker/Dockerfile", "docker/nginx/nginx", "ci/github/build.yml"},
+		},
+		{
+			name:    "disabled",
+			vars:    map[string]string{"use_docker": "false", "ci": "none"},
+			present: []string{"README.md"},
+			absent:  []string{"docker-compose.yml", "docker", "ci/github"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			p := NewProcessor(manifest, srcDir, destDir)
+			p.SetVariables(tt.vars)
+			if _, err := p.Process(context.Background()); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			for _, path := range tt.present {
+				if _, err := os.Stat(filepath.Join(destDir, path)); err != nil {
+					t.Errorf("%s should be generated", path)
+				}
+			}
+			for _, path := range tt.absent {
+				if _, err := os.Stat(filepath.Join(destDir, path)); !os.IsNotExist(err) {
+					t.Errorf("%s should not be generated", path)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_ExcludeNegation(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"main.js":                     "app",
+		"node_modules/left-pad/index": "module.exports = 1",
+		"node_modules/keep.txt":       "keep",
+		"build/out.o":                 "obj",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{
+		Name: "test",
+		Files: config.FileConfig{
+			Exclude: []string{"node_modules/**", "!node_modules/keep.txt", "build"},
+		},
+	}
+	destDir := t.TempDir()
+	if _, err := NewProcessor(manifest, srcDir, destDir).Process(context.Background()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	for _, path := range []string{"main.js", "node_modules/keep.txt"} {
+		if _, err := os.Stat(filepath.Join(destDir, path)); err != nil {
+			t.Errorf("%s should be generated", path)
+		}
+	}
+	for _, path := range []string{"node_modules/left-pad", "build"} {
+		if _, err := os.Stat(filepath.Join(destDir, path)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be generated", path)
+		}
+	}
+}
+
+func TestProcessor_Dotfiles(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		".github/workflows/ci.yml": "name: {{ project_name }}",
+		".env.example":             "DEBUG=1",
+		"gitignore":                "node_modules/",
+		".git/HEAD":                "ref: refs/heads/main",
+		".hg/store":                "data",
+		".idea/workspace.xml":      "<project/>",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{
+		Name: "test",
+		Files: config.FileConfig{
+			Rename: map[string]string{"gitignore": ".gitignore"},
+			Ignore: []string{".idea"},
+		},
+	}
+	destDir := t.TempDir()
+	p := NewProcessor(manifest, srcDir, destDir)
+	p.SetVariables(map[string]string{"project_name": "app"})
+	if _, err := p.Process(context.Background()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatalf(".github/workflows/ci.yml should be generated: %v", err)
+	}
+	if string(got) != "name: app" {
+		t.Errorf("ci.yml = %q, want %q", got, "name: app")
+	}
+	for _, path := range []string{".env.example", ".gitignore"} {
+		if _, err := os.Stat(filepath.Join(destDir, path)); err != nil {
+			t.Errorf("%s should be generated", path)
+		}
+	}
+	for _, path := range []string{".git", ".hg", ".idea", "gitignore"} {
+		if _, err := os.Stat(filepath.Join(destDir, path)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be generated", path)
+		}
+	}
+}
+
+func TestProcessor_StripSuffixes(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"main.go.tmpl":          "package {{ name }}",
+		"README.md.tmpl":        "# {{ name }}",
+		"cmd/__name__.go.tmpl":  "package cmd",
+		"templates/index.tmpl":  "{{ name }}",
+		"templates.tmpl/a.tmpl": "a",
+		".tmpl":                 "kept",
+		"config.yaml":           "name: {{ name }}",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{Files: config.FileConfig{
+		StripSuffixes: []string{".tmpl"},
+		Rename: map[string]string{
+			"README.md.tmpl":       "docs/README.md",
+			"templates/index.tmpl": "templates/index.tmpl",
+		},
+	}}
+	destDir := t.TempDir()
+	p := NewProcessor(manifest, srcDir, destDir)
+	p.SetVariables(map[string]string{"name": "app"})
+	if _, err := p.Process(context.Background()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	want := []string{".tmpl", "cmd/app.go", "config.yaml", "docs/README.md", "main.go", "templates.tmpl/a", "templates/index.tmpl"}
+	got := p.WrittenFiles()
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WrittenFiles() = %v, want %v", got, want)
+	}
+	if content, _ := os.ReadFile(filepath.Join(destDir, "main.go")); string(content) != "package app" {
+		t.Errorf("main.go = %q, want rendered", content)
+	}
+}
+
+func TestProcessor_IgnoreFile(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		IgnoreFile:          "# Editor files\n*.swp\n\nbuild/\n!build/keep.txt\n",
+		"main.go":           "package main",
+		"main.go.swp":       "swap",
+		"build/out.bin":     "out",
+		"build/keep.txt":    "keep",
+		"docs/notes.md":     "notes",
+		"docs/notes.md.swp": "swap",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{Files: config.FileConfig{Exclude: []string{"docs/"}}}
+	p := NewProcessor(manifest, srcDir, t.TempDir())
+	if _, err := p.Process(context.Background()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	got := p.WrittenFiles()
+	sort.Strings(got)
+	want := []string{"build/keep.txt", "main.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WrittenFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestProcessor_Result(t *testing.T) {
+	baseDir, moduleDir := t.TempDir(), t.TempDir()
+	for dir, files := range map[string]map[string]string{
+		baseDir:   {"README.md": "base", "main.go": "package main", "debug.log": "log", "docker/Dockerfile": "FROM scratch"},
+		moduleDir: {"README.md": "module", "auth.go": "package auth"},
+	} {
+		for path, content := range files {
+			fullPath := filepath.Join(dir, path)
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+		}
+	}
+
+	base := &config.Manifest{
+		Name: "base",
+		Files: config.FileConfig{
+			Exclude:    []string{"*.log"},
+			Conditions: map[string]string{"docker": "use_docker"},
+		},
+	}
+	module := &config.Manifest{Name: "module"}
+	destDir := t.TempDir()
+	owners := Owners{}
+
+	p := NewProcessor(base, baseDir, destDir)
+	p.SetOwners(owners)
+	baseResult, err := p.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	p = NewProcessor(module, moduleDir, destDir)
+	p.SetOwners(owners)
+	moduleResult, err := p.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	want := &Result{Created: []string{"README.md", "main.go"}, Skipped: []string{"debug.log", "docker/"}}
+	if !reflect.DeepEqual(baseResult, want) {
+		t.Errorf("base result = %+v, want %+v", baseResult, want)
+	}
+	want = &Result{Created: []string{"auth.go"}, Overwritten: []string{"README.md"}}
+	if !reflect.DeepEqual(moduleResult, want) {
+		t.Errorf("module result = %+v, want %+v", moduleResult, want)
+	}
+}
+
+func TestProcessor_InvalidCondition(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	manifest := &config.Manifest{Files: config.FileConfig{Conditions: map[string]string{"a.txt": "x =="}}}
+	if _, err := NewProcessor(manifest, srcDir, t.TempDir()).Process(context.Background()); err == nil {
+		t.Error("Process() should return error for an invalid condition")
+	}
+}
+
+func TestProcessor_Partials(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"_partials/license.txt": "Copyright {{ .author }}",
+		"main.py":               "# {{ include \"license.txt\" }}\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	destDir := t.TempDir()
+	p := NewProcessor(&config.Manifest{Engine: EngineGoTemplate}, srcDir, destDir)
+	p.SetVariables(map[string]string{"author": "Acme"})
+	if _, err := p.Process(context.Background()); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(destDir, "main.py"))
+	if string(got) != "# Copyright Acme\n" {
+		t.Errorf("main.py = %q, want included partial", got)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, PartialsDir)); !os.IsNotExist(err) {
+		t.Error("_partials should not be copied to the output")
+	}
+}
+
+func TestProcessor_KeepMarkers(t *testing.T) {
+	srcDir := t.TempDir()
+	for _, path := range []string{"logs/" + KeepFile, "tmp/.gitkeep", "__name__/" + KeepFile} {
+		fullPath := filepath.Join(srcDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, nil, 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	manifest := &config.Manifest{Files: config.FileConfig{Ignore: []string{".gitkeep"}}}
+	destDir := t.TempDir()
+	p := NewProcessor(manifest, srcDir, destDir)
+	p.SetVariables(map[string]string{"name": "app"})
+	result, err := p.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	for _, dir := range []string{"logs", "tmp", "app"} {
+		entries, err := os.ReadDir(filepath.Join(destDir, dir))
+		if err != nil {
+			t.Errorf("%s/ should be created: %v", dir, err)
+			continue
+		}
+		if len(entries) != 0 {
+			t.Errorf("%s/ = %v, want an empty directory without its marker", dir, entries)
+		}
+	}
+	if len(result.Created) != 0 || len(p.WrittenFiles()) != 0 {
+		t.Errorf("Process() created %v, want no files for markers", result.Created)
+	}
+}
+
+func TestSubstitute_Filters(t *testing.T) {
+	vars := map[string]string{"name": "My App", "org": "Acme Corp"}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"{{ name | upper }}", "MY APP"},
+		{"{{name|lower}}", "my app"},
+		{"{{ org | lower | title }}", "Acme Corp"},
+		{"{{ name | slug }}", "my_app"},
+		{"example.com/{{ org | module_path }}/{{ name | slug }}", "example.com/acme-corp/my_app"},
+		{"{{ name | shout }}", "{{ name | shout }}"},
+		{"{{ missing | upper }}", "{{ missing | upper }}"},
+	}
+
+	for _, tt := range tests {
+		if got := Substitute(tt.content, vars); got != tt.want {
+			t.Errorf("Substitute(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestProcessor_Unresolved(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": "name: {{ name }}\nkey: {{ api_key }}\nbackup: {{api_key}}\nagain: {{ api_key }}\n",
+		"ci.yml":      "ref: ${{ github.sha }}\nenv: ${{ env }}\n",
+		"values.yaml": "image: {{ .Values.image }}\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(srcDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	p := NewProcessor(&config.Manifest{}, srcDir, t.TempDir())
+	p.SetVariables(map[string]string{"name": "app"})
+	result, err := p.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	want := []Token{
+		{File: "config.yaml", Token: "{{ api_key }}"},
+		{File: "config.yaml", Token: "{{api_key}}"},
+	}
+	if !reflect.DeepEqual(result.Unresolved, want) {
+		t.Errorf("Unresolved = %v, want %v", result.Unresolved, want)
+	}
+}
+
+func TestProcessor_UnresolvedEscaped(t *testing.T) {
+	srcDir := t.TempDir()
+	content := `{{ .name }} renders {{ "{{ name }}" }} literally`
+	if err := os.WriteFile(filepath.Join(srcDir, "README.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	destDir := t.TempDir()
+	p := NewProcessor(&config.Manifest{Engine: EngineGoTemplate}, srcDir, destDir)
+	p.SetVariables(map[string]string{"name": "app"})
+	result, err := p.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(destDir, "README.md"))
+	if string(got) != "app renders {{ name }} literally" {
+		t.Fatalf("README.md = %q", got)
+	}
+	if len(result.Unresolved) != 0 {
+		t.Errorf("Unresolved = %v, want none for escaped braces", result.Unresolved)
+	}
+}
+
+func TestProcessor_Cancelled(t *testing.T) {
+	srcDir := t.TempDir()
+	for i := 0; i < 200; i++ {
+		dir := filepath.Join(srcDir, fmt.Sprintf("pkg%03d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package {{ name }}"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	destDir := filepath.Join(t.TempDir(), "out")
+	p := NewProcessor(&config.Manifest{}, srcDir, destDir)
+	p.SetVariables(map[string]string{"name": "app"})
+
+	_, err := p.Process(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Process() error = %v, want context.Canceled", err)
+	}
+	if written := p.WrittenFiles(); len(written) != 0 {
+		t.Errorf("Process() wrote %d files after cancellation, want none", len(written))
+	}
+}
+
+func TestProcessor_DeterministicOrder(t *testing.T) {
+	srcDir := t.TempDir()
+	// Written out of order so the walk cannot rely on creation order
+	files := []string{"zeta.txt", "b/2.txt", "a.txt", "b/1.txt", "B.txt", "a/z.txt", "app.txt", "__name__.txt"}
+	for _, path := range files {
+		fullPath := filepath.Join(srcDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(path), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	run := func() (*Result, string) {
+		destDir := t.TempDir()
+		p := NewProcessor(&config.Manifest{}, srcDir, destDir)
+		p.SetVariables(map[string]string{"name": "app"})
+		result, err := p.Process(context.Background())
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		return result, destDir
+	}
+
+	first, destDir := run()
+	second, _ := run()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("second run = %+v, want %+v", second, first)
+	}
+
+	// Depth-first, bytewise within each directory, so a/z.txt comes before
+	// a.txt; __name__.txt renders to app.txt before app.txt itself is reached
+	want := []string{"B.txt", "app.txt", "a/z.txt", "a.txt", "b/1.txt", "b/2.txt", "zeta.txt"}
+	if !reflect.DeepEqual(first.Created, want) {
+		t.Errorf("Created = %v, want %v", first.Created, want)
+	}
+	if !reflect.DeepEqual(first.Overwritten, []string{"app.txt"}) {
+		t.Errorf("Overwritten = %v, want [app.txt]", first.Overwritten)
+	}
+
+	// The later path in walk order wins a destination collision
+	got, _ := os.ReadFile(filepath.Join(destDir, "app.txt"))
+	if string(got) != "app.txt" {
+		t.Errorf("app.txt = %q, want the content of app.txt", got)
+	}
+}
+
+func TestProcessor_LineEndings(t *testing.T) {
+	srcDir := t.TempDir()
+	mixed := "first\r\nsecond\nthird\r\n{{ name }}\n"
+	binary := []byte("\x00\x01\r\n\x02\n")
+	if err := os.WriteFile(filepath.Join(srcDir, "run.sh"), []byte(mixed), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "data.bin"), binary, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"", "first\r\nsecond\nthird\r\napp\n"},
+		{LineEndingsPreserve, "first\r\nsecond\nthird\r\napp\n"},
+		{LineEndingsLF, "first\nsecond\nthird\napp\n"},
+		{LineEndingsCRLF, "first\r\nsecond\r\nthird\r\napp\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run("style "+tt.style, func(t *testing.T) {
+			destDir := t.TempDir()
+			p := NewProcessor(&config.Manifest{LineEndings: tt.style}, srcDir, destDir)
+			p.SetVariables(map[string]string{"name": "app"})
+			if _, err := p.Process(context.Background()); err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			if got, _ := os.ReadFile(filepath.Join(destDir, "run.sh")); string(got) != tt.want {
+				t.Errorf("run.sh = %q, want %q", got, tt.want)
+			}
+			if got, _ := os.ReadFile(filepath.Join(destDir, "data.bin")); !reflect.DeepEqual(got, binary) {
+				t.Errorf("data.bin = %q, want the binary file untouched", got)
+			}
+		})
+	}
+}
+
+func TestProcessor_KeepGoing(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"a.txt":     "{{ .name }}",
+		"bad.txt":   "line one\n{{ if }}",
+		"c/d.txt":   "{{ .name }}",
+		"worse.txt": "{{ end }}",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(srcDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	manifest := &config.Manifest{Engine: EngineGoTemplate}
+
+	p := NewProcessor(manifest, srcDir, t.TempDir())
+	p.SetVariables(map[string]string{"name": "app"})
+	if result, err := p.Process(context.Background()); err == nil || result != nil {
+		t.Fatalf("Process() = %v, %v, want the first error alone", result, err)
+	}
+
+	destDir := t.TempDir()
+	p = NewProcessor(manifest, srcDir, destDir)
+	p.SetVariables(map[string]string{"name": "app"})
+	p.SetKeepGoing(true)
+	result, err := p.Process(context.Background())
+	if err == nil || result == nil {
+		t.Fatalf("Process() = %v, %v, want a result and the joined errors", result, err)
+	}
+
+	if want := []string{"a.txt", "c/d.txt"}; !reflect.DeepEqual(result.Created, want) {
+		t.Errorf("Created = %v, want %v", result.Created, want)
+	}
+	if got, _ := os.ReadFile(filepath.Join(destDir, "c", "d.txt")); string(got) != "app" {
+		t.Errorf("c/d.txt = %q, want the file after the failure generated", got)
+	}
+
+	var failed []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var pe *ProcessError
+		if !errors.As(e, &pe) {
+			t.Fatalf("error %v is not a *ProcessError", e)
+		}
+		failed = append(failed, fmt.Sprintf("%s:%d", pe.File, pe.Line))
+	}
+	if want := []string{"bad.txt:2", "worse.txt:1"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed files = %v, want %v", failed, want)
+	}
+}
+
+func TestProcessor_ExternalIncludes(t *testing.T) {
+	// A monorepo with templates sharing files outside their directories
+	root := t.TempDir()
+	files := map[string]string{
+		"templates/api/README.md":    "# {{ project_name }}",
+		"shared/.editorconfig":       "root = true",
+		"shared/ci/build.yml":        "name: {{ project_name }} CI",
+		"shared/ci/.git/HEAD":        "ref: refs/heads/main",
+		"templates/web/package.json": "{}",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	srcDir := filepath.Join(root, "templates", "api")
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "shared", "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		includes []config.ExternalInclude
+		noRoot   bool
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "file",
+			includes: []config.ExternalInclude{{From: "../../shared/.editorconfig"}},
+			want:     map[string]string{".editorconfig": "root = true"},
+		},
+		{
+			name:     "directory with variables",
+			includes: []config.ExternalInclude{{From: "../../shared/ci", To: ".github/{{ project_name }}"}},
+			want:     map[string]string{".github/app/build.yml": "name: app CI"},
+		},
+		{
+			name:     "outside the root",
+			includes: []config.ExternalInclude{{From: "../../../etc/passwd"}},
+			wantErr:  "outside the source",
+		},
+		{
+			name:     "symlink out of the root",
+			includes: []config.ExternalInclude{{From: "../../shared/escape"}},
+			wantErr:  "outside the source",
+		},
+		{
+			name:     "root defaults to the template directory",
+			includes: []config.ExternalInclude{{From: "../../shared/.editorconfig"}},
+			noRoot:   true,
+			wantErr:  "outside the source",
+		},
+		{
+			name:     "destination outside the output",
+			includes: []config.ExternalInclude{{From: "../../shared/.editorconfig", To: "../.editorconfig"}},
+			wantErr:  "outside the output",
+		},
+		{
+			name:     "missing",
+			includes: []config.ExternalInclude{{From: "../web/missing.json"}},
+			wantErr:  "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			manifest := &config.Manifest{Name: "api", Files: config.FileConfig{ExternalIncludes: tt.includes}}
+			p := NewProcessor(manifest, srcDir, destDir)
+			p.SetVariables(map[string]string{"project_name": "app"})
+			if !tt.noRoot {
+				p.SetRoot(root)
+			}
+
+			_, err := p.Process(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Process() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			want := map[string]string{"README.md": "# app"}
+			for path, content := range tt.want {
+				want[path] = content
+			}
+			got := make(map[string]string)
+			for _, path := range p.WrittenFiles() {
+				data, _ := os.ReadFile(filepath.Join(destDir, filepath.FromSlash(path)))
+				got[path] = string(data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("written = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package scheme lets programs embedding scaffold add their own source URI
+// schemes, such as an in-house artifact store. Register handlers before
+// running cmd.Execute.
+package scheme
+
+import (
+	"context"
+
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// Source is a template source of a registered scheme
+type Source struct {
+	URI    string // As given, set by scaffold when the handler leaves it empty
+	URL    string // Location within the scheme, e.g. an artifact name
+	Ref    string // Version or tag, if the scheme has them
+	Subdir string // Subdirectory holding the template within what is fetched
+}
+
+// Handler parses and fetches the sources of a scheme
+type Handler interface {
+	// Parse turns a URI of the scheme into a Source
+	Parse(uri string) (*Source, error)
+	// Fetch makes the source available locally and returns its path. dir is
+	// the source's cache entry, which the handler may fetch into and find
+	// again on later fetches; a handler returning a path elsewhere leaves it
+	// empty.
+	Fetch(ctx context.Context, src *Source, dir string) (string, error)
+}
+
+// Register makes sources starting with name: parse and fetch with handler.
+// Like database/sql.Register, it panics when handler is nil, the scheme is
+// built in, such as git or oci, or it is registered twice.
+func Register(name string, handler Handler) {
+	if handler == nil {
+		panic("scheme: Register handler is nil")
+	}
+	source.RegisterScheme(name, adapter{handler})
+}
+
+// adapter serves a Handler as the source package's SchemeHandler
+type adapter struct {
+	h Handler
+}
+
+func (a adapter) Parse(uri string) (*source.Source, error) {
+	src, err := a.h.Parse(uri)
+	if err != nil || src == nil {
+		return nil, err
+	}
+	return &source.Source{URI: src.URI, URL: src.URL, Ref: src.Ref, Subdir: src.Subdir}, nil
+}
+
+func (a adapter) Fetch(ctx context.Context, src *source.Source, dir string) (string, error) {
+	return a.h.Fetch(ctx, &Source{URI: src.URI, URL: src.URL, Ref: src.Ref, Subdir: src.Subdir}, dir)
+}
@@ -0,0 +1,53 @@
+package scheme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makemore/scaffold/internal/source"
+)
+
+// store is a handler for store:<name>@<version> sources
+type store struct{}
+
+func (store) Parse(uri string) (*Source, error) {
+	name, version, _ := strings.Cut(strings.TrimPrefix(uri, "store:"), "@")
+	return &Source{URL: name, Ref: version}, nil
+}
+
+func (store) Fetch(ctx context.Context, src *Source, dir string) (string, error) {
+	return dir, os.WriteFile(filepath.Join(dir, "scaffold.yaml"), []byte("name: "+src.URL+"-"+src.Ref+"\n"), 0644)
+}
+
+func TestRegister(t *testing.T) {
+	Register("store", store{})
+
+	src, err := source.Parse("store:django@v2")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if src.Type != "store" || src.URI != "store:django@v2" || src.URL != "django" || src.Ref != "v2" {
+		t.Errorf("Parse() = %+v", src)
+	}
+	path, err := source.NewFetcher(t.TempDir()).Fetch(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(path, "scaffold.yaml")); string(got) != "name: django-v2\n" {
+		t.Errorf("scaffold.yaml = %q, want the handler's template", got)
+	}
+
+	for _, name := range []string{"store", "git", "url", "stdin"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) should panic", name)
+				}
+			}()
+			Register(name, store{})
+		}()
+	}
+}